@@ -0,0 +1,109 @@
+package singleflight
+
+import "context"
+
+// DoContextFunc is like DoContext, but passes fn a context that is
+// canceled once every caller waiting for the flight has gone away, so
+// expensive work can abort cleanly when nobody needs its result anymore.
+//
+// Callers go away when the context passed to DoContext or DoContextFunc is
+// done. Callers joining via Do or DoChan wait for the result until it is
+// available, so the context of fn is never canceled while such a caller is
+// waiting. The context of fn carries the values of the ctx of the caller
+// that started the flight, but not its cancellation. Once the flight is
+// abandoned, subsequent calls for key start a new flight.
+func (g *Group[K, V]) DoContextFunc(
+	ctx context.Context, key K, fn func(ctx context.Context) (V, error),
+) (v V, err error, shared bool) {
+	return g.doContext(ctx, key, fn, true)
+}
+
+// DoContextFunc is the sharded variant of Group.DoContextFunc.
+func (sg *ShardedGroup[K, V]) DoContextFunc(
+	ctx context.Context, key K, fn func(ctx context.Context) (V, error),
+) (v V, err error, shared bool) {
+	return sg.shards[sg.shardIndex(key)].DoContextFunc(ctx, key, fn)
+}
+
+// doContext implements DoContext and DoContextFunc. If cancelable, fn
+// receives a context canceled when the flight is abandoned.
+func (g *Group[K, V]) doContext(
+	ctx context.Context, key K, fn func(context.Context) (V, error), cancelable bool,
+) (v V, err error, shared bool) {
+	if err := g.admitDeadline(ctx, key); err != nil {
+		return v, err, false
+	}
+	if err := g.checkKey(key); err != nil {
+		return v, err, false
+	}
+
+	fk := g.flightKey(key, laneNormal)
+	ch := make(chan Result[V], 1)
+
+	c, leader, err := g.enlist(key, fk, ch)
+	if err != nil {
+		return v, err, false
+	}
+
+	if leader {
+		c.leader = ctx
+		if cancelable {
+			g.launch(c, key, fk, context.WithoutCancel(ctx), fn)
+		} else {
+			go g.doCall(c, key, fk, costDerived, task[V]{work: fn, ctx: context.WithoutCancel(ctx)})
+		}
+	}
+
+	defer c.task.region("waiting")()
+
+	select {
+	case res := <-ch:
+		if !leader && c.abdicated() && ctx.Err() == nil {
+			return g.doContext(ctx, key, fn, cancelable)
+		}
+
+		return res.Val, res.Err, res.Shared
+	case <-ctx.Done():
+		g.leave(c, fk)
+		return v, ctx.Err(), false
+	}
+}
+
+// launch executes fn for the call c, led by the caller, registered under fk.
+// fn receives a context derived from parent that is canceled once the flight
+// is abandoned.
+func (g *Group[K, V]) launch(
+	c *call[V], key K, fk flightKey[K], parent context.Context, fn func(context.Context) (V, error),
+) {
+	work, cancel := context.WithCancel(parent)
+
+	g.mu.Lock()
+	c.abandon = cancel
+	g.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		g.doCall(c, key, fk, costDerived, task[V]{work: fn, ctx: work})
+	}()
+}
+
+// leave records that a caller of the call c, registered under fk, stopped
+// waiting for its result. Once no caller is left, the flight is forgotten
+// and a cancelable execution is canceled, unless executions are detached
+// (see WithDetachedExecution).
+func (g *Group[K, V]) leave(c *call[V], fk flightKey[K]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.interest--
+	if c.interest > 0 || g.settings().detachedRetain > 0 {
+		return
+	}
+
+	if c.abandon != nil {
+		c.abandon()
+	}
+	if g.m[fk] == c {
+		delete(g.m, fk)
+	}
+}
@@ -0,0 +1,154 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type contextFuncDoer[T ~string, V any] interface {
+	doer[T, V]
+	DoContextFunc(context.Context, T, func(context.Context) (V, error)) (V, error, bool)
+}
+
+func TestGroupDoContextFuncAbandoned(t *testing.T) {
+	var g Group[string, int]
+	doContextFuncAbandoned(t, &g, keyA)
+}
+
+func TestShardedGroupDoContextFuncAbandoned(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	doContextFuncAbandoned(t, sg, keyB)
+}
+
+func doContextFuncAbandoned[T ~string](t *testing.T, d contextFuncDoer[T, int], key T) {
+	t.Helper()
+
+	aborted := make(chan error, 1)
+	fn := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return 0, ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(t.Context())
+	ctx2, cancel2 := context.WithCancel(t.Context())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		go func() {
+			defer wg.Done()
+			if _, err, _ := d.DoContextFunc(ctx, key, fn); !errors.Is(err, context.Canceled) {
+				t.Errorf("err=%v, want %v", err, context.Canceled)
+			}
+		}()
+		time.Sleep(sleepJoin / 3)
+	}
+
+	// one caller left: the work keeps running
+	cancel1()
+	select {
+	case err := <-aborted:
+		t.Fatalf("work aborted with %v while a caller is waiting", err)
+	case <-time.After(sleepJoin):
+	}
+
+	// last caller left: the work is canceled and the flight forgotten
+	cancel2()
+	wg.Wait()
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("work err=%v, want %v", err, context.Canceled)
+		}
+	case <-time.After(sleepHold):
+		t.Fatal("work not canceled after all callers left")
+	}
+
+	if v, err, shared := d.Do(key, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || err != nil || shared {
+		t.Fatalf("v=%d err=%v shared=%v, want fresh flight", v, err, shared)
+	}
+}
+
+func TestGroupDoContextFuncKeepsWorkForDo(t *testing.T) {
+	var g Group[string, int]
+
+	ctx, cancel := context.WithCancel(t.Context())
+	fn := func(ctx context.Context) (int, error) {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(2 * sleepJoin):
+			return wantValueInt, nil
+		}
+	}
+
+	go g.DoContextFunc(ctx, keyA, fn)
+	time.Sleep(sleepJoin / 3)
+
+	done := make(chan Result[int], 1)
+	go func() {
+		v, err, shared := g.Do(keyA, func() (int, error) { return 0, nil })
+		done <- Result[int]{Val: v, Err: err, Shared: shared}
+	}()
+	time.Sleep(sleepJoin / 3)
+
+	// a Do caller keeps waiting, so the work is not canceled
+	cancel()
+	if res := <-done; res.Val != wantValueInt || res.Err != nil || !res.Shared {
+		t.Fatalf("res=%+v, want %d shared", res, wantValueInt)
+	}
+}
+
+func TestGroupDetachedExecution(t *testing.T) {
+	g := NewGroup[string, int](WithDetachedExecution(sleepHold))
+	detachedExecutionCompletes(t, g, keyA)
+}
+
+func TestShardedGroupDetachedExecution(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithDetachedExecution(sleepHold)))
+	detachedExecutionCompletes(t, sg, keyB)
+}
+
+func detachedExecutionCompletes[T ~string](t *testing.T, d contextFuncDoer[T, int], key T) {
+	t.Helper()
+
+	var calls atomic.Int32
+	fn := func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(sleepJoin):
+			return wantValueInt, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin/3)
+	defer cancel()
+	if _, err, _ := d.DoContextFunc(ctx, key, fn); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// nobody is waiting anymore: the work keeps running and new callers join it
+	if v, err, shared := d.DoContextFunc(t.Context(), key, fn); v != wantValueInt || err != nil || !shared {
+		t.Fatalf("v=%d err=%v shared=%v, want shared %d", v, err, shared, wantValueInt)
+	}
+
+	// the result of an execution nobody waited for is retained
+	ctx, cancel = context.WithTimeout(t.Context(), sleepJoin/3)
+	defer cancel()
+	d.Forget(key)
+	d.DoContextFunc(ctx, key, fn)
+	time.Sleep(sleepJoin)
+	if v, err, shared := d.Do(key, func() (int, error) { return 0, nil }); v != wantValueInt || err != nil || !shared {
+		t.Fatalf("v=%d err=%v shared=%v, want retained %d", v, err, shared, wantValueInt)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls=%d, want 2", got)
+	}
+}
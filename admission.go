@@ -0,0 +1,50 @@
+package singleflight
+
+import "time"
+
+// inflight returns the call in flight for fk, if callers may still join it.
+// Calls running longer than the maximum share duration configured via
+// WithMaxShareDuration are not joined. The caller must hold g.mu.
+func (g *Group[K, V]) inflight(fk flightKey[K]) (*call[V], bool) {
+	c, ok := g.m[fk]
+	if !ok {
+		return nil, false
+	}
+
+	if maxShare := g.settings().maxShareDuration; maxShare > 0 && time.Since(c.start) > maxShare {
+		return nil, false
+	}
+
+	return c, true
+}
+
+// admit decides whether a caller may join the in-flight call c, enforcing
+// the admission limits of the group. The caller must hold g.mu.
+func (g *Group[K, V]) admit(c *call[V]) error {
+	config := g.settings()
+
+	if config.maxWaiters > 0 && c.dups >= config.maxWaiters {
+		return ErrTooManyWaiters
+	}
+
+	if config.loadShed.overloaded(len(g.m), g.waiters) {
+		return ErrLoadShed
+	}
+
+	return nil
+}
+
+// checkKey rejects key if its textual form exceeds the maximum key length
+// of the group, see WithMaxKeyLen.
+func (g *Group[K, V]) checkKey(key K) error {
+	maxKeyLen := g.settings().maxKeyLen
+	if maxKeyLen <= 0 {
+		return nil
+	}
+
+	if n := len(keyString(key)); n > maxKeyLen {
+		return &KeyTooLongError{Len: n, Max: maxKeyLen}
+	}
+
+	return nil
+}
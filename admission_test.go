@@ -0,0 +1,189 @@
+package singleflight
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupMaxWaiters(t *testing.T) {
+	g := NewGroup[string, int](WithMaxWaiters(2))
+	maxWaitersRejects(t, g, keyA, 2)
+}
+
+func maxWaitersRejects[T ~string](t *testing.T, d doer[T, int], key T, maxWaiters int) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	// leader plus maxWaiters waiters
+	n := maxWaiters + 1
+	var wg sync.WaitGroup
+	wg.Add(n)
+	errs := make([]error, n)
+	for i := range n {
+		go func() {
+			defer wg.Done()
+			_, errs[i], _ = d.Do(key, fn)
+		}()
+		time.Sleep(sleepJoin / 3)
+	}
+
+	if _, err, _ := d.Do(key, fn); !errors.Is(err, ErrTooManyWaiters) {
+		t.Fatalf("Do err=%v, want %v", err, ErrTooManyWaiters)
+	}
+	if res := <-d.DoChan(key, fn); !errors.Is(res.Err, ErrTooManyWaiters) {
+		t.Fatalf("DoChan err=%v, want %v", res.Err, ErrTooManyWaiters)
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("errs[%d]=%v, want nil", i, err)
+		}
+	}
+
+	// once the call completed, the key accepts callers again
+	if _, err, _ := d.Do(key, fn); err != nil {
+		t.Fatalf("err after completion=%v, want nil", err)
+	}
+}
+
+func TestGroupLoadShedding(t *testing.T) {
+	g := NewGroup[string, int](WithLoadShedding(LoadShedPolicy{MaxInFlight: 2}))
+	loadSheddingShedsFollowers(t, g, keyA, keyB)
+}
+
+func loadSheddingShedsFollowers[T ~string](t *testing.T, d doer[T, int], key1, key2 T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	slow := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+	fast := func() (int, error) { return wantValueInt, nil }
+
+	// a leader and a follower on key1 while below the threshold
+	var wg sync.WaitGroup
+	wg.Add(3)
+	errs := make([]error, 3)
+	for i, key := range []T{key1, key1, key2} {
+		go func() {
+			defer wg.Done()
+			_, errs[i], _ = d.Do(key, slow)
+		}()
+		time.Sleep(sleepJoin / 3)
+	}
+
+	// two keys in flight: followers are shed, leaders are not
+	if _, err, _ := d.Do(key1, slow); !errors.Is(err, ErrLoadShed) {
+		t.Fatalf("follower err=%v, want %v", err, ErrLoadShed)
+	}
+	if res := <-d.DoChan(key2, slow); !errors.Is(res.Err, ErrLoadShed) {
+		t.Fatalf("follower DoChan err=%v, want %v", res.Err, ErrLoadShed)
+	}
+	if _, err, _ := d.Do(key1+key2, fast); err != nil {
+		t.Fatalf("leader err=%v, want nil", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("errs[%d]=%v, want nil", i, err)
+		}
+	}
+}
+
+func TestGroupMaxKeyLen(t *testing.T) {
+	g := NewGroup[string, int](WithMaxKeyLen(8))
+	maxKeyLenRejects(t, g, 8)
+}
+
+func TestShardedGroupMaxKeyLen(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithMaxKeyLen(8)))
+	maxKeyLenRejects(t, sg, 8)
+}
+
+func maxKeyLenRejects[T ~string](t *testing.T, d doer[T, int], maxLen int) {
+	t.Helper()
+
+	var calls int
+	fn := func() (int, error) {
+		calls++
+		return wantValueInt, nil
+	}
+
+	if _, err, _ := d.Do(T(strings.Repeat("k", maxLen)), fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+
+	long := T(strings.Repeat("k", maxLen+1))
+	_, err, _ := d.Do(long, fn)
+
+	var tooLong *KeyTooLongError
+	if !errors.As(err, &tooLong) || !errors.Is(err, ErrKeyTooLong) {
+		t.Fatalf("err=%v, want %T matching %v", err, tooLong, ErrKeyTooLong)
+	}
+	if tooLong.Len != maxLen+1 || tooLong.Max != maxLen {
+		t.Fatalf("Len=%d Max=%d, want %d %d", tooLong.Len, tooLong.Max, maxLen+1, maxLen)
+	}
+	if res := <-d.DoChan(long, fn); !errors.Is(res.Err, ErrKeyTooLong) {
+		t.Fatalf("DoChan err=%v, want %v", res.Err, ErrKeyTooLong)
+	}
+
+	if calls != 1 {
+		t.Fatalf("calls=%d, want 1", calls)
+	}
+}
+
+func TestGroupMaxShareDuration(t *testing.T) {
+	g := NewGroup[string, int](WithMaxShareDuration(sleepJoin))
+	maxShareDurationDetaches(t, g, keyA)
+}
+
+func TestShardedGroupMaxShareDuration(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithMaxShareDuration(sleepJoin)))
+	maxShareDurationDetaches(t, sg, keyB)
+}
+
+func maxShareDurationDetaches[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			<-release
+		}
+		return int(n), nil
+	}
+
+	old := d.DoChan(key, fn)
+	joined := d.DoChan(key, fn)
+
+	// callers arriving after the share duration start their own execution
+	time.Sleep(2 * sleepJoin)
+	if v, _, shared := d.Do(key, fn); v != 2 || shared {
+		t.Fatalf("v=%d shared=%v, want new execution 2", v, shared)
+	}
+
+	close(release)
+	for _, ch := range []<-chan Result[int]{old, joined} {
+		if res := <-ch; res.Val != 1 {
+			t.Fatalf("old flight: %+v, want 1", res)
+		}
+	}
+}
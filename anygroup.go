@@ -0,0 +1,85 @@
+package singleflight
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// AnyGroup is a single coalescing domain for calls producing values of
+// different types, e.g. for plugin-style systems. Values are accessed in a
+// typed manner via Get, and keys may be bound to a value type up front via
+// Register.
+//
+// The zero value is ready to use; use NewAnyGroup to configure one.
+type AnyGroup[K comparable] struct {
+	group Group[K, any]
+	types sync.Map // K -> *V, see typeOf
+}
+
+// NewAnyGroup constructs an AnyGroup configured by opts.
+func NewAnyGroup[K comparable](opts ...GroupConfigOption) *AnyGroup[K] {
+	g := &AnyGroup[K]{}
+	g.group.configure(opts...)
+
+	return g
+}
+
+// Register binds key to the value type V. Subsequent calls to Get for key
+// with another value type fail with a *TypeMismatchError, which matches
+// ErrTypeMismatch, without joining or starting a flight. Registering a key
+// again with the same value type is a no-op, with another value type it
+// fails with a *TypeMismatchError.
+func Register[K comparable, V any](g *AnyGroup[K], key K) error {
+	want := typeOf[V]()
+
+	got, _ := g.types.LoadOrStore(key, want)
+	if got != want {
+		return &TypeMismatchError{Have: typeName(got), Want: typeName(want)}
+	}
+
+	return nil
+}
+
+// Get executes and deduplicates fn for key on g and returns its value as V.
+//
+// Behavior matches Group.Do. If key is registered with another value type,
+// or a caller joins a flight of key producing another value type, Get
+// fails with a *TypeMismatchError describing both types instead of
+// returning the zero value.
+func Get[K comparable, V any](g *AnyGroup[K], key K, fn func() (V, error)) (v V, err error, shared bool) {
+	want := typeOf[V]()
+	if got, ok := g.types.Load(key); ok && got != want {
+		return v, &TypeMismatchError{Have: typeName(got), Want: typeName(want)}, false
+	}
+
+	val, err, shared := g.group.Do(key, func() (any, error) {
+		return fn()
+	})
+
+	// a nil value is only valid for interface types, whose zero value is
+	// nil as well
+	v, ok := val.(V)
+	if !ok && err == nil && (val != nil || any(v) != nil) {
+		return v, &TypeMismatchError{Have: fmt.Sprintf("%T", val), Want: typeName(want)}, shared
+	}
+
+	return v, err, shared
+}
+
+// Forget forgets the flight of key, see Group.Forget. Registrations of key
+// are kept.
+func (g *AnyGroup[K]) Forget(key K) bool {
+	return g.group.Forget(key)
+}
+
+// typeOf returns the token of the value type V: a nil *V, which only
+// equals the token of V.
+func typeOf[V any]() any {
+	return (*V)(nil)
+}
+
+// typeName returns the name of the value type of the token t.
+func typeName(t any) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", t), "*")
+}
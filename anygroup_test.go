@@ -0,0 +1,127 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type plugin struct{ name string }
+
+func TestAnyGroupGet(t *testing.T) {
+	var g AnyGroup[string]
+
+	n, err, _ := Get(&g, "count", func() (int, error) { return wantValueInt, nil })
+	if n != wantValueInt || err != nil {
+		t.Fatalf("n=%d err=%v, want %d nil", n, err, wantValueInt)
+	}
+
+	p, err, _ := Get(&g, "plugin", func() (*plugin, error) { return &plugin{name: "a"}, nil })
+	if p == nil || p.name != "a" || err != nil {
+		t.Fatalf("p=%v err=%v, want a nil", p, err)
+	}
+
+	errFn := errors.New("failed")
+	if _, err, _ := Get(&g, "count", func() (int, error) { return 0, errFn }); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+}
+
+func TestAnyGroupRegister(t *testing.T) {
+	g := NewAnyGroup[string]()
+
+	if err := Register[string, int](g, "count"); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if err := Register[string, int](g, "count"); err != nil {
+		t.Fatalf("re-register err=%v, want nil", err)
+	}
+	if err := Register[string, string](g, "count"); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("err=%v, want %v", err, ErrTypeMismatch)
+	}
+
+	called := false
+	_, err, _ := Get(g, "count", func() (string, error) {
+		called = true
+		return "", nil
+	})
+	if !errors.Is(err, ErrTypeMismatch) || called {
+		t.Fatalf("err=%v called=%v, want %v false", err, called, ErrTypeMismatch)
+	}
+}
+
+func TestAnyGroupJoinTypeMismatch(t *testing.T) {
+	var g AnyGroup[string]
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Get(&g, keyA, func() (int, error) {
+			<-release
+			return wantValueInt, nil
+		})
+	}()
+	time.Sleep(sleepJoin)
+
+	go func() {
+		time.Sleep(sleepJoin)
+		close(release)
+	}()
+
+	_, err, shared := Get(&g, keyA, func() (string, error) { return "", nil })
+	if !errors.Is(err, ErrTypeMismatch) || !shared {
+		t.Fatalf("err=%v shared=%v, want %v true", err, shared, ErrTypeMismatch)
+	}
+
+	var te *TypeMismatchError
+	if !errors.As(err, &te) || te.Have != "int" || te.Want != "string" {
+		t.Fatalf("err=%v, want *TypeMismatchError from int to string", err)
+	}
+
+	wg.Wait()
+}
+
+func TestAnyGroupNilValueMismatch(t *testing.T) {
+	var g AnyGroup[string]
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Get(&g, keyA, func() (error, error) { //nolint:revive
+			<-release
+			return nil, nil
+		})
+	}()
+	time.Sleep(sleepJoin)
+	time.AfterFunc(sleepJoin, func() { close(release) })
+
+	// a nil interface value does not silently become the zero int
+	if _, err, _ := Get(&g, keyA, func() (int, error) { return wantValueInt, nil }); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("err=%v, want %v", err, ErrTypeMismatch)
+	}
+
+	wg.Wait()
+}
+
+func TestAnyGroupTypeTokens(t *testing.T) {
+	if typeOf[int]() != typeOf[int]() || typeOf[int]() == typeOf[*int]() || typeOf[any]() == typeOf[error]() {
+		t.Fatal("type tokens do not identify their types")
+	}
+
+	for name, token := range map[string]any{
+		"int":                      typeOf[int](),
+		"*int":                     typeOf[*int](),
+		"[]string":                 typeOf[[]string](),
+		"interface {}":             typeOf[any](),
+		"singleflight.Result[int]": typeOf[Result[int]](),
+	} {
+		if got := typeName(token); got != name {
+			t.Errorf("typeName=%q, want %q", got, name)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package singleflight
+
+// DoAsync is like DoChan, but calls done with the result once the flight
+// completes instead of returning a channel, e.g. for fire-and-observe cache
+// warming. done is called on a goroutine of its own.
+func (g *Group[K, V]) DoAsync(key K, fn func() (V, error), done func(Result[V])) {
+	notifyAsync(g.DoChan(key, fn), done)
+}
+
+// DoAsync is the sharded variant of Group.DoAsync.
+func (sg *ShardedGroup[K, V]) DoAsync(key K, fn func() (V, error), done func(Result[V])) {
+	notifyAsync(sg.DoChan(key, fn), done)
+}
+
+// notifyAsync calls done with the result received on ch.
+func notifyAsync[V any](ch <-chan Result[V], done func(Result[V])) {
+	go func() {
+		done(<-ch)
+	}()
+}
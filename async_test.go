@@ -0,0 +1,55 @@
+package singleflight
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type asyncDoer[T ~string, V any] interface {
+	doer[T, V]
+	DoAsync(T, func() (V, error), func(Result[V]))
+}
+
+func TestGroupDoAsync(t *testing.T) {
+	var g Group[string, int]
+	doAsyncNotifies(t, &g, keyA)
+}
+
+func TestShardedGroupDoAsync(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	doAsyncNotifies(t, sg, keyB)
+}
+
+func doAsyncNotifies[T ~string](t *testing.T, d asyncDoer[T, int], key T) {
+	t.Helper()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	results := make(chan Result[int], numCallers)
+	for range numCallers {
+		d.DoAsync(key, fn, func(res Result[int]) { results <- res })
+	}
+	close(release)
+
+	for range numCallers {
+		select {
+		case res := <-results:
+			if res.Val != wantValueInt || res.Err != nil {
+				t.Fatalf("res=%+v, want %d", res, wantValueInt)
+			}
+		case <-time.After(sleepHold):
+			t.Fatal("callback not called")
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+}
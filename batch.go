@@ -0,0 +1,97 @@
+package singleflight
+
+import "fmt"
+
+// DoBatch executes and deduplicates the batch loader fn for keys. Keys
+// already in flight are joined, and fn is executed once for the remaining
+// keys, if any; its results are handed to every caller of the flights of
+// those keys, as if each key had been loaded by a flight of its own.
+//
+// The result of every key is returned by key. Keys for which fn returns no
+// value fail with ErrMissingResult, and if fn fails, every key it was
+// executed for fails with its error. As with Start, execution policies such
+// as rate limits and cost budgets do not apply to the batch.
+func (g *Group[K, V]) DoBatch(keys []K, fn func(keys []K) (map[K]V, error)) map[K]Result[V] {
+	return doBatch(keys, fn, g.Start)
+}
+
+// DoBatch is the sharded variant of Group.DoBatch. A single execution of
+// fn loads the keys of every shard.
+func (sg *ShardedGroup[K, V]) DoBatch(keys []K, fn func(keys []K) (map[K]V, error)) map[K]Result[V] {
+	return doBatch(keys, fn, sg.Start)
+}
+
+// doBatch implements DoBatch, starting or joining the flight of every key
+// via start.
+func doBatch[K comparable, V any](
+	keys []K, fn func(keys []K) (map[K]V, error), start func(key K) (*Completer[V], bool),
+) map[K]Result[V] {
+	flights := make(map[K]*Completer[V], len(keys))
+	var load []K
+
+	for _, key := range keys {
+		if _, ok := flights[key]; ok {
+			continue
+		}
+
+		fc, joined := start(key)
+		flights[key] = fc
+		if !joined {
+			load = append(load, key)
+		}
+	}
+
+	if len(load) > 0 {
+		loadBatch(load, fn, flights)
+	}
+
+	results := make(map[K]Result[V], len(flights))
+	for key, fc := range flights {
+		results[key] = fc.result()
+	}
+
+	return results
+}
+
+// loadBatch executes fn for keys and completes their flights with its
+// results. If fn panics, the flights are completed with a PanicError before
+// the panic is propagated, so their callers do not wait forever.
+func loadBatch[K comparable, V any](keys []K, fn func(keys []K) (map[K]V, error), flights map[K]*Completer[V]) {
+	normalReturn := false
+	defer func() {
+		if normalReturn {
+			return
+		}
+
+		// a nil recover means fn invoked runtime.Goexit, which proceeds
+		r := recover()
+		err := errGoexit
+		if r != nil {
+			err = newPanicError(r)
+		}
+
+		var zero V
+		for _, key := range keys {
+			flights[key].Complete(zero, err)
+		}
+
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	vals, err := fn(keys)
+	normalReturn = true
+
+	for _, key := range keys {
+		v, ok := vals[key]
+		switch {
+		case err != nil:
+			flights[key].Complete(v, err)
+		case !ok:
+			flights[key].Complete(v, fmt.Errorf("%w: %v", ErrMissingResult, key))
+		default:
+			flights[key].Complete(v, nil)
+		}
+	}
+}
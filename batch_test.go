@@ -0,0 +1,106 @@
+package singleflight
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type batchDoer[T ~string, V any] interface {
+	doer[T, V]
+	DoBatch([]T, func([]T) (map[T]V, error)) map[T]Result[V]
+}
+
+func TestGroupDoBatch(t *testing.T) {
+	var g Group[string, int]
+	doBatchJoins(t, &g)
+}
+
+func TestShardedGroupDoBatch(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithShardCount(4))
+	doBatchJoins(t, sg)
+}
+
+func doBatchJoins[T ~string](t *testing.T, d batchDoer[T, int]) {
+	t.Helper()
+
+	release := make(chan struct{})
+	inflight := d.DoChan("a", func() (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	var mu sync.Mutex
+	var loaded [][]T
+	fn := func(keys []T) (map[T]int, error) {
+		mu.Lock()
+		loaded = append(loaded, slices.Clone(keys))
+		mu.Unlock()
+
+		vals := make(map[T]int, len(keys))
+		for _, key := range keys {
+			if key != "c" {
+				vals[key] = len(key) + 1
+			}
+		}
+		return vals, nil
+	}
+
+	time.AfterFunc(sleepJoin, func() { close(release) })
+	results := d.DoBatch([]T{"a", "bb", "c", "bb"}, fn)
+
+	if len(loaded) != 1 || !slices.Equal(loaded[0], []T{"bb", "c"}) {
+		t.Fatalf("loaded=%v, want one batch of [bb c]", loaded)
+	}
+	if res := results["a"]; res.Val != 1 || res.Err != nil || !res.Shared {
+		t.Fatalf("a=%+v, want joined 1", res)
+	}
+	if res := results["bb"]; res.Val != 3 || res.Err != nil {
+		t.Fatalf("bb=%+v, want 3", res)
+	}
+	if res := results["c"]; !errors.Is(res.Err, ErrMissingResult) {
+		t.Fatalf("c err=%v, want %v", res.Err, ErrMissingResult)
+	}
+	if res := <-inflight; res.Val != 1 {
+		t.Fatalf("in-flight val=%d, want 1", res.Val)
+	}
+}
+
+func TestGroupDoBatchError(t *testing.T) {
+	var g Group[string, int]
+
+	errFn := errors.New("failed")
+	results := g.DoBatch([]string{keyA, keyB}, func([]string) (map[string]int, error) {
+		return nil, errFn
+	})
+	for key, res := range results {
+		if !errors.Is(res.Err, errFn) {
+			t.Fatalf("%s err=%v, want %v", key, res.Err, errFn)
+		}
+	}
+
+	// a panicking loader releases the callers of its flights
+	release := make(chan struct{})
+	recovered := make(chan any, 1)
+	go func() {
+		defer func() { recovered <- recover() }()
+		g.DoBatch([]string{keyA}, func([]string) (map[string]int, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	time.Sleep(sleepJoin)
+
+	ch := g.DoChan(keyA, func() (int, error) { return 0, nil })
+	close(release)
+
+	var pe *PanicError
+	if res := <-ch; !errors.As(res.Err, &pe) || pe.Value != "boom" {
+		t.Fatalf("err=%v, want *PanicError of boom", res.Err)
+	}
+	if r := <-recovered; r != "boom" {
+		t.Fatalf("recovered %v, want boom", r)
+	}
+}
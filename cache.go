@@ -0,0 +1,337 @@
+package singleflight
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// minCacheSweep is the number of cached entries below which expired
+// entries are not swept.
+const minCacheSweep = 64
+
+// CachedGroup keeps the results of completed flights for a TTL and serves
+// them without executing fn again, while concurrent calls for a key whose
+// result is missing or expired are still deduplicated by the underlying
+// Singleflighter.
+//
+// Only successful results are cached, unless errors are cached as well via
+// WithErrorTTL. With WithServeStale, the last successful result of a key
+// is served in place of a failed refresh. With WithMaxEntries, the least
+// recently used results are evicted once the cache is full. CachedGroup
+// implements Singleflighter.
+type CachedGroup[K comparable, V any] struct {
+	group   Singleflighter[K, V]
+	ttl     time.Duration
+	config  CacheConfig
+	onEvict func(key K, reason EvictionReason)
+
+	mu      sync.Mutex
+	entries map[K]*list.Element // of *cacheEntry[K, V]
+	lru     list.List           // most recently used first
+	flights map[K]*cacheFlight  // of keys with calls awaiting a flight
+	sweepAt int
+}
+
+// EvictionReason describes why a cached result was evicted, see
+// WithOnEvict.
+type EvictionReason int
+
+const (
+	// EvictedCapacity reports a result evicted as the least recently used
+	// one of a full cache, see WithMaxEntries.
+	EvictedCapacity EvictionReason = iota
+	// EvictedExpired reports a result dropped after it expired.
+	EvictedExpired
+)
+
+// String returns the name of r.
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictedCapacity:
+		return "capacity"
+	case EvictedExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// cacheEntry is a cached result of a CachedGroup.
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	val     V
+	err     error
+	expires time.Time
+}
+
+// cacheFlight tracks the calls of a CachedGroup awaiting the flights of a
+// key, so that their results are not cached once the key is forgotten.
+type cacheFlight struct {
+	waiting   int
+	forgotten bool
+}
+
+// eviction is a result evicted from a CachedGroup, reported to the
+// eviction callback once the lock is released.
+type eviction[K comparable] struct {
+	key    K
+	reason EvictionReason
+}
+
+// NewCachedGroup constructs a CachedGroup caching the results of group for
+// ttl, configured by opts. If group is nil, a new Group is used. It panics
+// if an eviction callback set via WithOnEvict does not match the key type.
+func NewCachedGroup[K comparable, V any](
+	group Singleflighter[K, V], ttl time.Duration, opts ...CacheConfigOption,
+) *CachedGroup[K, V] {
+	if group == nil {
+		group = &Group[K, V]{}
+	}
+
+	cg := &CachedGroup[K, V]{
+		group:   group,
+		ttl:     ttl,
+		entries: make(map[K]*list.Element),
+		flights: make(map[K]*cacheFlight),
+		sweepAt: minCacheSweep,
+	}
+
+	for _, opt := range opts {
+		opt(&cg.config)
+	}
+
+	if cg.config.onEvict != nil {
+		onEvict, ok := cg.config.onEvict.(func(K, EvictionReason))
+		if !ok {
+			panic(fmt.Sprintf("singleflight: eviction callback %T does not match key type %s",
+				cg.config.onEvict, typeName(typeOf[K]())))
+		}
+		cg.onEvict = onEvict
+	}
+
+	return cg
+}
+
+// Do returns the cached result of key, if any, or executes and
+// deduplicates fn for key and caches its result.
+//
+// Behavior matches the Do method of the underlying Singleflighter. Results
+// served from the cache are reported as shared. Stale results served in
+// place of a failed execution are returned without error; use DoChan to
+// tell them apart via Result.Stale.
+func (cg *CachedGroup[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	if e, ok := cg.lookup(key); ok {
+		return e.val, e.err, true
+	}
+
+	return cg.Refresh(key, fn)
+}
+
+// Refresh executes and deduplicates fn for key like Do, bypassing the cached
+// result of key, and caches its result. It joins a flight of key in
+// progress. Refresh implements Refresher.
+func (cg *CachedGroup[K, V]) Refresh(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	flight := cg.await(key)
+	v, err, shared = cg.group.Do(key, fn)
+	res := cg.settle(key, flight, Result[V]{Val: v, Err: err, Shared: shared})
+
+	return res.Val, res.Err, res.Shared
+}
+
+// DoChan is the channel-based variant of Do.
+func (cg *CachedGroup[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	ch := make(chan Result[V], 1)
+	if e, ok := cg.lookup(key); ok {
+		ch <- Result[V]{Val: e.val, Err: e.err, Shared: true}
+		return ch
+	}
+
+	flight := cg.await(key)
+	base := cg.group.DoChan(key, fn)
+
+	go func() {
+		ch <- cg.settle(key, flight, <-base)
+	}()
+
+	return ch
+}
+
+// DoContext is like Do, but stops waiting when ctx is done, returning
+// ctx.Err() while the execution continues for the other callers. The
+// result of the execution is cached either way.
+func (cg *CachedGroup[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return waitContext(ctx, cg.DoChan(key, fn))
+}
+
+// Forget drops the cached result of key and forgets its flight, so the
+// next call for key executes fn again. Results of flights in progress are
+// not cached. Forgotten results are not reported as evicted. Forget reports
+// whether there was a cached result or flight of key.
+func (cg *CachedGroup[K, V]) Forget(key K) bool {
+	cg.mu.Lock()
+	el, cached := cg.entries[key]
+	if cached {
+		cg.remove(el)
+	}
+	if flight, ok := cg.flights[key]; ok {
+		flight.forgotten = true
+		delete(cg.flights, key)
+	}
+	cg.mu.Unlock()
+
+	return cg.group.Forget(key) || cached
+}
+
+// lookup returns the unexpired cached result of key, if any, and marks it
+// as recently used.
+func (cg *CachedGroup[K, V]) lookup(key K) (e cacheEntry[K, V], ok bool) {
+	var evicted []eviction[K]
+	defer func() { cg.evicted(evicted) }()
+
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+
+	el, ok := cg.entries[key]
+	if !ok {
+		return e, false
+	}
+
+	entry := el.Value.(*cacheEntry[K, V]) //nolint:forcetypeassert
+	if now := time.Now(); !now.Before(entry.expires) {
+		if !cg.retained(entry, now) {
+			cg.remove(el)
+			evicted = append(evicted, eviction[K]{key: key, reason: EvictedExpired})
+		}
+
+		return e, false
+	}
+
+	cg.lru.MoveToFront(el)
+
+	return *entry, true
+}
+
+// retained reports whether the expired entry e is still kept at now to be
+// served stale, see WithServeStale.
+func (cg *CachedGroup[K, V]) retained(e *cacheEntry[K, V], now time.Time) bool {
+	return e.err == nil && now.Before(e.expires.Add(cg.config.staleFor))
+}
+
+// await registers a call awaiting a flight of key and returns the flight
+// to settle it with. Results of flights of key awaited before key is
+// forgotten are not cached.
+func (cg *CachedGroup[K, V]) await(key K) *cacheFlight {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+
+	flight, ok := cg.flights[key]
+	if !ok {
+		flight = new(cacheFlight)
+		cg.flights[key] = flight
+	}
+	flight.waiting++
+
+	return flight
+}
+
+// settle caches the result res of a flight of key awaited via flight,
+// unless errors are not cached or key was forgotten since, and returns the
+// result to deliver. A failed result is replaced by the last
+// successful result of key if it may be served stale.
+func (cg *CachedGroup[K, V]) settle(key K, flight *cacheFlight, res Result[V]) Result[V] {
+	var evicted []eviction[K]
+	defer func() { cg.evicted(evicted) }()
+
+	now := time.Now()
+
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+
+	flight.waiting--
+	if flight.waiting == 0 && cg.flights[key] == flight {
+		delete(cg.flights, key)
+	}
+	if flight.forgotten {
+		return res
+	}
+
+	el, cached := cg.entries[key]
+	if res.Err != nil && cached {
+		if e := el.Value.(*cacheEntry[K, V]); cg.retained(e, now) { //nolint:forcetypeassert
+			return Result[V]{Val: e.val, Shared: res.Shared, Stale: true}
+		}
+	}
+
+	ttl := cg.ttl
+	if res.Err != nil {
+		ttl = cg.errorTTL(res.Err)
+	}
+	if ttl <= 0 {
+		return res
+	}
+
+	entry := &cacheEntry[K, V]{key: key, val: res.Val, err: res.Err, expires: now.Add(ttl)}
+	if cached {
+		el.Value = entry
+		cg.lru.MoveToFront(el)
+	} else {
+		cg.entries[key] = cg.lru.PushFront(entry)
+	}
+
+	// evict the least recently used entries beyond the capacity
+	for limit := cg.config.maxEntries; limit > 0 && len(cg.entries) > limit; {
+		back := cg.lru.Back()
+		cg.remove(back)
+		evicted = append(evicted, eviction[K]{
+			key:    back.Value.(*cacheEntry[K, V]).key, //nolint:forcetypeassert
+			reason: EvictedCapacity,
+		})
+	}
+
+	// sweep expired entries whenever the cache has doubled in size
+	if len(cg.entries) >= cg.sweepAt {
+		for k, el := range cg.entries {
+			if e := el.Value.(*cacheEntry[K, V]); !now.Before(e.expires) && !cg.retained(e, now) { //nolint:forcetypeassert
+				cg.remove(el)
+				evicted = append(evicted, eviction[K]{key: k, reason: EvictedExpired})
+			}
+		}
+		cg.sweepAt = max(2*len(cg.entries), minCacheSweep)
+	}
+
+	return res
+}
+
+// remove removes the cached entry el. The caller must hold cg.mu.
+func (cg *CachedGroup[K, V]) remove(el *list.Element) {
+	cg.lru.Remove(el)
+	delete(cg.entries, el.Value.(*cacheEntry[K, V]).key) //nolint:forcetypeassert
+}
+
+// evicted reports the evicted entries to the eviction callback, if any.
+// The caller must not hold cg.mu.
+func (cg *CachedGroup[K, V]) evicted(evicted []eviction[K]) {
+	if cg.onEvict == nil {
+		return
+	}
+
+	for _, ev := range evicted {
+		cg.onEvict(ev.key, ev.reason)
+	}
+}
+
+// errorTTL returns the TTL err is cached for, zero if it is not cached.
+func (cg *CachedGroup[K, V]) errorTTL(err error) time.Duration {
+	if cg.config.errorTTL == nil ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0
+	}
+
+	return cg.config.errorTTL(err)
+}
@@ -0,0 +1,259 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedGroupDo(t *testing.T) {
+	cg := NewCachedGroup[string, int](nil, sleepHold)
+
+	var calls int32
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(sleepJoin)
+		return wantValueInt, nil
+	}
+
+	// concurrent misses share one execution
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	for range numCallers {
+		go func() {
+			defer wg.Done()
+			if v, err, _ := cg.Do(keyA, fn); v != wantValueInt || err != nil {
+				t.Errorf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// hits are served from the cache
+	if v, err, shared := cg.Do(keyA, fn); v != wantValueInt || err != nil || !shared {
+		t.Fatalf("v=%d err=%v shared=%v, want cached %d", v, err, shared, wantValueInt)
+	}
+	if res := <-cg.DoChan(keyA, fn); res.Val != wantValueInt || !res.Shared {
+		t.Fatalf("res=%+v, want cached %d", res, wantValueInt)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+
+	// expired results are refreshed
+	time.Sleep(sleepHold)
+	cg.Do(keyA, fn)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("calls after expiry=%d, want 2", got)
+	}
+}
+
+func TestCachedGroupErrors(t *testing.T) {
+	var calls int32
+	errFn := errors.New("failed")
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, errFn
+	}
+
+	cg := NewCachedGroup[string, int](nil, sleepHold)
+	cg.Do(keyA, fn)
+	if _, err, _ := cg.Do(keyA, fn); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("calls=%d, want 2 with uncached errors", got)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	cg = NewCachedGroup[string, int](nil, sleepHold, WithErrorTTL(sleepHold))
+	cg.Do(keyA, fn)
+	if _, err, shared := cg.Do(keyA, fn); !errors.Is(err, errFn) || !shared {
+		t.Fatalf("err=%v shared=%v, want cached %v", err, shared, errFn)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1 with cached errors", got)
+	}
+}
+
+func TestCachedGroupForget(t *testing.T) {
+	cg := NewCachedGroup[string, int](nil, time.Minute)
+
+	release := make(chan struct{})
+	ch := cg.DoChan(keyA, func() (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	// the result of a forgotten flight is not cached
+	cg.Forget(keyA)
+	close(release)
+	<-ch
+
+	if v, _, shared := cg.Do(keyA, func() (int, error) { return 2, nil }); v != 2 || shared {
+		t.Fatalf("v=%d shared=%v, want fresh 2", v, shared)
+	}
+
+	cg.Forget(keyA)
+	if v, _, _ := cg.Do(keyA, func() (int, error) { return 3, nil }); v != 3 {
+		t.Fatalf("v=%d after Forget, want 3", v)
+	}
+}
+
+func TestCachedGroupForgetOtherKey(t *testing.T) {
+	cg := NewCachedGroup[string, int](nil, time.Minute)
+
+	release := make(chan struct{})
+	ch := cg.DoChan(keyA, func() (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	// forgetting another key does not keep the result of keyA from being
+	// cached
+	cg.Forget(keyB)
+	close(release)
+	<-ch
+
+	if v, _, shared := cg.Do(keyA, func() (int, error) { return 2, nil }); v != 1 || !shared {
+		t.Fatalf("v=%d shared=%v, want cached 1", v, shared)
+	}
+	if n := len(cg.flights); n != 0 {
+		t.Fatalf("flights=%d once settled, want 0", n)
+	}
+}
+
+func TestCachedGroupServeStale(t *testing.T) {
+	cg := NewCachedGroup[string, int](nil, sleepJoin, WithServeStale(sleepHold))
+
+	errFn := errors.New("failed")
+	failing := func() (int, error) { return 0, errFn }
+
+	cg.Do(keyA, func() (int, error) { return wantValueInt, nil })
+	time.Sleep(sleepJoin)
+
+	// an expired result is refreshed, and served stale if that fails
+	res := <-cg.DoChan(keyA, failing)
+	if res.Val != wantValueInt || res.Err != nil || !res.Stale {
+		t.Fatalf("res=%+v, want stale %d", res, wantValueInt)
+	}
+	if v, err, _ := cg.Do(keyA, failing); v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want stale %d", v, err, wantValueInt)
+	}
+
+	// beyond the stale window the error is returned
+	time.Sleep(sleepHold)
+	if _, err, _ := cg.Do(keyA, failing); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+
+	// keys without a previous result fail as usual
+	if _, err, _ := cg.Do(keyB, failing); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+}
+
+func TestCachedGroupErrorTTLFunc(t *testing.T) {
+	errNotFound := errors.New("not found")
+	cg := NewCachedGroup[string, int](nil, time.Minute, WithErrorTTLFunc(func(err error) time.Duration {
+		if errors.Is(err, errNotFound) {
+			return time.Minute
+		}
+		return 0
+	}))
+
+	var calls int32
+	fail := func(err error) func() (int, error) {
+		return func() (int, error) {
+			atomic.AddInt32(&calls, 1)
+			return 0, err
+		}
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		err  error
+		want int32
+	}{
+		{name: "cached error", key: "missing", err: errNotFound, want: 1},
+		{name: "uncached error", key: "flaky", err: errors.New("timeout"), want: 2},
+		{name: "cancellation", key: "canceled", err: context.Canceled, want: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			for range 2 {
+				if _, err, _ := cg.Do(tc.key, fail(tc.err)); !errors.Is(err, tc.err) {
+					t.Fatalf("err=%v, want %v", err, tc.err)
+				}
+			}
+			if got := atomic.LoadInt32(&calls); got != tc.want {
+				t.Fatalf("calls=%d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCachedGroupMaxEntries(t *testing.T) {
+	type evicted struct {
+		key    string
+		reason EvictionReason
+	}
+
+	var got []evicted
+	cg := NewCachedGroup[string, int](nil, sleepJoin,
+		WithMaxEntries(2),
+		WithOnEvict(func(key string, reason EvictionReason) {
+			got = append(got, evicted{key, reason})
+		}),
+	)
+
+	value := func(v int) func() (int, error) {
+		return func() (int, error) { return v, nil }
+	}
+
+	cg.Do("a", value(1))
+	cg.Do("b", value(2))
+	cg.Do("a", value(0)) // a is now the most recently used
+	cg.Do("c", value(3))
+
+	if v, _, shared := cg.Do("a", value(0)); v != 1 || !shared {
+		t.Fatalf("a=%d shared=%v, want cached 1", v, shared)
+	}
+	if v, _, shared := cg.Do("b", value(4)); v != 4 || shared {
+		t.Fatalf("b=%d shared=%v, want fresh 4 after eviction", v, shared)
+	}
+
+	time.Sleep(sleepJoin)
+	cg.Do("b", value(5))
+
+	want := []evicted{
+		{"b", EvictedCapacity},
+		{"c", EvictedCapacity},
+		{"b", EvictedExpired},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("evicted=%v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("evicted=%v, want %v", got, want)
+		}
+	}
+}
+
+func TestCachedGroupOnEvictOfAnotherType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "func(int, singleflight.EvictionReason)") {
+			t.Fatalf("recovered %v, want panic naming the callback", r)
+		}
+	}()
+	NewCachedGroup[string, int](nil, time.Minute, WithOnEvict(func(int, EvictionReason) {}))
+}
@@ -0,0 +1,182 @@
+// Command singleflight-inspect renders the flights in progress on the
+// singleflight groups of a live service, as served by the handler of
+// package sfdebug.
+//
+// Usage:
+//
+//	singleflight-inspect [-url URL] [-group NAME] [-top N] [-watch INTERVAL]
+//
+// Without -watch, a single snapshot is rendered. With -watch, the snapshot
+// is refreshed at the given interval until interrupted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iwpnd/singleflightx/sfdebug"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+func main() {
+	var (
+		endpoint = flag.String("url", "http://localhost:6060"+sfdebug.DefaultPath, "URL of the debug handler")
+		group    = flag.String("group", "", "only show the named group")
+		top      = flag.Int("top", 10, "number of hot keys to show per group")
+		watch    = flag.Duration("watch", 0, "refresh interval; 0 renders a single snapshot")
+	)
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Stdout, *endpoint, *group, *top, *watch); err != nil {
+		fmt.Fprintln(os.Stderr, "singleflight-inspect:", err)
+		os.Exit(1)
+	}
+}
+
+// run renders snapshots of endpoint to w, once or every watch interval
+// until ctx is done.
+func run(ctx context.Context, w io.Writer, endpoint, group string, top int, watch time.Duration) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	for {
+		snap, err := fetch(ctx, client, endpoint, group)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+
+			return err
+		}
+
+		if watch > 0 {
+			fmt.Fprint(w, clearScreen)
+		}
+		render(w, snap, top)
+
+		if watch <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watch):
+		}
+	}
+}
+
+// fetch retrieves a snapshot from endpoint, restricted to group if set.
+func fetch(ctx context.Context, client *http.Client, endpoint, group string) (sfdebug.Snapshot, error) {
+	var snap sfdebug.Snapshot
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return snap, fmt.Errorf("parse url: %w", err)
+	}
+	if group != "" {
+		q := u.Query()
+		q.Set("group", group)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return snap, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return snap, fmt.Errorf("query %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return snap, fmt.Errorf("query %s: %s", u, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		return snap, fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	return snap, nil
+}
+
+// render writes snap to w, showing up to top hot keys per group.
+func render(w io.Writer, snap sfdebug.Snapshot, top int) {
+	fmt.Fprintf(w, "snapshot at %s\n", snap.Time.Format(time.RFC3339))
+
+	for _, g := range snap.Groups {
+		waiters := 0
+		for _, f := range g.Flights {
+			waiters += f.Waiters
+		}
+
+		fmt.Fprintf(w, "\n%s: %d in flight, %d waiting", g.Name, len(g.Flights), waiters)
+		if g.Stats != nil {
+			fmt.Fprintf(w, ", %d calls, %d executions, dedupe %.1f%%",
+				g.Stats.Calls, g.Stats.Executions, 100*g.DedupeRatio)
+		}
+		fmt.Fprintln(w)
+
+		if len(g.Flights) > 0 {
+			sharded := len(g.Shards) > 0
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprint(tw, "  KEY\tLANE\tWAITERS\tAGE")
+			if sharded {
+				fmt.Fprint(tw, "\tSHARD")
+			}
+			fmt.Fprintln(tw)
+			for i, f := range g.Flights {
+				if i == top {
+					fmt.Fprintf(tw, "  … %d more\t\t\t\n", len(g.Flights)-top)
+					break
+				}
+				fmt.Fprintf(tw, "  %q\t%s\t%d\t%s", f.Key, f.Lane, f.Waiters, f.Age.Round(time.Millisecond))
+				if sharded {
+					fmt.Fprintf(tw, "\t%d", f.Shard)
+				}
+				fmt.Fprintln(tw)
+			}
+			tw.Flush()
+		}
+
+		if len(g.Shards) > 0 {
+			fmt.Fprintln(w, "  shards:")
+			renderShards(w, g.Shards)
+		}
+	}
+}
+
+// renderShards writes a bar per shard, scaled to the busiest shard.
+func renderShards(w io.Writer, shards []int) {
+	const width = 40
+
+	busiest := 0
+	for _, n := range shards {
+		busiest = max(busiest, n)
+	}
+
+	for i, n := range shards {
+		bar := 0
+		if busiest > 0 {
+			bar = n * width / busiest
+		}
+		fmt.Fprintf(w, "  %4d %-*s %d\n", i, width, strings.Repeat("█", bar), n)
+	}
+}
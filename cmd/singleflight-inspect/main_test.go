@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+	"github.com/iwpnd/singleflightx/sfdebug"
+)
+
+func serve(t *testing.T, snap sfdebug.Snapshot) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		s := snap
+		if name := r.URL.Query().Get("group"); name != "" {
+			s.Groups = nil
+			for _, g := range snap.Groups {
+				if g.Name == name {
+					s.Groups = append(s.Groups, g)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(s)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func snapshot() sfdebug.Snapshot {
+	return sfdebug.Snapshot{
+		Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Groups: []sfdebug.GroupSnapshot{
+			{
+				Name: "search",
+				Flights: []singleflight.FlightInfo{
+					{Key: "q", Lane: "normal", Waiters: 3, Age: time.Second, Shard: 1},
+				},
+				Shards: []int{0, 1},
+			},
+			{
+				Name: "users",
+				Flights: []singleflight.FlightInfo{
+					{Key: "user:1", Lane: "normal", Waiters: 2, Age: 1500 * time.Millisecond},
+					{Key: "user:2", Lane: "priority", Waiters: 1, Age: time.Second},
+					{Key: "user:3", Lane: "fallback", Age: time.Second},
+				},
+				Stats:       &singleflight.GroupStats{Calls: 10, Executions: 4},
+				DedupeRatio: 0.6,
+			},
+		},
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv, _ := serve(t, snapshot())
+
+	snap, err := fetch(context.Background(), srv.Client(), srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Groups) != 2 || !snap.Time.Equal(snapshot().Time) {
+		t.Fatalf("snap=%+v, want 2 groups", snap)
+	}
+
+	snap, err = fetch(context.Background(), srv.Client(), srv.URL, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Groups) != 1 || snap.Groups[0].Name != "users" || len(snap.Groups[0].Flights) != 3 {
+		t.Fatalf("groups=%+v, want users only", snap.Groups)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	garbled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{"))
+	}))
+	defer garbled.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{"status", failing.URL, "500 Internal Server Error"},
+		{"body", garbled.URL, "decode snapshot"},
+		{"url", "http://[::1", "parse url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fetch(context.Background(), http.DefaultClient, tt.endpoint, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err=%v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	var b strings.Builder
+	render(&b, snapshot(), 2)
+	out := b.String()
+
+	for _, want := range []string{
+		"snapshot at 2024-01-02T03:04:05Z",
+		"search: 1 in flight, 3 waiting\n",
+		"users: 3 in flight, 3 waiting, 10 calls, 4 executions, dedupe 60.0%\n",
+		`"user:1"  normal    2        1.5s`,
+		`"user:2"  priority  1        1s`,
+		"… 1 more",
+		"SHARD",
+		"     1 " + strings.Repeat("█", 40) + " 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output misses %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "user:3") {
+		t.Errorf("output shows more than 2 keys:\n%s", out)
+	}
+}
+
+func TestRenderQuotesKeys(t *testing.T) {
+	snap := sfdebug.Snapshot{Groups: []sfdebug.GroupSnapshot{{
+		Name:    "users",
+		Flights: []singleflight.FlightInfo{{Key: "\x1b]0;pwned\a\x1b[2J", Lane: "normal"}},
+	}}}
+
+	var b strings.Builder
+	render(&b, snap, 10)
+	out := b.String()
+
+	if strings.ContainsAny(out, "\x1b\a") {
+		t.Fatalf("output contains raw control characters: %q", out)
+	}
+	if !strings.Contains(out, `"\x1b]0;pwned\a\x1b[2J"`) {
+		t.Fatalf("output misses quoted key: %q", out)
+	}
+}
+
+func TestRun(t *testing.T) {
+	srv, requests := serve(t, snapshot())
+
+	var b strings.Builder
+	if err := run(context.Background(), &b, srv.URL, "search", 10, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("requests=%d, want 1", n)
+	}
+	out := b.String()
+	if strings.Contains(out, clearScreen) || !strings.Contains(out, "search:") || strings.Contains(out, "users:") {
+		t.Fatalf("output=%q, want a single snapshot of search", out)
+	}
+}
+
+func TestRunWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 3 {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(snapshot())
+	}))
+	defer srv.Close()
+
+	var b strings.Builder
+	if err := run(ctx, &b, srv.URL, "", 10, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(b.String(), clearScreen); got != 2 {
+		t.Fatalf("screen cleared %d times, want 2", got)
+	}
+	if got := strings.Count(b.String(), "snapshot at"); got != 2 {
+		t.Fatalf("rendered %d snapshots, want 2", got)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	err := run(context.Background(), &strings.Builder{}, srv.URL, "", 10, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Fatalf("err=%v, want 404", err)
+	}
+}
@@ -0,0 +1,58 @@
+package singleflight
+
+import (
+	"errors"
+	"time"
+)
+
+// coalesced returns the call registered under fk that completed within the
+// coalescing window, if any, and counts the caller it is served to. The
+// caller must hold g.mu.
+func (g *Group[K, V]) coalesced(fk flightKey[K]) (*call[V], bool) {
+	c, ok := g.recent[fk]
+	if ok {
+		g.calls++
+		g.shared++
+		key, _ := keyOf(fk, c)
+		g.emit(EventWaiterJoined, key, c)
+	}
+
+	return c, ok
+}
+
+// retain keeps the completed call c, registered under fk, for the
+// coalescing window configured via WithCoalesceWindow, or the retention of
+// detached executions nobody waited for anymore (see
+// WithDetachedExecution), unless it panicked, exited, failed validation
+// (see WithValidator) or failed while callers must not receive its error
+// (see WithLeaderErrors and WithLeaderReelection). The caller must hold
+// g.mu.
+func (g *Group[K, V]) retain(c *call[V], fk flightKey[K]) {
+	window := g.settings().coalesceWindow
+	if c.interest == 0 {
+		window = max(window, g.settings().detachedRetain)
+	}
+	if window <= 0 || c.err == errGoexit || c.leaderFailed() || c.abdicated() { //nolint:errorlint
+		return
+	}
+	if errors.Is(c.err, ErrInvalidResult) {
+		return
+	}
+	if _, ok := c.err.(*PanicError); ok { //nolint:errorlint
+		return
+	}
+
+	if g.recent == nil {
+		g.recent = make(map[flightKey[K]]*call[V])
+	}
+	g.recent[fk] = c
+
+	time.AfterFunc(window, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if g.recent[fk] == c {
+			delete(g.recent, fk)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package singleflight
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupCoalesceWindow(t *testing.T) {
+	g := NewGroup[string, int](WithCoalesceWindow(sleepHold))
+	coalesceWindowShares(t, g, keyA)
+}
+
+func TestShardedGroupCoalesceWindow(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithCoalesceWindow(sleepHold)))
+	coalesceWindowShares(t, sg, keyB)
+}
+
+func coalesceWindowShares[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	var calls int32
+	fn := func() (int, error) {
+		return int(atomic.AddInt32(&calls, 1)), nil
+	}
+
+	if v, _, shared := d.Do(key, fn); v != 1 || shared {
+		t.Fatalf("v=%d shared=%v, want 1 not shared", v, shared)
+	}
+
+	// callers right after completion receive the completed result
+	if v, _, shared := d.Do(key, fn); v != 1 || !shared {
+		t.Fatalf("v=%d shared=%v, want coalesced 1", v, shared)
+	}
+	if res := <-d.DoChan(key, fn); res.Val != 1 || !res.Shared {
+		t.Fatalf("DoChan: %+v, want coalesced 1", res)
+	}
+
+	// Forget drops the completed result
+	d.Forget(key)
+	if v, _, _ := d.Do(key, fn); v != 2 {
+		t.Fatalf("v=%d after Forget, want 2", v)
+	}
+
+	// callers after the window start a new execution
+	time.Sleep(2 * sleepHold)
+	if v, _, shared := d.Do(key, fn); v != 3 || shared {
+		t.Fatalf("v=%d shared=%v after window, want 3 not shared", v, shared)
+	}
+}
+
+func TestGroupCoalesceWindowSkipsPanics(t *testing.T) {
+	g := NewGroup[string, int](WithCoalesceWindow(sleepHold), WithRecoverPanics())
+
+	g.Do(keyA, func() (int, error) { panic("boom") })
+
+	v, err, _ := g.Do(keyA, func() (int, error) { return wantValueInt, nil })
+	if v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want %d after panic", v, err, wantValueInt)
+	}
+	if stats := g.Stats(); stats.InFlight != 0 {
+		t.Fatalf("InFlight=%d, want completed flights not in flight", stats.InFlight)
+	}
+}
@@ -0,0 +1,101 @@
+package singleflight
+
+import "sync"
+
+// Completer completes a flight started via Group.Start.
+type Completer[V any] struct {
+	complete func(v V, err error)
+	c        *call[V]
+	once     sync.Once
+}
+
+// Start starts a flight for key that is completed later via the returned
+// Completer instead of by a work function, e.g. from a message consumer or
+// webhook callback. Other callers of Do, DoChan or Start for key join the
+// flight until it is completed.
+//
+// If a flight for key is already in flight, Start joins it and reports
+// joined; Complete of the returned Completer is then a no-op, and Wait
+// waits for the result of the flight. Execution policies such as rate
+// limits and cost budgets do not apply to flights started via Start.
+//
+// A flight that is never completed keeps its callers waiting; bound their
+// wait via DoContext or Forget the key to release new callers.
+func (g *Group[K, V]) Start(key K) (completer *Completer[V], joined bool) {
+	fk := g.flightKey(key, laneNormal)
+
+	defer func() {
+		if joined {
+			g.joined(key)
+		} else {
+			g.started(key)
+		}
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.m == nil {
+		g.m = make(map[flightKey[K]]*call[V])
+	}
+
+	if c, ok := g.coalesced(fk); ok {
+		return &Completer[V]{c: c}, true
+	}
+
+	if c, ok := g.inflight(fk); ok {
+		g.join(key, c)
+
+		return &Completer[V]{c: c}, true
+	}
+
+	c := g.newCall(key, fk)
+
+	return &Completer[V]{
+		c: c,
+		complete: func(v V, err error) {
+			if validate := g.validator(); validate != nil {
+				v, err = checkValid(validate, v, err)
+			}
+			c.val, c.err = g.checkResult(v, err)
+			g.observe(key, c)
+
+			g.mu.Lock()
+			defer g.mu.Unlock()
+
+			g.finish(c, key, fk)
+			g.deliver(c, fk)
+		},
+	}, false
+}
+
+// Complete completes the flight with v and err, handing them to every
+// caller of the flight. Only the first call of Complete on the Completer
+// that started the flight has an effect.
+func (fc *Completer[V]) Complete(v V, err error) {
+	if fc.complete == nil {
+		return
+	}
+
+	fc.once.Do(func() {
+		fc.complete(v, err)
+	})
+}
+
+// Wait waits for the flight to be completed and returns its result.
+func (fc *Completer[V]) Wait() (V, error) {
+	res := fc.result()
+	return res.Val, res.Err
+}
+
+// result waits for the flight to be completed and returns its result for
+// the caller that started or joined it.
+func (fc *Completer[V]) result() Result[V] {
+	fc.c.wg.Wait()
+
+	if fc.complete == nil {
+		return fc.c.joinedResult()
+	}
+
+	return fc.c.result(fc.c.dups > 0)
+}
@@ -0,0 +1,63 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupStart(t *testing.T) {
+	var g Group[string, int]
+
+	fc, joined := g.Start(keyA)
+	if joined {
+		t.Fatal("joined=true for new flight")
+	}
+
+	joiner, joined := g.Start(keyA)
+	if !joined {
+		t.Fatal("joined=false for in-flight key")
+	}
+	joiner.Complete(0, errors.New("ignored"))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var (
+		v      int
+		shared bool
+		res    Result[int]
+	)
+	go func() {
+		defer wg.Done()
+		v, _, shared = g.Do(keyA, func() (int, error) {
+			t.Error("fn executed for started flight")
+			return 0, nil
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		res = <-g.DoChan(keyA, func() (int, error) { return 0, nil })
+	}()
+	time.Sleep(sleepJoin)
+
+	// delivered from a callback
+	go fc.Complete(wantValueInt, nil)
+	wg.Wait()
+
+	if v != wantValueInt || !shared {
+		t.Fatalf("Do v=%d shared=%v, want %d true", v, shared, wantValueInt)
+	}
+	if res.Val != wantValueInt || !res.Shared {
+		t.Fatalf("DoChan res=%+v, want %d shared", res, wantValueInt)
+	}
+	if v, err := joiner.Wait(); v != wantValueInt || err != nil {
+		t.Fatalf("Wait v=%d err=%v, want %d nil", v, err, wantValueInt)
+	}
+
+	// only the first Complete has an effect, and the key is free again
+	fc.Complete(1, nil)
+	if v, _, shared := g.Do(keyA, func() (int, error) { return 2, nil }); v != 2 || shared {
+		t.Fatalf("v=%d shared=%v, want 2 false", v, shared)
+	}
+}
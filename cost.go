@@ -0,0 +1,124 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+)
+
+// CostPolicy determines what happens to an execution that does not fit into
+// the remaining cost budget of a group.
+type CostPolicy int
+
+const (
+	// CostReject fails the execution with ErrCostBudget.
+	CostReject CostPolicy = iota
+	// CostQueue makes the execution wait until enough budget is released.
+	// Queued executions are admitted in arrival order.
+	CostQueue
+)
+
+// costBudget is a weighted semaphore bounding the total cost of
+// concurrently executing flights.
+type costBudget struct {
+	mu      sync.Mutex
+	waiting []costWaiter
+
+	budget int64
+	used   int64
+	policy CostPolicy
+}
+
+// costWaiter is an execution queued for budget.
+type costWaiter struct {
+	ready chan struct{}
+	cost  int64
+}
+
+// costDerived is the cost of flights whose cost is derived via WithCostFn,
+// which happens once they execute, so only for the leader of a flight.
+const costDerived int64 = 0
+
+// costOf returns the cost of a flight for key started by Do or DoChan.
+// Costs below 1 count as 1.
+func (g *Group[K, V]) costOf(key K) int64 {
+	costFn := g.settings().costFn
+	if costFn == nil {
+		return 1
+	}
+
+	return max(costFn(keyString(key)), 1)
+}
+
+// acquire reserves cost from the budget, waiting for or failing with
+// ErrCostBudget according to the policy if the budget is exhausted. A
+// queued execution stops waiting once ctx is done, returning ctx.Err().
+func (b *costBudget) acquire(ctx context.Context, cost int64) error {
+	if cost > b.budget {
+		return ErrCostBudget
+	}
+
+	b.mu.Lock()
+
+	if len(b.waiting) == 0 && b.used+cost <= b.budget {
+		b.used += cost
+		b.mu.Unlock()
+
+		return nil
+	}
+
+	if b.policy != CostQueue {
+		b.mu.Unlock()
+		return ErrCostBudget
+	}
+
+	w := costWaiter{ready: make(chan struct{}), cost: cost}
+	b.waiting = append(b.waiting, w)
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.waiting {
+		if b.waiting[i].ready == w.ready {
+			b.waiting = append(b.waiting[:i], b.waiting[i+1:]...)
+			b.admit()
+
+			return ctx.Err()
+		}
+	}
+
+	// admitted while ctx was done
+	b.used -= cost
+	b.admit()
+
+	return ctx.Err()
+}
+
+// release returns cost to the budget and admits queued executions that fit
+// into the freed budget.
+func (b *costBudget) release(cost int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= cost
+	b.admit()
+}
+
+// admit admits queued executions, in arrival order, as long as they fit
+// into the remaining budget. The caller must hold b.mu.
+func (b *costBudget) admit() {
+	for len(b.waiting) > 0 && b.used+b.waiting[0].cost <= b.budget {
+		w := b.waiting[0]
+		b.waiting[0] = costWaiter{}
+		b.waiting = b.waiting[1:]
+
+		b.used += w.cost
+		close(w.ready)
+	}
+}
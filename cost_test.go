@@ -0,0 +1,202 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type costDoer[T ~string, V any] interface {
+	DoWithCost(T, int64, func() (V, error)) (V, error, bool)
+}
+
+func TestGroupCostBudgetReject(t *testing.T) {
+	g := NewGroup[string, int](WithCostBudget(10, CostReject))
+	costBudgetRejects(t, g)
+}
+
+func TestShardedGroupCostBudgetReject(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithCostBudget(10, CostReject)))
+	costBudgetRejects(t, sg)
+}
+
+func costBudgetRejects(t *testing.T, d costDoer[string, int]) {
+	t.Helper()
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.DoWithCost(keyA, 8, func() (int, error) {
+			<-release
+			return wantValueInt, nil
+		})
+	}()
+
+	// let the expensive flight start
+	time.Sleep(sleepJoin)
+
+	fn := func() (int, error) { return wantValueInt, nil }
+	if _, err, _ := d.DoWithCost(keyB, 3, fn); !errors.Is(err, ErrCostBudget) {
+		t.Fatalf("err=%v, want %v", err, ErrCostBudget)
+	}
+	if _, err, _ := d.DoWithCost(keyB, 2, fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if _, err, _ := d.DoWithCost(keyB, 11, fn); !errors.Is(err, ErrCostBudget) {
+		t.Fatalf("err=%v, want %v", err, ErrCostBudget)
+	}
+	if _, err, _ := d.DoWithCost(keyB, 10, fn); err != nil {
+		t.Fatalf("err after release=%v, want nil", err)
+	}
+}
+
+func TestGroupCostBudgetQueue(t *testing.T) {
+	g := NewGroup[string, int](WithCostBudget(10, CostQueue))
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.DoWithCost(keyA, 8, func() (int, error) {
+			<-release
+			return wantValueInt, nil
+		})
+	}()
+
+	// let the expensive flight start
+	time.Sleep(sleepJoin)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoWithCost(keyB, 5, func() (int, error) { return wantValueInt, nil })
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected flight to queue for budget")
+	case <-time.After(sleepJoin):
+	}
+
+	close(release)
+	wg.Wait()
+
+	if err := <-done; err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+}
+
+func TestGroupCostFn(t *testing.T) {
+	g := NewGroup[string, int](
+		WithCostBudget(10, CostReject),
+		WithCostFn(func(key string) int64 {
+			if strings.HasPrefix(key, "report:") {
+				return 20
+			}
+			return 1
+		}),
+	)
+
+	fn := func() (int, error) { return wantValueInt, nil }
+	if _, err, _ := g.Do("report:q3", fn); !errors.Is(err, ErrCostBudget) {
+		t.Fatalf("err=%v, want %v", err, ErrCostBudget)
+	}
+	if res := <-g.DoChan("report:q3", fn); !errors.Is(res.Err, ErrCostBudget) {
+		t.Fatalf("DoChan err=%v, want %v", res.Err, ErrCostBudget)
+	}
+	if _, err, _ := g.Do("cache:q3", fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+}
+
+func TestGroupCostBelowOne(t *testing.T) {
+	for name, cost := range map[string]int64{"zero": 0, "negative": -10} {
+		g := NewGroup[string, int](WithCostBudget(2, CostReject), WithCostFn(func(string) int64 { return cost }))
+
+		release := make(chan struct{})
+		blocking := func() (int, error) { <-release; return wantValueInt, nil }
+		a := g.DoChan(keyA, blocking)
+		b := make(chan struct{})
+		go func() { defer close(b); g.DoWithCost(keyB, cost, blocking) }()
+		time.Sleep(sleepJoin)
+
+		// both flights count as 1 against the budget of 2
+		if _, err, _ := g.DoWithCost("key-c", 1, func() (int, error) { return 0, nil }); !errors.Is(err, ErrCostBudget) {
+			t.Fatalf("%s: err=%v, want %v", name, err, ErrCostBudget)
+		}
+		close(release)
+		<-a
+		<-b
+	}
+}
+
+func TestGroupCostFnLeaderOnly(t *testing.T) {
+	var calls atomic.Int32
+	g := NewGroup[string, int](
+		WithCostBudget(10, CostReject),
+		WithCostFn(func(string) int64 { calls.Add(1); return 1 }),
+	)
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for range numCallers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Do(keyA, func() (int, error) { <-release; return wantValueInt, nil })
+		}()
+	}
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("cost function calls=%d, want 1", got)
+	}
+}
+
+func TestGroupCostBudgetQueueCanceled(t *testing.T) {
+	g := NewGroup[string, int](WithCostBudget(10, CostQueue))
+
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go func() {
+		defer close(held)
+		g.DoWithCost(keyA, 10, func() (int, error) { <-release; return wantValueInt, nil })
+	}()
+	time.Sleep(sleepJoin)
+
+	// the queued flight is abandoned by its only caller
+	var ran atomic.Bool
+	ctx, cancel := context.WithTimeout(context.Background(), sleepJoin)
+	defer cancel()
+	if _, err, _ := g.DoContextFunc(ctx, keyB, func(context.Context) (int, error) {
+		ran.Store(true)
+		return wantValueInt, nil
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+	time.Sleep(sleepJoin / 3)
+
+	close(release)
+	<-held
+	time.Sleep(sleepJoin / 3)
+
+	if ran.Load() {
+		t.Fatal("abandoned flight executed once budget was released")
+	}
+	if _, err, _ := g.DoWithCost("key-c", 10, func() (int, error) { return wantValueInt, nil }); err != nil {
+		t.Fatalf("err=%v, want the whole budget released", err)
+	}
+}
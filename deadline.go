@@ -0,0 +1,98 @@
+package singleflight
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDeadlineSmoothing is the weight of the latest execution duration
+// in the moving average used by WithDeadlineAwareJoins when no smoothing
+// is configured.
+const DefaultDeadlineSmoothing = 0.2
+
+// DefaultDeadlinePatterns is the number of patterns whose execution
+// durations are averaged by WithDeadlineAwareJoins when no bound is
+// configured.
+const DefaultDeadlinePatterns = 1024
+
+// DeadlinePolicy configures deadline-aware join rejection.
+type DeadlinePolicy struct {
+	// Pattern maps a key to the pattern whose execution durations are
+	// averaged, e.g. to strip IDs from keys of the same kind. If nil, every
+	// key is tracked on its own.
+	Pattern func(key string) string
+	// Smoothing is the weight of the latest execution duration in the
+	// exponential moving average, in (0, 1]. Values outside of that range
+	// select DefaultDeadlineSmoothing.
+	Smoothing float64
+	// MaxPatterns bounds the number of patterns whose averages are kept.
+	// Once it is reached, the average of an arbitrary pattern is dropped
+	// for each new one. Values below one select DefaultDeadlinePatterns.
+	MaxPatterns int
+}
+
+// latencyAverages holds the moving average of execution durations per
+// pattern. It is guarded by the mutex of the owning group.
+type latencyAverages map[string]time.Duration
+
+// pattern returns the pattern key is averaged under.
+func (p *DeadlinePolicy) pattern(key string) string {
+	if p.Pattern == nil {
+		return key
+	}
+
+	return p.Pattern(key)
+}
+
+// record folds the duration of a completed execution for key into the
+// moving average of its pattern, making room for a new pattern if
+// policy.MaxPatterns are tracked already.
+func (la *latencyAverages) record(policy *DeadlinePolicy, key string, elapsed time.Duration) {
+	if *la == nil {
+		*la = make(latencyAverages)
+	}
+
+	pattern := policy.pattern(key)
+
+	avg, ok := (*la)[pattern]
+	if !ok {
+		if limit := policy.MaxPatterns; limit > 0 && len(*la) >= limit {
+			for evicted := range *la {
+				delete(*la, evicted)
+				break
+			}
+		}
+		(*la)[pattern] = elapsed
+		return
+	}
+	(*la)[pattern] = avg + time.Duration(policy.Smoothing*float64(elapsed-avg))
+}
+
+// admitDeadline returns ErrInsufficientDeadline if a flight for key is in
+// progress and is expected to complete after the deadline of ctx.
+func (g *Group[K, V]) admitDeadline(ctx context.Context, key K) error {
+	policy := g.settings().deadlineAware
+	if policy == nil {
+		return nil
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, inFlight := g.inflight(g.flightKey(key, laneNormal))
+	if !inFlight {
+		return nil
+	}
+
+	avg, known := g.averages[policy.pattern(keyString(key))]
+	if known && c.start.Add(avg).After(deadline) {
+		return ErrInsufficientDeadline
+	}
+
+	return nil
+}
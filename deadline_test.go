@@ -0,0 +1,103 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type contextDoer[T ~string, V any] interface {
+	DoContext(context.Context, T, func() (V, error)) (V, error, bool)
+}
+
+func TestGroupDeadlineAwareJoins(t *testing.T) {
+	g := NewGroup[string, int](WithDeadlineAwareJoins(DeadlinePolicy{}))
+	deadlineAwareJoins(t, g, keyA)
+}
+
+func TestShardedGroupDeadlineAwareJoins(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(
+		WithDeadlineAwareJoins(DeadlinePolicy{}),
+	))
+	deadlineAwareJoins(t, sg, keyB)
+}
+
+func deadlineAwareJoins[T ~string](t *testing.T, d contextDoer[T, int], key T) {
+	t.Helper()
+
+	slow := func() (int, error) {
+		time.Sleep(4 * sleepHold)
+		return wantValueInt, nil
+	}
+
+	// nothing in flight: a short deadline is not rejected up front
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+	if _, err, _ := d.DoContext(ctx, key, slow); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// wait for the first flight to complete and seed the average
+	time.Sleep(4 * sleepHold)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.DoContext(t.Context(), key, slow)
+	}()
+	time.Sleep(sleepJoin)
+
+	short, cancelShort := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancelShort()
+	if _, err, _ := d.DoContext(short, key, slow); !errors.Is(err, ErrInsufficientDeadline) {
+		t.Fatalf("err=%v, want %v", err, ErrInsufficientDeadline)
+	}
+
+	long, cancelLong := context.WithTimeout(t.Context(), time.Second)
+	defer cancelLong()
+	if v, err, shared := d.DoContext(long, key, slow); err != nil || v != wantValueInt || !shared {
+		t.Fatalf("v=%d err=%v shared=%v, want %d nil true", v, err, shared, wantValueInt)
+	}
+
+	wg.Wait()
+}
+
+func TestLatencyAveragesPattern(t *testing.T) {
+	policy := &DeadlinePolicy{
+		Pattern:   func(key string) string { return key[:strings.IndexByte(key, ':')] },
+		Smoothing: 0.5,
+	}
+
+	var la latencyAverages
+	la.record(policy, "user:1", 100*time.Millisecond)
+	la.record(policy, "user:2", 200*time.Millisecond)
+	la.record(policy, "team:1", time.Second)
+
+	if got := la["user"]; got != 150*time.Millisecond {
+		t.Fatalf("average of user = %v, want %v", got, 150*time.Millisecond)
+	}
+	if got := la["team"]; got != time.Second {
+		t.Fatalf("average of team = %v, want %v", got, time.Second)
+	}
+}
+
+func TestLatencyAveragesMaxPatterns(t *testing.T) {
+	policy := &DeadlinePolicy{Smoothing: 0.5, MaxPatterns: 2}
+
+	var la latencyAverages
+	for i := range 10 {
+		la.record(policy, "user:"+strconv.Itoa(i), time.Millisecond)
+	}
+
+	if len(la) != 2 {
+		t.Fatalf("patterns=%d, want 2", len(la))
+	}
+	if _, ok := la["user:9"]; !ok {
+		t.Fatal("average of the latest key dropped")
+	}
+}
@@ -0,0 +1,208 @@
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrTenantQuota is returned when starting a flight would exceed the
+	// maximum number of concurrently executing flights of a tenant.
+	ErrTenantQuota = errors.New("singleflight: tenant quota exceeded")
+
+	// ErrTooManyWaiters is returned when a caller would join an in-flight
+	// call that already has the maximum number of waiters configured via
+	// WithMaxWaiters.
+	ErrTooManyWaiters = errors.New("singleflight: too many waiters")
+
+	// ErrLoadShed is returned to a caller that would join an in-flight call
+	// while the group is overloaded according to its LoadShedPolicy.
+	ErrLoadShed = errors.New("singleflight: load shed")
+
+	// ErrRateLimited is returned when a flight would exceed the execution
+	// budget configured for its key via WithRateLimit.
+	ErrRateLimited = errors.New("singleflight: rate limited")
+
+	// ErrCostBudget is returned when a flight does not fit into the cost
+	// budget configured via WithCostBudget.
+	ErrCostBudget = errors.New("singleflight: cost budget exceeded")
+
+	// ErrInsufficientDeadline is returned when a caller's context deadline
+	// is shorter than the expected remaining time of the in-flight call it
+	// would join; see WithDeadlineAwareJoins.
+	ErrInsufficientDeadline = errors.New("singleflight: insufficient deadline")
+
+	// ErrKeyTooLong is matched by the KeyTooLongError returned for keys
+	// exceeding the maximum length configured via WithMaxKeyLen.
+	ErrKeyTooLong = errors.New("singleflight: key too long")
+
+	// ErrResultTooLarge is matched by the ResultTooLargeError returned for
+	// results exceeding the maximum size configured via WithMaxResultSize.
+	ErrResultTooLarge = errors.New("singleflight: result too large")
+
+	// ErrTypeMismatch is matched by the *TypeMismatchError returned when a
+	// value of an AnyGroup is requested as another type.
+	ErrTypeMismatch = errors.New("singleflight: value type mismatch")
+
+	// ErrValueType is an alias of ErrTypeMismatch.
+	//
+	// Deprecated: Use ErrTypeMismatch.
+	ErrValueType = ErrTypeMismatch
+
+	// ErrExecutionTimeout is matched by the ExecutionTimeoutError returned
+	// for executions exceeding the timeout configured via
+	// WithExecutionTimeout.
+	ErrExecutionTimeout = errors.New("singleflight: execution timed out")
+
+	// ErrMissingResult is returned for keys a batch loader returned no
+	// value for, see Group.DoBatch.
+	ErrMissingResult = errors.New("singleflight: missing result")
+
+	// ErrShared is matched by the *SharedError returned to callers of a
+	// flight that failed while executed by another caller, see
+	// WithSharedErrors.
+	ErrShared = errors.New("singleflight: shared error")
+
+	// ErrLeaderFailed is matched by the *LeaderFailedError returned to
+	// callers of a flight that failed while executed by another caller, see
+	// WithLeaderErrors.
+	ErrLeaderFailed = errors.New("singleflight: leader failed")
+
+	// ErrInvalidResult is matched by the *ValidationError returned for
+	// results failing the validator configured via WithValidator.
+	ErrInvalidResult = errors.New("singleflight: invalid result")
+)
+
+// KeyTooLongError is returned for keys whose textual form exceeds the
+// maximum length configured via WithMaxKeyLen. It matches ErrKeyTooLong.
+type KeyTooLongError struct {
+	// Len is the length of the rejected key in bytes.
+	Len int
+	// Max is the configured maximum key length in bytes.
+	Max int
+}
+
+// Error implements error.
+func (e *KeyTooLongError) Error() string {
+	return fmt.Sprintf("singleflight: key of %d bytes exceeds maximum of %d", e.Len, e.Max)
+}
+
+// Unwrap returns ErrKeyTooLong.
+func (e *KeyTooLongError) Unwrap() error {
+	return ErrKeyTooLong
+}
+
+// ResultTooLargeError is returned for results whose size exceeds the
+// maximum configured via WithMaxResultSize. It matches ErrResultTooLarge.
+type ResultTooLargeError struct {
+	// Size is the size of the result as reported by the sizer.
+	Size int64
+	// Max is the configured maximum result size.
+	Max int64
+}
+
+// Error implements error.
+func (e *ResultTooLargeError) Error() string {
+	return fmt.Sprintf("singleflight: result of %d bytes exceeds maximum of %d", e.Size, e.Max)
+}
+
+// Unwrap returns ErrResultTooLarge.
+func (e *ResultTooLargeError) Unwrap() error {
+	return ErrResultTooLarge
+}
+
+// TypeMismatchError is returned when a value of an AnyGroup is requested as
+// a type other than the one its key is registered with or produced. It
+// matches ErrTypeMismatch.
+type TypeMismatchError struct {
+	// Have is the name of the type the key is registered with or the
+	// flight produced, as formatted by the %T verb of fmt, e.g. "int", or
+	// "<nil>" for a nil interface value.
+	Have string
+	// Want is the name of the requested type.
+	Want string
+}
+
+// Error implements error.
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("singleflight: value type mismatch: have %s, want %s", e.Have, e.Want)
+}
+
+// Unwrap returns ErrTypeMismatch.
+func (e *TypeMismatchError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// ExecutionTimeoutError is returned to the callers of a flight whose
+// execution exceeded the timeout configured via WithExecutionTimeout. It
+// matches ErrExecutionTimeout.
+type ExecutionTimeoutError struct {
+	// Timeout is the configured execution timeout.
+	Timeout time.Duration
+}
+
+// Error implements error.
+func (e *ExecutionTimeoutError) Error() string {
+	return fmt.Sprintf("singleflight: execution exceeded timeout of %v", e.Timeout)
+}
+
+// Unwrap returns ErrExecutionTimeout.
+func (e *ExecutionTimeoutError) Unwrap() error {
+	return ErrExecutionTimeout
+}
+
+// SharedError is returned to callers that joined a flight executed by
+// another caller if the flight failed, in groups configured via
+// WithSharedErrors. It matches both ErrShared and the error of the flight.
+type SharedError struct {
+	// Err is the error of the flight.
+	Err error
+}
+
+// Error implements error, returning the message of Err.
+func (e *SharedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns ErrShared and Err.
+func (e *SharedError) Unwrap() []error {
+	return []error{ErrShared, e.Err}
+}
+
+// LeaderFailedError is returned in place of the error of a failed flight
+// to callers that joined it, in groups configured via WithLeaderErrors. It
+// matches ErrLeaderFailed, but not the error of the flight, which is only
+// available as Err.
+type LeaderFailedError struct {
+	// Err is the error of the flight.
+	Err error
+}
+
+// Error implements error.
+func (e *LeaderFailedError) Error() string {
+	return "singleflight: leader failed: " + e.Err.Error()
+}
+
+// Unwrap returns ErrLeaderFailed.
+func (e *LeaderFailedError) Unwrap() error {
+	return ErrLeaderFailed
+}
+
+// ValidationError is returned to every caller of a flight whose result
+// failed the validator configured via WithValidator. It matches both
+// ErrInvalidResult and the error returned by the validator.
+type ValidationError struct {
+	// Err is the error returned by the validator.
+	Err error
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	return "singleflight: invalid result: " + e.Err.Error()
+}
+
+// Unwrap returns ErrInvalidResult and Err.
+func (e *ValidationError) Unwrap() []error {
+	return []error{ErrInvalidResult, e.Err}
+}
@@ -0,0 +1,153 @@
+package singleflight
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the type of an Event.
+type EventType uint8
+
+const (
+	// EventCallStarted is emitted when a flight starts.
+	EventCallStarted EventType = iota + 1
+	// EventWaiterJoined is emitted when a caller joins a flight instead of
+	// starting one, or is served its result within the coalescing window.
+	EventWaiterJoined
+	// EventCallFinished is emitted when a flight completes.
+	EventCallFinished
+	// EventForgotten is emitted when a flight or completed result is
+	// forgotten via Forget, its variants or a Watchdog.
+	EventForgotten
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventCallStarted:
+		return "call started"
+	case EventWaiterJoined:
+		return "waiter joined"
+	case EventCallFinished:
+		return "call finished"
+	case EventForgotten:
+		return "forgotten"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a lifecycle event of a flight of key, see Group.Events.
+type Event[K comparable] struct {
+	Type EventType
+	Key  K
+	Time time.Time
+
+	// Waiters is the number of callers that joined the flight, not counting
+	// the caller that started it.
+	Waiters int
+	// Duration and Err are the duration and error of a finished flight.
+	Duration time.Duration
+	Err      error
+	// Dropped is the number of events dropped for the subscriber since the
+	// previous event it received, because its buffer was full.
+	Dropped uint64
+}
+
+// eventStream is a subscriber of the events of one or more groups.
+type eventStream[K comparable] struct {
+	ch      chan Event[K]
+	dropped atomic.Uint64
+}
+
+// send hands e to the subscriber without blocking, dropping it if the
+// buffer of the subscriber is full.
+func (s *eventStream[K]) send(e Event[K]) {
+	e.Dropped = s.dropped.Load()
+	select {
+	case s.ch <- e:
+		s.dropped.Add(-e.Dropped)
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Events subscribes to the lifecycle events of the flights of g, so
+// observability pipelines can follow the activity of the group without
+// polling its statistics.
+//
+// The returned channel receives an Event when a flight starts, a caller
+// joins it, it finishes or it is forgotten, until cancel is called, after
+// which it is closed. The channel is buffered with capacity buffer. Events
+// are never waited for: events arriving while the buffer is full are
+// dropped and counted in Dropped of the next event delivered.
+func (g *Group[K, V]) Events(buffer int) (events <-chan Event[K], cancel func()) {
+	s := &eventStream[K]{ch: make(chan Event[K], max(buffer, 0))}
+	g.stream(s)
+
+	var once sync.Once
+
+	return s.ch, func() {
+		once.Do(func() {
+			g.unstream(s)
+			close(s.ch)
+		})
+	}
+}
+
+// stream registers the subscriber s.
+func (g *Group[K, V]) stream(s *eventStream[K]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.streams = append(g.streams, s)
+}
+
+// unstream unregisters the subscriber s. Once it returns, no more events
+// are sent to s.
+func (g *Group[K, V]) unstream(s *eventStream[K]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.streams = slices.DeleteFunc(g.streams, func(other *eventStream[K]) bool {
+		return other == s
+	})
+}
+
+// emit sends an event of type t for the flight c of key to the
+// subscribers of g, if any. The caller must hold g.mu.
+func (g *Group[K, V]) emit(t EventType, key K, c *call[V]) {
+	if len(g.streams) == 0 {
+		return
+	}
+
+	e := Event[K]{Type: t, Key: key, Time: time.Now(), Waiters: c.dups}
+	if t == EventCallFinished {
+		e.Duration, e.Err = e.Time.Sub(c.start), c.err
+	}
+	for _, s := range g.streams {
+		s.send(e)
+	}
+}
+
+// Events subscribes to the lifecycle events of the flights of all shards
+// of sg on a single channel, see Group.Events.
+func (sg *ShardedGroup[K, V]) Events(buffer int) (events <-chan Event[K], cancel func()) {
+	s := &eventStream[K]{ch: make(chan Event[K], max(buffer, 0))}
+	for i := range sg.shards {
+		sg.shards[i].stream(s)
+	}
+
+	var once sync.Once
+
+	return s.ch, func() {
+		once.Do(func() {
+			for i := range sg.shards {
+				sg.shards[i].unstream(s)
+			}
+			close(s.ch)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package singleflight
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// eventSource is a group whose lifecycle events can be subscribed to.
+type eventSource interface {
+	doer[string, int]
+	Events(buffer int) (<-chan Event[string], func())
+}
+
+func TestGroupEvents(t *testing.T) {
+	eventsReportLifecycle(t, NewGroup[string, int]())
+}
+
+func TestShardedGroupEvents(t *testing.T) {
+	eventsReportLifecycle(t, NewShardedGroup[string, int]())
+}
+
+func eventsReportLifecycle(t *testing.T, d eventSource) {
+	t.Helper()
+
+	events, cancel := d.Events(16)
+
+	errFn := errors.New("failed")
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return 0, errFn
+	}
+
+	chans := []<-chan Result[int]{d.DoChan(keyA, fn), d.DoChan(keyA, fn)}
+	time.Sleep(sleepJoin)
+	close(release)
+	for _, ch := range chans {
+		<-ch
+	}
+
+	d.DoChan(keyB, func() (int, error) {
+		time.Sleep(sleepJoin)
+		return wantValueInt, nil
+	})
+	time.Sleep(sleepJoin / 3)
+	d.Forget(keyB)
+	time.Sleep(sleepHold)
+
+	cancel()
+	cancel()
+
+	var got []string
+	for e := range events {
+		got = append(got, e.Type.String()+" "+e.Key)
+
+		switch e.Type {
+		case EventWaiterJoined:
+			if e.Waiters != 1 {
+				t.Fatalf("joined: Waiters=%d, want 1", e.Waiters)
+			}
+		case EventCallFinished:
+			if e.Key == keyA && (!errors.Is(e.Err, errFn) || e.Waiters != 1 || e.Duration < sleepJoin) {
+				t.Fatalf("finished: %+v, want errFn with 1 waiter after %v", e, sleepJoin)
+			}
+		}
+		if e.Time.IsZero() || e.Dropped != 0 {
+			t.Fatalf("event %+v, want timestamp without drops", e)
+		}
+	}
+
+	want := []string{
+		"call started " + keyA, "waiter joined " + keyA, "call finished " + keyA,
+		"call started " + keyB, "forgotten " + keyB, "call finished " + keyB,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events=%q, want %q", got, want)
+	}
+}
+
+func TestGroupEventsDropWhenFull(t *testing.T) {
+	g := NewGroup[string, int]()
+	events, cancel := g.Events(1)
+	defer cancel()
+
+	fn := func() (int, error) { return wantValueInt, nil }
+	g.Do(keyA, fn)
+	g.Do(keyB, fn)
+
+	if e := <-events; e.Type != EventCallStarted || e.Dropped != 0 {
+		t.Fatalf("first event %+v, want call started", e)
+	}
+
+	g.Do(keyA, fn)
+	if e := <-events; e.Type != EventCallStarted || e.Dropped != 3 {
+		t.Fatalf("event %+v, want call started after 3 dropped", e)
+	}
+}
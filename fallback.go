@@ -0,0 +1,46 @@
+package singleflight
+
+// DoWithFallback executes and deduplicates primary for key and, only if the
+// primary flight fails, executes and deduplicates fallback in a separate
+// flight of key.
+//
+// Concurrent callers coalesce at both levels: primary runs once for all
+// callers of key, and if it fails, fallback runs once for all callers that
+// observed the failure. The returned shared flag refers to the flight whose
+// result is returned.
+func (g *Group[K, V]) DoWithFallback(
+	key K, primary, fallback func() (V, error),
+) (v V, err error, shared bool) {
+	v, err, shared = g.Do(key, primary)
+	if err == nil {
+		return v, nil, shared
+	}
+
+	return g.do(key, laneFallback, costDerived, fallback)
+}
+
+// DoWithFallback is the sharded variant of Group.DoWithFallback.
+//
+// Both flights of a key live on the shard determined by key.
+func (sg *ShardedGroup[K, V]) DoWithFallback(
+	key K, primary, fallback func() (V, error),
+) (v V, err error, shared bool) {
+	return sg.shards[sg.shardIndex(key)].DoWithFallback(key, primary, fallback)
+}
+
+// FallbackChain returns a work function executing loaders in order until
+// one succeeds, e.g. to be passed to Do. The loaders run within the single
+// flight of the work function, so concurrent callers fall back once
+// together instead of each on their own, and only the error of the last
+// loader is shared with the callers if every loader fails.
+func FallbackChain[V any](loaders ...func() (V, error)) func() (V, error) {
+	return func() (v V, err error) {
+		for _, load := range loaders {
+			if v, err = load(); err == nil {
+				return v, nil
+			}
+		}
+
+		return v, err
+	}
+}
@@ -0,0 +1,131 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fallbackDoer[T ~string, V any] interface {
+	DoWithFallback(T, func() (V, error), func() (V, error)) (V, error, bool)
+}
+
+func TestGroupDoWithFallback(t *testing.T) {
+	var g Group[string, int]
+	fallbackOnlyOnFailure(t, &g, keyA)
+}
+
+func TestShardedGroupDoWithFallback(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	fallbackOnlyOnFailure(t, sg, keyB)
+}
+
+func fallbackOnlyOnFailure[T ~string](t *testing.T, d fallbackDoer[T, int], key T) {
+	t.Helper()
+
+	t.Run("primary succeeds", func(t *testing.T) {
+		var fallbacks int32
+		primary := func() (int, error) { return wantValueInt, nil }
+		fallback := func() (int, error) {
+			atomic.AddInt32(&fallbacks, 1)
+			return 0, nil
+		}
+
+		v, err, _ := d.DoWithFallback(key, primary, fallback)
+		if err != nil {
+			t.Fatalf("err=%v, want nil", err)
+		}
+		if v != wantValueInt {
+			t.Fatalf("v=%d, want %d", v, wantValueInt)
+		}
+		if got := atomic.LoadInt32(&fallbacks); got != 0 {
+			t.Fatalf("fallback calls = %d, want 0", got)
+		}
+	})
+
+	t.Run("primary fails", func(t *testing.T) {
+		var primaries, fallbacks int32
+		primary := func() (int, error) {
+			atomic.AddInt32(&primaries, 1)
+			time.Sleep(sleepJoin)
+			return 0, errors.New("boom")
+		}
+		fallback := func() (int, error) {
+			atomic.AddInt32(&fallbacks, 1)
+			time.Sleep(sleepJoin)
+			return wantValueInt, nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(numCallers)
+
+		vals := make([]int, numCallers)
+		errs := make([]error, numCallers)
+		for i := range numCallers {
+			go func(i int) {
+				defer wg.Done()
+				vals[i], errs[i], _ = d.DoWithFallback(key, primary, fallback)
+			}(i)
+		}
+		wg.Wait()
+
+		for i := range numCallers {
+			if errs[i] != nil {
+				t.Fatalf("errs[%d]=%v, want nil", i, errs[i])
+			}
+			if vals[i] != wantValueInt {
+				t.Fatalf("vals[%d]=%d, want %d", i, vals[i], wantValueInt)
+			}
+		}
+		if got := atomic.LoadInt32(&primaries); got != 1 {
+			t.Fatalf("primary calls = %d, want 1", got)
+		}
+		if got := atomic.LoadInt32(&fallbacks); got != 1 {
+			t.Fatalf("fallback calls = %d, want 1", got)
+		}
+	})
+}
+
+func TestFallbackChain(t *testing.T) {
+	var g Group[string, int]
+
+	var calls [3]int32
+	errLast := errors.New("last")
+	loader := func(i int, v int, err error) func() (int, error) {
+		return func() (int, error) {
+			atomic.AddInt32(&calls[i], 1)
+			time.Sleep(sleepJoin / 3)
+			return v, err
+		}
+	}
+
+	chain := FallbackChain(
+		loader(0, 0, errors.New("primary")),
+		loader(1, wantValueInt, nil),
+		loader(2, 0, errLast),
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	for range numCallers {
+		go func() {
+			defer wg.Done()
+			if v, err, _ := g.Do(keyA, chain); v != wantValueInt || err != nil {
+				t.Errorf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the chain ran once for all callers and stopped at the first success
+	if calls != [3]int32{1, 1, 0} {
+		t.Fatalf("calls=%v, want [1 1 0]", calls)
+	}
+
+	failing := FallbackChain(loader(0, 0, errors.New("primary")), loader(2, 0, errLast))
+	if _, err, _ := g.Do(keyB, failing); !errors.Is(err, errLast) {
+		t.Fatalf("err=%v, want %v", err, errLast)
+	}
+}
@@ -0,0 +1,144 @@
+package singleflight
+
+import (
+	"strings"
+	"time"
+)
+
+// CallMeta describes a flight in progress to the condition of ForgetIf.
+type CallMeta = FlightInfo
+
+// ForgetIf forgets the flights of key in progress for which cond reports
+// true, like Forget, and reports whether any flight was forgotten. cond is
+// called with the group's lock held and must not call into the group.
+//
+// For example, a flight running for longer than a minute is forgotten via
+//
+//	g.ForgetIf(key, func(meta CallMeta) bool { return meta.Age > time.Minute })
+func (g *Group[K, V]) ForgetIf(key K, cond func(meta CallMeta) bool) bool {
+	now := time.Now()
+
+	var keys []K
+	defer func() { g.forgot(keys) }()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	forgotten := false
+	for _, l := range lanes {
+		fk := g.flightKey(key, l)
+		if c, ok := g.m[fk]; ok && cond(g.flightInfo(fk, c, now)) {
+			delete(g.m, fk)
+			g.forget(fk, c, &keys)
+			forgotten = true
+		}
+	}
+
+	return forgotten
+}
+
+// ForgetIf is the sharded variant of Group.ForgetIf.
+func (sg *ShardedGroup[K, V]) ForgetIf(key K, cond func(meta CallMeta) bool) bool {
+	return sg.shards[sg.shardIndex(key)].ForgetIf(key, cond)
+}
+
+// ForgetAfter arranges for key to be forgotten after d, see Forget, to
+// bound how long its in-flight or completed entry can be joined. Calling
+// stop cancels the scheduled Forget; it reports whether the Forget was
+// canceled before it happened.
+func (g *Group[K, V]) ForgetAfter(key K, d time.Duration) (stop func() bool) {
+	return time.AfterFunc(d, func() {
+		g.Forget(key)
+	}).Stop
+}
+
+// ForgetAfter is the sharded variant of Group.ForgetAfter.
+func (sg *ShardedGroup[K, V]) ForgetAfter(key K, d time.Duration) (stop func() bool) {
+	return sg.shards[sg.shardIndex(key)].ForgetAfter(key, d)
+}
+
+// ForgetAll forgets every in-flight and recently completed entry of g, like
+// calling Forget for every key, and returns the number of distinct keys
+// forgotten. Callers of forgotten flights still receive their results.
+func (g *Group[K, V]) ForgetAll() int {
+	return g.ForgetMatching(func(K) bool { return true })
+}
+
+// ForgetAll forgets every in-flight and recently completed entry on every
+// shard of sg, see Group.ForgetAll.
+func (sg *ShardedGroup[K, V]) ForgetAll() int {
+	n := 0
+	for i := range sg.shards {
+		n += sg.shards[i].ForgetAll()
+	}
+
+	return n
+}
+
+// ForgetMatching forgets every in-flight and recently completed entry of g
+// whose key match reports true for, and returns the number of distinct keys
+// forgotten. match is called with the group's lock held and must not call
+// into the group. Entries tracked by digest whose key was not kept are
+// never matched, see WithLongKeyHashing.
+func (g *Group[K, V]) ForgetMatching(match func(key K) bool) int {
+	var keys []K
+	defer func() { g.forgot(keys) }()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// a key may have entries in several lanes and both in flight and
+	// recently completed, but is counted once
+	forgotten := make(map[K]struct{})
+	for _, m := range []map[flightKey[K]]*call[V]{g.m, g.recent} {
+		for fk, c := range m {
+			if key, ok := keyOf(fk, c); ok && match(key) {
+				delete(m, fk)
+				g.forget(fk, c, &keys)
+				forgotten[key] = struct{}{}
+			}
+		}
+	}
+
+	return len(forgotten)
+}
+
+// ForgetPrefix forgets every in-flight and recently completed entry of g
+// whose key starts with prefix in its textual form, see ForgetMatching.
+func (g *Group[K, V]) ForgetPrefix(prefix string) int {
+	return g.ForgetMatching(func(key K) bool {
+		return strings.HasPrefix(keyString(key), prefix)
+	})
+}
+
+// ForgetMatching forgets the matching entries on every shard of sg, see
+// Group.ForgetMatching.
+func (sg *ShardedGroup[K, V]) ForgetMatching(match func(key K) bool) int {
+	n := 0
+	for i := range sg.shards {
+		n += sg.shards[i].ForgetMatching(match)
+	}
+
+	return n
+}
+
+// ForgetPrefix forgets the entries whose key starts with prefix on every
+// shard of sg, see Group.ForgetPrefix.
+func (sg *ShardedGroup[K, V]) ForgetPrefix(prefix string) int {
+	n := 0
+	for i := range sg.shards {
+		n += sg.shards[i].ForgetPrefix(prefix)
+	}
+
+	return n
+}
+
+// keyOf returns the key of the call c registered under fk, or false if c
+// is tracked by digest and its key was not kept, see WithLongKeyRetention.
+func keyOf[K comparable, V any](fk flightKey[K], c *call[V]) (K, bool) {
+	if key, ok := c.key.(K); ok {
+		return key, true
+	}
+
+	return fk.key, fk.digest == keyDigest{}
+}
@@ -0,0 +1,232 @@
+package singleflight
+
+import (
+	"testing"
+	"time"
+)
+
+type conditionalForgetter[T ~string] interface {
+	DoChan(T, func() (int, error)) <-chan Result[int]
+	ForgetIf(T, func(CallMeta) bool) bool
+}
+
+func TestGroupForgetIf(t *testing.T) {
+	var g Group[string, int]
+	forgetIfMatches(t, &g, keyA)
+}
+
+func TestShardedGroupForgetIf(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	forgetIfMatches(t, sg, keyB)
+}
+
+func forgetIfMatches[T ~string](t *testing.T, d conditionalForgetter[T], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	if d.ForgetIf(key, func(CallMeta) bool { return true }) {
+		t.Fatal("ForgetIf reported a flight of an idle key")
+	}
+
+	ch := d.DoChan(key, fn)
+	joined := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+
+	if d.ForgetIf(key, func(meta CallMeta) bool { return meta.Waiters < 1 }) {
+		t.Fatal("ForgetIf forgot a flight not matching the condition")
+	}
+	if !d.ForgetIf(key, func(meta CallMeta) bool {
+		return meta.Waiters == 1 && meta.Age >= sleepJoin && meta.Key == string(key)
+	}) {
+		t.Fatal("ForgetIf did not forget a flight matching the condition")
+	}
+
+	// new callers start a new flight
+	fresh := d.DoChan(key, func() (int, error) { return 1, nil })
+	if res := <-fresh; res.Val != 1 {
+		t.Fatalf("fresh: %+v, want 1", res)
+	}
+
+	close(release)
+	for _, ch := range []<-chan Result[int]{ch, joined} {
+		if res := <-ch; res.Val != wantValueInt {
+			t.Fatalf("forgotten flight: %+v, want %d", res, wantValueInt)
+		}
+	}
+}
+
+type allForgetter[T ~string] interface {
+	doer[T, int]
+	ForgetAll() int
+}
+
+func TestGroupForgetAll(t *testing.T) {
+	g := NewGroup[string, int](WithCoalesceWindow(time.Minute))
+	forgetAllClears(t, g)
+}
+
+func TestShardedGroupForgetAll(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithCoalesceWindow(time.Minute)))
+	forgetAllClears(t, sg)
+}
+
+func forgetAllClears[T ~string](t *testing.T, d allForgetter[T]) {
+	t.Helper()
+
+	release := make(chan struct{})
+	inflight := d.DoChan(keyA, func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	})
+	d.Do(keyB, func() (int, error) { return 1, nil }) // kept for the coalescing window
+
+	if n := d.ForgetAll(); n != 2 {
+		t.Fatalf("ForgetAll()=%d, want 2", n)
+	}
+
+	for _, key := range []T{keyA, keyB} {
+		if v, _, shared := d.Do(key, func() (int, error) { return 2, nil }); v != 2 || shared {
+			t.Fatalf("%s: v=%d shared=%v, want new execution", key, v, shared)
+		}
+	}
+
+	close(release)
+	if res := <-inflight; res.Val != wantValueInt {
+		t.Fatalf("forgotten flight: %+v, want %d", res, wantValueInt)
+	}
+	if n := d.ForgetAll(); n != 2 {
+		t.Fatalf("ForgetAll()=%d, want the 2 coalesced results", n)
+	}
+}
+
+type prefixForgetter[T ~string] interface {
+	doer[T, int]
+	ForgetPrefix(string) int
+}
+
+func TestGroupForgetPrefix(t *testing.T) {
+	var g Group[string, int]
+	forgetPrefixPurges(t, &g)
+}
+
+func TestGroupForgetPrefixHashedKeys(t *testing.T) {
+	g := NewGroup[string, int](WithLongKeyHashing(4), WithLongKeyRetention())
+	forgetPrefixPurges(t, g)
+}
+
+func TestShardedGroupForgetPrefix(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	forgetPrefixPurges(t, sg)
+}
+
+func forgetPrefixPurges[T ~string](t *testing.T, d prefixForgetter[T]) {
+	t.Helper()
+
+	release := make(chan struct{})
+	defer close(release)
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	for _, key := range []T{"tenant-a:1", "tenant-a:2", "tenant-b:1"} {
+		d.DoChan(key, fn)
+	}
+
+	if n := d.ForgetPrefix("tenant-a:"); n != 2 {
+		t.Fatalf("ForgetPrefix()=%d, want 2", n)
+	}
+	if d.Forget("tenant-a:1") || !d.Forget("tenant-b:1") {
+		t.Fatal("ForgetPrefix forgot the wrong flights")
+	}
+}
+
+func TestGroupForgetMatching(t *testing.T) {
+	var g Group[int, int]
+
+	release := make(chan struct{})
+	defer close(release)
+	for id := range 4 {
+		g.DoChan(id, func() (int, error) {
+			<-release
+			return id, nil
+		})
+	}
+
+	if n := g.ForgetMatching(func(id int) bool { return id%2 == 0 }); n != 2 {
+		t.Fatalf("ForgetMatching()=%d, want 2", n)
+	}
+	if stats := g.Stats(); stats.InFlight != 2 {
+		t.Fatalf("InFlight=%d, want 2", stats.InFlight)
+	}
+}
+
+func TestGroupForgetMatchingCountsKeys(t *testing.T) {
+	g := NewGroup[string, int](WithCoalesceWindow(time.Minute))
+
+	// keyA has a recently completed entry and a flight in another lane
+	g.Do(keyA, func() (int, error) { return 1, nil })
+	release := make(chan struct{})
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		g.DoWithPriority(keyA, PriorityHigh, func() (int, error) {
+			<-release
+			return 2, nil
+		})
+	}()
+	time.Sleep(sleepJoin)
+
+	if n := g.ForgetAll(); n != 1 {
+		t.Fatalf("ForgetAll()=%d, want 1 key", n)
+	}
+	close(release)
+	<-ch
+}
+
+type scheduledForgetter[T ~string] interface {
+	doer[T, int]
+	ForgetAfter(T, time.Duration) func() bool
+}
+
+func TestGroupForgetAfter(t *testing.T) {
+	var g Group[string, int]
+	forgetAfterSchedules(t, &g, keyA)
+}
+
+func TestShardedGroupForgetAfter(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	forgetAfterSchedules(t, sg, keyB)
+}
+
+func forgetAfterSchedules[T ~string](t *testing.T, d scheduledForgetter[T], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	defer close(release)
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	d.DoChan(key, fn)
+	d.ForgetAfter(key, sleepJoin)
+	time.Sleep(2 * sleepJoin)
+	if d.Forget(key) {
+		t.Fatal("flight not forgotten after the scheduled duration")
+	}
+
+	d.DoChan(key, fn)
+	if stop := d.ForgetAfter(key, sleepJoin); !stop() {
+		t.Fatal("stop did not cancel the scheduled Forget")
+	}
+	time.Sleep(2 * sleepJoin)
+	if !d.Forget(key) {
+		t.Fatal("canceled Forget forgot the flight")
+	}
+}
@@ -0,0 +1,73 @@
+package singleflight
+
+import "context"
+
+// Future is the eventual result of a flight, see DoFuture. Unlike the
+// channel returned by DoChan, a Future may be polled, awaited any number of
+// times and handed between layers.
+type Future[V any] struct {
+	done chan struct{}
+	res  Result[V]
+}
+
+// DoFuture is like DoChan, but returns the result as a Future.
+func (g *Group[K, V]) DoFuture(key K, fn func() (V, error)) *Future[V] {
+	return newFuture(g.DoChan(key, fn))
+}
+
+// DoFuture is the sharded variant of Group.DoFuture.
+func (sg *ShardedGroup[K, V]) DoFuture(key K, fn func() (V, error)) *Future[V] {
+	return newFuture(sg.DoChan(key, fn))
+}
+
+// newFuture returns a Future resolved by the result received on ch.
+func newFuture[V any](ch <-chan Result[V]) *Future[V] {
+	f := &Future[V]{done: make(chan struct{})}
+
+	go func() {
+		f.res = <-ch
+		close(f.done)
+	}()
+
+	return f
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future[V]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the result until ctx is done, in which case it returns
+// ctx.Err(). The flight is not affected by ctx, so Wait may be called
+// again later.
+func (f *Future[V]) Wait(ctx context.Context) (V, error) {
+	select {
+	case <-f.done:
+		return f.res.Val, f.res.Err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// TryGet returns the result if it is available, without waiting. ok
+// reports whether it is.
+func (f *Future[V]) TryGet() (v V, err error, ok bool) {
+	select {
+	case <-f.done:
+		return f.res.Val, f.res.Err, true
+	default:
+		return v, nil, false
+	}
+}
+
+// Shared reports whether the result was shared with other callers. It is
+// only meaningful once the result is available.
+func (f *Future[V]) Shared() bool {
+	select {
+	case <-f.done:
+		return f.res.Shared
+	default:
+		return false
+	}
+}
@@ -0,0 +1,62 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type futureDoer[T ~string, V any] interface {
+	doer[T, V]
+	DoFuture(T, func() (V, error)) *Future[V]
+}
+
+func TestGroupDoFuture(t *testing.T) {
+	var g Group[string, int]
+	doFutureResolves(t, &g, keyA)
+}
+
+func TestShardedGroupDoFuture(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	doFutureResolves(t, sg, keyB)
+}
+
+func doFutureResolves[T ~string](t *testing.T, d futureDoer[T, int], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	f := d.DoFuture(key, func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	})
+	joined := d.DoFuture(key, func() (int, error) { return 0, nil })
+
+	if _, _, ok := f.TryGet(); ok {
+		t.Fatal("TryGet ok before completion")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+	if _, err := f.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	select {
+	case <-f.Done():
+	case <-time.After(sleepHold):
+		t.Fatal("future not done after completion")
+	}
+
+	// a future may be awaited again and polled
+	for range 2 {
+		if v, err := f.Wait(t.Context()); v != wantValueInt || err != nil {
+			t.Fatalf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+		}
+	}
+	<-joined.Done()
+	if v, err, ok := joined.TryGet(); v != wantValueInt || err != nil || !ok || !joined.Shared() {
+		t.Fatalf("joined v=%d err=%v ok=%v shared=%v, want shared %d", v, err, ok, joined.Shared(), wantValueInt)
+	}
+}
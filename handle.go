@@ -0,0 +1,64 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+)
+
+// Handle is the registration of a caller of DoChanHandle for the result of
+// a flight. The caller either receives the result on Chan or detaches via
+// Cancel.
+type Handle[V any] struct {
+	ch     <-chan Result[V]
+	cancel func()
+	once   sync.Once
+}
+
+// Chan returns the channel the result of the flight is sent on.
+func (h *Handle[V]) Chan() <-chan Result[V] {
+	return h.ch
+}
+
+// Cancel detaches the caller from the flight. Once every caller waiting for
+// the flight has detached, the flight is forgotten, so subsequent calls for
+// its key start a new flight, and the context of its work is canceled.
+//
+// Cancel may be called more than once and after the result is received. A
+// result delivered before Cancel may still be received on Chan.
+func (h *Handle[V]) Cancel() {
+	h.once.Do(h.cancel)
+}
+
+// DoChanHandle is like DoChan, but returns a Handle the caller can detach
+// from the flight with, instead of keeping its interest in the result
+// forever. fn receives a context that is canceled once every caller waiting
+// for the flight has gone away, see DoContextFunc.
+func (g *Group[K, V]) DoChanHandle(key K, fn func(ctx context.Context) (V, error)) *Handle[V] {
+	ch := make(chan Result[V], 1)
+	h := &Handle[V]{ch: ch, cancel: func() {}}
+
+	if err := g.checkKey(key); err != nil {
+		ch <- Result[V]{Err: err}
+		return h
+	}
+
+	fk := g.flightKey(key, laneNormal)
+
+	c, leader, err := g.enlist(key, fk, ch)
+	if err != nil {
+		ch <- Result[V]{Err: err}
+		return h
+	}
+	if leader {
+		g.launch(c, key, fk, context.Background(), fn)
+	}
+
+	h.cancel = func() { g.leave(c, fk) }
+
+	return h
+}
+
+// DoChanHandle is the sharded variant of Group.DoChanHandle.
+func (sg *ShardedGroup[K, V]) DoChanHandle(key K, fn func(ctx context.Context) (V, error)) *Handle[V] {
+	return sg.shards[sg.shardIndex(key)].DoChanHandle(key, fn)
+}
@@ -0,0 +1,86 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type handleDoer[T ~string, V any] interface {
+	doer[T, V]
+	DoChanHandle(T, func(context.Context) (V, error)) *Handle[V]
+}
+
+func TestGroupDoChanHandle(t *testing.T) {
+	var g Group[string, int]
+	doChanHandleCancel(t, &g, keyA)
+}
+
+func TestShardedGroupDoChanHandle(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	doChanHandleCancel(t, sg, keyB)
+}
+
+func doChanHandleCancel[T ~string](t *testing.T, d handleDoer[T, int], key T) {
+	t.Helper()
+
+	aborted := make(chan error, 1)
+	fn := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		aborted <- ctx.Err()
+		return 0, ctx.Err()
+	}
+
+	h1 := d.DoChanHandle(key, fn)
+	h2 := d.DoChanHandle(key, fn)
+
+	// one caller left: the work keeps running
+	h1.Cancel()
+	h1.Cancel()
+	select {
+	case err := <-aborted:
+		t.Fatalf("work aborted with %v while a caller is waiting", err)
+	case <-time.After(sleepJoin):
+	}
+
+	// last caller left: the work is canceled and the flight forgotten
+	h2.Cancel()
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("work err=%v, want %v", err, context.Canceled)
+		}
+	case <-time.After(sleepHold):
+		t.Fatal("work not canceled after all callers left")
+	}
+
+	if v, err, shared := d.Do(key, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || err != nil || shared {
+		t.Fatalf("v=%d err=%v shared=%v, want fresh flight", v, err, shared)
+	}
+}
+
+func TestGroupDoChanHandleResult(t *testing.T) {
+	var g Group[string, int]
+
+	release := make(chan struct{})
+	h := g.DoChanHandle(keyA, func(context.Context) (int, error) {
+		<-release
+		return wantValueInt, nil
+	})
+
+	// a plain DoChan caller keeps the flight alive
+	ch := g.DoChan(keyA, func() (int, error) { return 0, nil })
+	h.Cancel()
+	close(release)
+
+	if res := <-ch; res.Val != wantValueInt || res.Err != nil || !res.Shared {
+		t.Fatalf("res=%+v, want shared %d", res, wantValueInt)
+	}
+
+	h = g.DoChanHandle(keyA, func(context.Context) (int, error) { return wantValueInt, nil })
+	if res := <-h.Chan(); res.Val != wantValueInt || res.Err != nil {
+		t.Fatalf("res=%+v, want %d", res, wantValueInt)
+	}
+	h.Cancel()
+}
@@ -0,0 +1,48 @@
+package singleflight
+
+import (
+	"context"
+	"time"
+)
+
+// hedged returns fn wrapped to start a second execution of fn if the first
+// one has not completed after delay, see WithHedging. The first successful
+// result wins, and the context of the other execution is canceled. If both
+// executions fail, the error of the last one is returned. A panic of fn is
+// returned as a PanicError right away, and so is a runtime.Goexit of fn as
+// errGoexit.
+func hedged[V any](
+	ctx context.Context, delay time.Duration, fn func(context.Context) (V, error),
+) func() (V, error) {
+	return func() (V, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		done := make(chan Result[V], 2)
+		start := func() {
+			go capture(done, func() (V, error) { return fn(ctx) })
+		}
+
+		start()
+		running := 1
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		for {
+			select {
+			case res := <-done:
+				running--
+
+				_, panicked := res.Err.(*PanicError) //nolint:errorlint
+				exited := res.Err == errGoexit       //nolint:errorlint
+				if res.Err == nil || panicked || exited || running == 0 {
+					return res.Val, res.Err
+				}
+			case <-timer.C:
+				start()
+				running++
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupHedging(t *testing.T) {
+	g := NewGroup[string, int](WithHedging(sleepJoin / 3))
+
+	var attempts int32
+	canceled := make(chan error, 1)
+	fn := func(ctx context.Context) (int, error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return 0, ctx.Err()
+		}
+		return wantValueInt, nil
+	}
+
+	v, err, _ := g.DoContextFunc(t.Context(), keyA, fn)
+	if v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want hedged %d", v, err, wantValueInt)
+	}
+
+	// the losing execution is canceled
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("loser err=%v, want %v", err, context.Canceled)
+		}
+	case <-time.After(sleepHold):
+		t.Fatal("losing execution not canceled")
+	}
+
+	// fast executions are not hedged
+	atomic.StoreInt32(&attempts, 0)
+	g.Do(keyB, func() (int, error) {
+		atomic.AddInt32(&attempts, 1)
+		return wantValueInt, nil
+	})
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Fatalf("attempts=%d, want 1", got)
+	}
+}
+
+func TestShardedGroupHedging(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithHedging(sleepJoin / 3)))
+
+	var attempts int32
+	errFn := errors.New("failed")
+	fn := func() (int, error) {
+		switch atomic.AddInt32(&attempts, 1) {
+		case 1:
+			time.Sleep(sleepJoin)
+			return 0, errFn
+		default:
+			time.Sleep(sleepJoin)
+			return wantValueInt, nil
+		}
+	}
+
+	// the first successful result wins over an earlier failure
+	if v, err, _ := sg.Do(keyA, fn); v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want %d", v, err, wantValueInt)
+	}
+}
+
+func TestGroupHedgingGoexit(t *testing.T) {
+	g := NewGroup[string, int](WithHedging(sleepHold))
+	doGoexits(t, g, keyA)
+}
@@ -0,0 +1,80 @@
+package singleflight
+
+import "time"
+
+// Hooks receives lifecycle events of the flights of a Group, see WithHooks.
+// Hooks are called synchronously without the group's lock held and must
+// not block.
+type Hooks[K comparable] interface {
+	// OnCallStart is called when a flight of key starts.
+	OnCallStart(key K)
+	// OnCallEnd is called when a flight of key completes after d with err.
+	OnCallEnd(key K, d time.Duration, err error)
+	// OnSharedJoin is called when a caller joins the flight of key instead
+	// of starting one, or is served its result within the coalescing
+	// window.
+	OnSharedJoin(key K)
+	// OnForget is called when a flight or completed result of key is
+	// forgotten via Forget, its variants or a Watchdog.
+	OnForget(key K)
+}
+
+// NopHooks implements Hooks by doing nothing. Embed it to implement only
+// some of the hooks.
+type NopHooks[K comparable] struct{}
+
+// OnCallStart implements Hooks.
+func (NopHooks[K]) OnCallStart(K) {}
+
+// OnCallEnd implements Hooks.
+func (NopHooks[K]) OnCallEnd(K, time.Duration, error) {}
+
+// OnSharedJoin implements Hooks.
+func (NopHooks[K]) OnSharedJoin(K) {}
+
+// OnForget implements Hooks.
+func (NopHooks[K]) OnForget(K) {}
+
+// hooks returns the lifecycle hooks of g, if any.
+func (g *Group[K, V]) hooks() Hooks[K] {
+	h, _ := g.settings().hooks.(Hooks[K])
+	return h
+}
+
+// started reports the start of a flight of key to the hooks of g, if any.
+func (g *Group[K, V]) started(key K) {
+	if h := g.hooks(); h != nil {
+		h.OnCallStart(key)
+	}
+}
+
+// joined reports a caller joining the flight of key to the hooks of g, if
+// any.
+func (g *Group[K, V]) joined(key K) {
+	if h := g.hooks(); h != nil {
+		h.OnSharedJoin(key)
+	}
+}
+
+// forgot reports the forgotten entries of keys to the hooks of g, if any.
+func (g *Group[K, V]) forgot(keys []K) {
+	if h := g.hooks(); h != nil {
+		for _, key := range keys {
+			h.OnForget(key)
+		}
+	}
+}
+
+// forget records the forgetting of the entry c registered under fk,
+// collecting its key for the hooks, if any, and emitting it to the
+// subscribers of events. The caller must hold g.mu.
+func (g *Group[K, V]) forget(fk flightKey[K], c *call[V], keys *[]K) {
+	g.forgets++
+	key, ok := keyOf(fk, c)
+	if ok && g.settings().hooks != nil {
+		*keys = append(*keys, key)
+	}
+	if len(g.streams) > 0 {
+		g.emit(EventForgotten, key, c)
+	}
+}
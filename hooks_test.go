@@ -0,0 +1,90 @@
+package singleflight
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hookRecorder records the lifecycle events reported to it.
+type hookRecorder struct {
+	NopHooks[string]
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *hookRecorder) record(event, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event+" "+key)
+}
+
+func (r *hookRecorder) OnCallStart(key string)  { r.record("start", key) }
+func (r *hookRecorder) OnSharedJoin(key string) { r.record("join", key) }
+func (r *hookRecorder) OnForget(key string)     { r.record("forget", key) }
+
+func (r *hookRecorder) OnCallEnd(key string, _ time.Duration, _ error) {
+	r.record("end", key)
+}
+
+func TestGroupHooks(t *testing.T) {
+	var rec hookRecorder
+	g := NewGroup[string, int](WithHooks[string](&rec))
+	hooksReportLifecycle(t, g, &rec)
+}
+
+func TestShardedGroupHooks(t *testing.T) {
+	var rec hookRecorder
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithHooks[string](&rec)))
+	hooksReportLifecycle(t, sg, &rec)
+}
+
+func hooksReportLifecycle(t *testing.T, d doer[string, int], rec *hookRecorder) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	chans := []<-chan Result[int]{d.DoChan(keyA, fn), d.DoChan(keyA, fn), d.DoChan(keyA, fn)}
+	time.Sleep(sleepJoin)
+	close(release)
+	for _, ch := range chans {
+		<-ch
+	}
+
+	d.DoChan(keyB, func() (int, error) {
+		time.Sleep(sleepJoin)
+		return wantValueInt, nil
+	})
+	time.Sleep(sleepJoin / 3)
+	d.Forget(keyB)
+	time.Sleep(sleepHold)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	want := []string{
+		"start " + keyA, "join " + keyA, "join " + keyA, "end " + keyA,
+		"start " + keyB, "forget " + keyB, "end " + keyB,
+	}
+	if !slices.Equal(rec.events, want) {
+		t.Fatalf("events=%q, want %q", rec.events, want)
+	}
+}
+
+func TestGroupHooksOfAnotherKeyType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "NopHooks[string]") {
+			t.Fatalf("recovered %v, want panic naming the hooks", r)
+		}
+	}()
+	NewGroup[int, int](WithHooks[string](NopHooks[string]{}))
+}
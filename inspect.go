@@ -0,0 +1,214 @@
+package singleflight
+
+import (
+	"cmp"
+	"encoding/hex"
+	"slices"
+	"time"
+)
+
+// FlightInfo describes a flight in progress, see Group.Inflight.
+type FlightInfo struct {
+	// Key is the textual form of the key of the flight. Keys tracked by
+	// digest due to WithLongKeyHashing are reported as "sha256:" followed
+	// by the hex-encoded digest.
+	Key string `json:"key"`
+	// Lane is the lane of the flight: "normal", "fallback" or "priority".
+	Lane string `json:"lane"`
+	// Waiters is the number of callers that joined the flight.
+	Waiters int `json:"waiters"`
+	// Start is the time the flight started.
+	Start time.Time `json:"start"`
+	// Age is the time since the flight started.
+	Age time.Duration `json:"age"`
+	// Shard is the index of the shard of a ShardedGroup the flight is in
+	// progress on. It is zero for flights of a Group.
+	Shard int `json:"shard"`
+}
+
+// Inflight returns a snapshot of the flights in progress on g, ordered by
+// descending number of waiters, i.e. hottest keys first.
+func (g *Group[K, V]) Inflight() []FlightInfo {
+	now := time.Now()
+
+	g.mu.Lock()
+	flights := make([]FlightInfo, 0, len(g.m))
+	for fk, c := range g.m {
+		flights = append(flights, g.flightInfo(fk, c, now))
+	}
+	g.mu.Unlock()
+
+	sortFlights(flights)
+
+	return flights
+}
+
+// Inflight returns a snapshot of the flights in progress on all shards of
+// sg, ordered like Group.Inflight.
+func (sg *ShardedGroup[K, V]) Inflight() []FlightInfo {
+	var flights []FlightInfo
+	for i := range sg.shards {
+		flights = append(flights, sg.shards[i].Inflight()...)
+	}
+
+	sortFlights(flights)
+
+	return flights
+}
+
+// InFlight reports whether a flight of key is in progress on g, in any
+// lane.
+func (g *Group[K, V]) InFlight(key K) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, l := range lanes {
+		if _, ok := g.m[g.flightKey(key, l)]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Waiters returns the number of callers that joined the flights of key in
+// progress on g, zero if there is none.
+func (g *Group[K, V]) Waiters(key K) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := 0
+	for _, l := range lanes {
+		if c, ok := g.m[g.flightKey(key, l)]; ok {
+			n += c.dups
+		}
+	}
+
+	return n
+}
+
+// InFlight reports whether a flight of key is in progress on its shard,
+// see Group.InFlight.
+func (sg *ShardedGroup[K, V]) InFlight(key K) bool {
+	return sg.shards[sg.shardIndex(key)].InFlight(key)
+}
+
+// Waiters returns the number of callers that joined the flights of key on
+// its shard, see Group.Waiters.
+func (sg *ShardedGroup[K, V]) Waiters(key K) int {
+	return sg.shards[sg.shardIndex(key)].Waiters(key)
+}
+
+// Keys returns the keys of the flights in progress on g, in no particular
+// order. A key with flights in several lanes is listed once; flights
+// tracked by digest whose key was not kept are not listed, see
+// WithLongKeyHashing.
+func (g *Group[K, V]) Keys() []K {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	seen := make(map[K]struct{}, len(g.m))
+	keys := make([]K, 0, len(g.m))
+	for fk, c := range g.m {
+		key, ok := keyOf(fk, c)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Len returns the number of keys with flights in progress on g, i.e. the
+// length of Keys.
+func (g *Group[K, V]) Len() int {
+	return len(g.Keys())
+}
+
+// Keys returns the keys of the flights in progress on all shards of sg, see
+// Group.Keys.
+func (sg *ShardedGroup[K, V]) Keys() []K {
+	var keys []K
+	for i := range sg.shards {
+		keys = append(keys, sg.shards[i].Keys()...)
+	}
+
+	return keys
+}
+
+// Len returns the number of keys with flights in progress on all shards of
+// sg, i.e. the length of Keys.
+func (sg *ShardedGroup[K, V]) Len() int {
+	n := 0
+	for i := range sg.shards {
+		n += sg.shards[i].Len()
+	}
+
+	return n
+}
+
+// ShardLoad returns the number of flights in progress per shard of sg,
+// indexed by shard.
+func (sg *ShardedGroup[K, V]) ShardLoad() []int {
+	load := make([]int, len(sg.shards))
+	for i := range sg.shards {
+		g := &sg.shards[i]
+
+		g.mu.Lock()
+		load[i] = len(g.m)
+		g.mu.Unlock()
+	}
+
+	return load
+}
+
+// flightInfo describes the call c registered under fk at now.
+func (g *Group[K, V]) flightInfo(fk flightKey[K], c *call[V], now time.Time) FlightInfo {
+	return FlightInfo{
+		Key:     fk.String(),
+		Lane:    fk.lane.String(),
+		Waiters: c.dups,
+		Start:   c.start,
+		Age:     now.Sub(c.start),
+		Shard:   max(g.shard-1, 0),
+	}
+}
+
+// String returns the textual form of the key of fk.
+func (fk flightKey[K]) String() string {
+	switch {
+	case fk.text != "":
+		return fk.text
+	case fk.digest != keyDigest{}:
+		return "sha256:" + hex.EncodeToString(fk.digest[:])
+	default:
+		return keyString(fk.key)
+	}
+}
+
+// String returns the name of l.
+func (l lane) String() string {
+	switch l {
+	case laneFallback:
+		return "fallback"
+	case lanePriority:
+		return "priority"
+	default:
+		return "normal"
+	}
+}
+
+// sortFlights orders flights by descending waiters, then by key.
+func sortFlights(flights []FlightInfo) {
+	slices.SortFunc(flights, func(a, b FlightInfo) int {
+		if a.Waiters != b.Waiters {
+			return b.Waiters - a.Waiters
+		}
+
+		return cmp.Compare(a.Key, b.Key)
+	})
+}
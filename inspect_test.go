@@ -0,0 +1,176 @@
+package singleflight
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupInflight(t *testing.T) {
+	g := NewGroup[string, int](WithLongKeyHashing(32))
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	long := strings.Repeat("k", 64)
+
+	var wg sync.WaitGroup
+	for _, key := range []string{keyA, keyA, keyA, long} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Do(key, fn)
+		}()
+		time.Sleep(sleepJoin / 5)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.DoWithFallback(keyB, func() (int, error) { return 0, errors.New("miss") }, fn)
+	}()
+	time.Sleep(sleepJoin)
+
+	flights := g.Inflight()
+	close(release)
+	wg.Wait()
+
+	if len(flights) != 3 {
+		t.Fatalf("flights=%+v, want 3", flights)
+	}
+	if f := flights[0]; f.Key != keyA || f.Lane != "normal" || f.Waiters != 2 || f.Age <= 0 {
+		t.Fatalf("hottest flight=%+v, want %s normal with 2 waiters", f, keyA)
+	}
+	if f := flights[1]; f.Key != keyB || f.Lane != "fallback" {
+		t.Fatalf("flight=%+v, want %s fallback", f, keyB)
+	}
+	if f := flights[2]; !strings.HasPrefix(f.Key, "sha256:") {
+		t.Fatalf("flight=%+v, want digest key", f)
+	}
+
+	if flights := g.Inflight(); len(flights) != 0 {
+		t.Fatalf("flights after completion=%+v, want none", flights)
+	}
+}
+
+func TestShardedGroupShardLoad(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithShardCount(4))
+
+	release := make(chan struct{})
+	for _, key := range []string{keyA, keyB} {
+		sg.DoChan(key, func() (int, error) {
+			<-release
+			return wantValueInt, nil
+		})
+	}
+	time.Sleep(sleepJoin)
+
+	load := sg.ShardLoad()
+	flights := sg.Inflight()
+	close(release)
+
+	total := 0
+	for _, n := range load {
+		total += n
+	}
+	if len(load) != 4 || total != 2 || len(flights) != 2 {
+		t.Fatalf("load=%v flights=%+v, want 4 shards with 2 flights", load, flights)
+	}
+	for _, f := range flights {
+		if want := int(sg.shardIndex(f.Key)); f.Shard != want {
+			t.Fatalf("flight %q on shard %d, want %d", f.Key, f.Shard, want)
+		}
+	}
+}
+
+type keyInspector[T ~string] interface {
+	doer[T, int]
+	InFlight(T) bool
+	Waiters(T) int
+}
+
+func TestGroupInFlightWaiters(t *testing.T) {
+	var g Group[string, int]
+	inFlightWaitersReports(t, &g, keyA)
+}
+
+func TestShardedGroupInFlightWaiters(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	inFlightWaitersReports(t, sg, keyB)
+}
+
+func inFlightWaitersReports[T ~string](t *testing.T, d keyInspector[T], key T) {
+	t.Helper()
+
+	if d.InFlight(key) || d.Waiters(key) != 0 {
+		t.Fatal("idle key reported in flight")
+	}
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+	chans := make([]<-chan Result[int], 3)
+	for i := range chans {
+		chans[i] = d.DoChan(key, fn)
+	}
+
+	if !d.InFlight(key) || d.Waiters(key) != 2 {
+		t.Fatalf("InFlight=%v Waiters=%d, want in flight with 2 waiters", d.InFlight(key), d.Waiters(key))
+	}
+
+	close(release)
+	for _, ch := range chans {
+		<-ch
+	}
+	if d.InFlight(key) || d.Waiters(key) != 0 {
+		t.Fatal("completed key reported in flight")
+	}
+}
+
+type keyLister[T ~string] interface {
+	doer[T, int]
+	Keys() []T
+	Len() int
+}
+
+func TestGroupKeys(t *testing.T) {
+	g := NewGroup[string, int](WithKeyNormalizer(strings.ToLower))
+	keysListsInflight(t, g)
+}
+
+func TestShardedGroupKeys(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	keysListsInflight(t, sg)
+}
+
+func keysListsInflight[T ~string](t *testing.T, d keyLister[T]) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+	chans := []<-chan Result[int]{d.DoChan(keyA, fn), d.DoChan(keyA, fn), d.DoChan(keyB, fn)}
+
+	keys := d.Keys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []T{keyA, keyB}) || d.Len() != 2 {
+		t.Fatalf("Keys()=%v Len()=%d, want [%s %s]", keys, d.Len(), keyA, keyB)
+	}
+
+	close(release)
+	for _, ch := range chans {
+		<-ch
+	}
+	if keys := d.Keys(); len(keys) != 0 || d.Len() != 0 {
+		t.Fatalf("Keys()=%v after completion, want none", keys)
+	}
+}
@@ -0,0 +1,63 @@
+package singleflight
+
+import "testing"
+
+func TestKeyBuilderKey(t *testing.T) {
+	var kb KeyBuilder
+	got := kb.String("user").Int64(-42).Uint64(7).Bool(true).String("eu|west").Bytes([]byte(`a\b`)).Key()
+
+	want := `s:user|i:-42|u:7|b:true|s:eu\|west|x:a\\b`
+	if got != want {
+		t.Fatalf("Key()=%q, want %q", got, want)
+	}
+	if appended := string(kb.AppendKey([]byte("p/"))); appended != "p/"+want {
+		t.Fatalf("AppendKey()=%q, want %q", appended, "p/"+want)
+	}
+
+	kb.Reset()
+	if got := kb.String("x").Key(); got != "s:x" {
+		t.Fatalf("Key() after Reset=%q, want %q", got, "s:x")
+	}
+}
+
+func TestKeyBuilderUnambiguous(t *testing.T) {
+	build := func(parts func(kb *KeyBuilder)) string {
+		var kb KeyBuilder
+		parts(&kb)
+		return kb.Key()
+	}
+
+	tests := []struct {
+		name string
+		a, b func(kb *KeyBuilder)
+	}{
+		{
+			name: "separator in string",
+			a:    func(kb *KeyBuilder) { kb.String("a|b").String("c") },
+			b:    func(kb *KeyBuilder) { kb.String("a").String("b|c") },
+		},
+		{
+			name: "escape in string",
+			a:    func(kb *KeyBuilder) { kb.String(`a\`).String("b") },
+			b:    func(kb *KeyBuilder) { kb.String(`a\|b`) },
+		},
+		{
+			name: "type of part",
+			a:    func(kb *KeyBuilder) { kb.String("1") },
+			b:    func(kb *KeyBuilder) { kb.Int64(1) },
+		},
+		{
+			name: "empty parts",
+			a:    func(kb *KeyBuilder) { kb.String("").String("") },
+			b:    func(kb *KeyBuilder) { kb.String("") },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if a, b := build(tc.a), build(tc.b); a == b {
+				t.Fatalf("keys collide: %q", a)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyedStats is a point-in-time snapshot of the activity of a KeyedMutex
+// or KeyedLimiter.
+type KeyedStats struct {
+	// Acquired is the number of successful acquisitions.
+	Acquired uint64
+	// Rejected is the number of acquisitions that failed because no slot
+	// was available or the context was done first.
+	Rejected uint64
+	// Held is the number of slots currently held across all keys.
+	Held int64
+	// Waiting is the number of callers currently waiting for a slot.
+	Waiting int64
+}
+
+// keyedSlots hands out up to capacity slots per key. It is the machinery
+// behind KeyedMutex and KeyedLimiter.
+type keyedSlots[T comparable] struct {
+	router   shardRouter
+	pick     func(T) uint64 // see WithShardPicker and WithKeyHash, nil if not configured
+	shards   []slotShard[T]
+	capacity int
+
+	acquired atomic.Uint64
+	rejected atomic.Uint64
+	held     atomic.Int64
+	waiting  atomic.Int64
+}
+
+// slotShard holds the slot entries of the keys routed to it.
+type slotShard[T comparable] struct {
+	mu      sync.Mutex
+	entries map[T]*slotEntry
+}
+
+// slotEntry tracks the slots of a single key. refs counts holders and
+// waiters, so the entry can be dropped once nobody references it.
+type slotEntry struct {
+	slots chan struct{}
+	refs  int
+}
+
+// newKeyedSlots returns keyedSlots with capacity slots per key of type T,
+// sharded according to opts.
+func newKeyedSlots[T comparable](capacity int, opts ...ShardConfigOption) *keyedSlots[T] {
+	config := newShardConfig(opts...)
+
+	ks := &keyedSlots[T]{
+		router:   newShardRouter(config),
+		pick:     configuredKeyIndex[T](config),
+		shards:   make([]slotShard[T], config.shardCount),
+		capacity: max(capacity, 1),
+	}
+	for i := range ks.shards {
+		ks.shards[i].entries = make(map[T]*slotEntry)
+	}
+
+	return ks
+}
+
+// shard returns the shard of key.
+func (ks *keyedSlots[T]) shard(key T) *slotShard[T] {
+	if ks.pick != nil {
+		return &ks.shards[ks.pick(key)]
+	}
+
+	return &ks.shards[indexKey(ks.router, key)]
+}
+
+// ref returns the entry of key with its reference count incremented. The
+// caller must hold s.mu.
+func (s *slotShard[T]) ref(key T, capacity int) *slotEntry {
+	e, ok := s.entries[key]
+	if !ok {
+		e = &slotEntry{slots: make(chan struct{}, capacity)}
+		s.entries[key] = e
+	}
+	e.refs++
+
+	return e
+}
+
+// unref decrements the reference count of e and drops it once unused. The
+// caller must hold s.mu.
+func (s *slotShard[T]) unref(key T, e *slotEntry) {
+	e.refs--
+	if e.refs == 0 {
+		delete(s.entries, key)
+	}
+}
+
+// acquire blocks until a slot of key is available or ctx is done.
+func (ks *keyedSlots[T]) acquire(ctx context.Context, key T) error {
+	shard := ks.shard(key)
+
+	shard.mu.Lock()
+	e := shard.ref(key, ks.capacity)
+	shard.mu.Unlock()
+
+	ks.waiting.Add(1)
+	defer ks.waiting.Add(-1)
+
+	select {
+	case e.slots <- struct{}{}:
+		ks.acquired.Add(1)
+		ks.held.Add(1)
+
+		return nil
+	case <-ctx.Done():
+		shard.mu.Lock()
+		shard.unref(key, e)
+		shard.mu.Unlock()
+
+		ks.rejected.Add(1)
+
+		return ctx.Err()
+	}
+}
+
+// tryAcquire acquires a slot of key if one is available without blocking.
+func (ks *keyedSlots[T]) tryAcquire(key T) bool {
+	shard := ks.shard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	e := shard.ref(key, ks.capacity)
+	select {
+	case e.slots <- struct{}{}:
+		ks.acquired.Add(1)
+		ks.held.Add(1)
+
+		return true
+	default:
+		shard.unref(key, e)
+		ks.rejected.Add(1)
+
+		return false
+	}
+}
+
+// release returns a slot of key. It panics if no slot of key is held.
+func (ks *keyedSlots[T]) release(key T) {
+	shard := ks.shard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	e, ok := shard.entries[key]
+	if !ok {
+		panic("singleflight: release of unheld key")
+	}
+
+	select {
+	case <-e.slots:
+	default:
+		panic("singleflight: release of unheld key")
+	}
+
+	shard.unref(key, e)
+	ks.held.Add(-1)
+}
+
+// stats returns a snapshot of the statistics of ks.
+func (ks *keyedSlots[T]) stats() KeyedStats {
+	return KeyedStats{
+		Acquired: ks.acquired.Load(),
+		Rejected: ks.rejected.Load(),
+		Held:     ks.held.Load(),
+		Waiting:  ks.waiting.Load(),
+	}
+}
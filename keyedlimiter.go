@@ -0,0 +1,45 @@
+package singleflight
+
+import "context"
+
+// KeyedLimiter caps the number of concurrent holders per key.
+//
+// It is the counting counterpart of KeyedMutex: up to n callers may hold a
+// key at the same time, while callers of different keys proceed
+// independently. Keys are distributed across shards like in ShardedGroup,
+// and the state of a key is dropped once it is neither held nor awaited.
+type KeyedLimiter[T comparable] struct {
+	slots *keyedSlots[T]
+}
+
+// NewKeyedLimiter constructs a KeyedLimiter allowing up to n concurrent
+// holders per key, sharded according to opts. Values of n below 1 are
+// treated as 1.
+func NewKeyedLimiter[T comparable](n int, opts ...ShardConfigOption) *KeyedLimiter[T] {
+	return &KeyedLimiter[T]{
+		slots: newKeyedSlots[T](n, opts...),
+	}
+}
+
+// Acquire blocks until the caller holds one of the slots of key, or returns
+// ctx.Err() once ctx is done.
+func (kl *KeyedLimiter[T]) Acquire(ctx context.Context, key T) error {
+	return kl.slots.acquire(ctx, key)
+}
+
+// TryAcquire acquires a slot of key without blocking and reports whether
+// it succeeded.
+func (kl *KeyedLimiter[T]) TryAcquire(key T) bool {
+	return kl.slots.tryAcquire(key)
+}
+
+// Release releases a slot of key previously acquired. It panics if no slot
+// of key is held.
+func (kl *KeyedLimiter[T]) Release(key T) {
+	kl.slots.release(key)
+}
+
+// Stats returns a snapshot of the statistics of kl.
+func (kl *KeyedLimiter[T]) Stats() KeyedStats {
+	return kl.slots.stats()
+}
@@ -0,0 +1,104 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiterBoundsHolders(t *testing.T) {
+	const n = 3
+
+	kl := NewKeyedLimiter[string](n, WithShardCount(4))
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+		maxHeld int
+	)
+
+	wg.Add(numCallers * 4)
+	for range numCallers * 4 {
+		go func() {
+			defer wg.Done()
+
+			if err := kl.Acquire(t.Context(), keyA); err != nil {
+				t.Errorf("Acquire err=%v, want nil", err)
+				return
+			}
+			defer kl.Release(keyA)
+
+			mu.Lock()
+			holders++
+			maxHeld = max(maxHeld, holders)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			holders--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxHeld > n {
+		t.Fatalf("max concurrent holders = %d, want <= %d", maxHeld, n)
+	}
+	if stats := kl.Stats(); stats.Acquired != numCallers*4 || stats.Held != 0 || stats.Waiting != 0 {
+		t.Fatalf("stats=%+v, want Acquired=%d Held=0 Waiting=0", stats, numCallers*4)
+	}
+	if n := keyedEntries(kl.slots); n != 0 {
+		t.Fatalf("entries after release = %d, want 0", n)
+	}
+}
+
+func TestKeyedLimiterTryAcquireAndContext(t *testing.T) {
+	kl := NewKeyedLimiter[string](2)
+
+	if !kl.TryAcquire(keyA) || !kl.TryAcquire(keyA) {
+		t.Fatal("expected two slots to be available")
+	}
+	if kl.TryAcquire(keyA) {
+		t.Fatal("expected TryAcquire beyond capacity to fail")
+	}
+	if !kl.TryAcquire(keyB) {
+		t.Fatal("expected TryAcquire on other key to succeed")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+	if err := kl.Acquire(ctx, keyA); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+
+	stats := kl.Stats()
+	if stats.Acquired != 3 || stats.Rejected != 2 || stats.Held != 3 {
+		t.Fatalf("stats=%+v, want Acquired=3 Rejected=2 Held=3", stats)
+	}
+
+	kl.Release(keyA)
+	kl.Release(keyA)
+	kl.Release(keyB)
+}
+
+func TestKeyedLimiterComparableKeys(t *testing.T) {
+	kl := NewKeyedLimiter[int](2, WithShardCount(4))
+
+	if !kl.TryAcquire(1) || !kl.TryAcquire(1) || kl.TryAcquire(1) {
+		t.Fatal("expected two slots of key 1 to be available")
+	}
+	if !kl.TryAcquire(2) {
+		t.Fatal("expected TryAcquire on other key to succeed")
+	}
+
+	kl.Release(1)
+	kl.Release(1)
+	kl.Release(2)
+	if n := keyedEntries(kl.slots); n != 0 {
+		t.Fatalf("entries after release = %d, want 0", n)
+	}
+}
@@ -0,0 +1,51 @@
+package singleflight
+
+import "context"
+
+// KeyedMutex provides mutual exclusion per key.
+//
+// Where Group shares the result of one execution among concurrent callers,
+// KeyedMutex serializes callers of the same key while callers of different
+// keys proceed independently. Keys are distributed across shards like in
+// ShardedGroup, and the state of a key is dropped once it is neither held
+// nor awaited.
+type KeyedMutex[T comparable] struct {
+	slots *keyedSlots[T]
+}
+
+// NewKeyedMutex constructs a KeyedMutex sharded according to opts.
+func NewKeyedMutex[T comparable](opts ...ShardConfigOption) *KeyedMutex[T] {
+	return &KeyedMutex[T]{
+		slots: newKeyedSlots[T](1, opts...),
+	}
+}
+
+// Lock locks key. If key is already locked, Lock blocks until it is
+// unlocked.
+func (km *KeyedMutex[T]) Lock(key T) {
+	_ = km.slots.acquire(context.Background(), key) //nolint:errcheck
+}
+
+// LockContext is like Lock, but gives up and returns ctx.Err() once ctx is
+// done.
+func (km *KeyedMutex[T]) LockContext(ctx context.Context, key T) error {
+	return km.slots.acquire(ctx, key)
+}
+
+// TryLock tries to lock key and reports whether it succeeded.
+func (km *KeyedMutex[T]) TryLock(key T) bool {
+	return km.slots.tryAcquire(key)
+}
+
+// Unlock unlocks key. It panics if key is not locked.
+//
+// As with sync.Mutex, a locked key is not associated with a particular
+// goroutine; one goroutine may lock a key and another unlock it.
+func (km *KeyedMutex[T]) Unlock(key T) {
+	km.slots.release(key)
+}
+
+// Stats returns a snapshot of the statistics of km.
+func (km *KeyedMutex[T]) Stats() KeyedStats {
+	return km.slots.stats()
+}
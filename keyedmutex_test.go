@@ -0,0 +1,165 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestKeyedMutexExclusion(t *testing.T) {
+	km := NewKeyedMutex[string](WithShardCount(4))
+
+	var (
+		wg      sync.WaitGroup
+		holders int
+		maxHeld int
+		mu      sync.Mutex
+	)
+
+	wg.Add(numCallers * 10)
+	for range numCallers * 10 {
+		go func() {
+			defer wg.Done()
+
+			km.Lock(keyA)
+			defer km.Unlock(keyA)
+
+			mu.Lock()
+			holders++
+			maxHeld = max(maxHeld, holders)
+			mu.Unlock()
+
+			mu.Lock()
+			holders--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxHeld != 1 {
+		t.Fatalf("max concurrent holders = %d, want 1", maxHeld)
+	}
+	if n := keyedEntries(km.slots); n != 0 {
+		t.Fatalf("entries after unlock = %d, want 0", n)
+	}
+}
+
+func TestKeyedMutexTryLock(t *testing.T) {
+	km := NewKeyedMutex[string]()
+
+	if !km.TryLock(keyA) {
+		t.Fatal("expected TryLock on unlocked key to succeed")
+	}
+	if km.TryLock(keyA) {
+		t.Fatal("expected TryLock on locked key to fail")
+	}
+	if !km.TryLock(keyB) {
+		t.Fatal("expected TryLock on other key to succeed")
+	}
+
+	km.Unlock(keyA)
+	km.Unlock(keyB)
+
+	if n := keyedEntries(km.slots); n != 0 {
+		t.Fatalf("entries after unlock = %d, want 0", n)
+	}
+}
+
+func TestKeyedMutexLockContext(t *testing.T) {
+	km := NewKeyedMutex[string]()
+	km.Lock(keyA)
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+	if err := km.LockContext(ctx, keyA); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+
+	km.Unlock(keyA)
+	if err := km.LockContext(t.Context(), keyA); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	km.Unlock(keyA)
+}
+
+func TestKeyedMutexUnlockOfUnlockedKey(t *testing.T) {
+	km := NewKeyedMutex[string]()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Unlock of unlocked key to panic")
+		}
+	}()
+	km.Unlock(keyA)
+}
+
+// keyedEntries returns the number of keys tracked by ks.
+func keyedEntries[T comparable](ks *keyedSlots[T]) int {
+	var n int
+	for i := range ks.shards {
+		ks.shards[i].mu.Lock()
+		n += len(ks.shards[i].entries)
+		ks.shards[i].mu.Unlock()
+	}
+
+	return n
+}
+
+func TestKeyedMutexShardPicker(t *testing.T) {
+	type tenantKey string
+
+	km := NewKeyedMutex[tenantKey](
+		WithShardCount(4),
+		WithShardPicker(func(key tenantKey) uint64 { return uint64(key[0] - '0') }),
+	)
+
+	km.Lock("1:a")
+	km.Lock("2:a")
+	if got := len(km.slots.shards[1].entries); got != 1 {
+		t.Fatalf("entries of shard 1=%d, want 1", got)
+	}
+	// picks beyond the shard count wrap around
+	km.Lock("5:b")
+	if got := len(km.slots.shards[1].entries); got != 2 {
+		t.Fatalf("entries of shard 1=%d, want 2", got)
+	}
+}
+
+func TestKeyedMutexKeyHash(t *testing.T) {
+	type tenantKey string
+
+	km := NewKeyedMutex[tenantKey](
+		WithShardCount(4),
+		WithKeyHash(func(key tenantKey) uint64 { return uint64(len(key)) }),
+	)
+
+	km.Lock("a")
+	km.Lock("bbbbb")
+	if got := len(km.slots.shards[1].entries); got != 2 {
+		t.Fatalf("entries of shard 1=%d, want 2", got)
+	}
+}
+
+func TestKeyedMutexComparableKeys(t *testing.T) {
+	type invoiceKey struct {
+		tenant string
+		id     int
+	}
+
+	km := NewKeyedMutex[invoiceKey](WithShardCount(4))
+
+	km.Lock(invoiceKey{"acme", 1})
+	if km.TryLock(invoiceKey{"acme", 1}) {
+		t.Fatal("expected TryLock of a locked key to fail")
+	}
+	if !km.TryLock(invoiceKey{"acme", 2}) {
+		t.Fatal("expected TryLock of another key to succeed")
+	}
+
+	km.Unlock(invoiceKey{"acme", 1})
+	km.Unlock(invoiceKey{"acme", 2})
+	if n := keyedEntries(km.slots); n != 0 {
+		t.Fatalf("entries after unlock = %d, want 0", n)
+	}
+}
@@ -0,0 +1,159 @@
+package singleflight
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Keyer is implemented by keys that serialize themselves into a stable
+// string, e.g. composite request parameters that are not comparable or
+// whose fields should not all take part in deduplication.
+type Keyer interface {
+	SingleflightKey() string
+}
+
+// KeyAppender is implemented by keys that append their stable serialized
+// form to a buffer, avoiding intermediate allocations while building it.
+type KeyAppender interface {
+	AppendKey(dst []byte) []byte
+}
+
+// KeyerGroup deduplicates calls keyed by values that are not used as keys
+// directly but are serialized to a string first, see NewKeyerGroup and
+// NewKeyAppenderGroup.
+//
+// Calls are deduplicated by the underlying string-keyed Singleflighter, so
+// two keys with the same serialization share a flight.
+type KeyerGroup[K any, V any] struct {
+	base  Singleflighter[string, V]
+	keyFn func(K) (string, error)
+}
+
+// StringerGroup is a KeyerGroup over keys implementing fmt.Stringer, such
+// as UUID types, *url.URL or domain ID types, see NewStringerGroup.
+type StringerGroup[K fmt.Stringer, V any] = KeyerGroup[K, V]
+
+// keyBufPool recycles the buffers keys are appended to.
+var keyBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 64)
+		return &b
+	},
+}
+
+// NewKeyerGroup returns a KeyerGroup that serializes keys via their
+// SingleflightKey method and deduplicates on base. If base is nil, a new
+// Group is used.
+func NewKeyerGroup[K Keyer, V any](base Singleflighter[string, V]) *KeyerGroup[K, V] {
+	return newKeyerGroup(base, func(key K) (string, error) {
+		return key.SingleflightKey(), nil
+	})
+}
+
+// NewKeyAppenderGroup returns a KeyerGroup that serializes keys via their
+// AppendKey method and deduplicates on base. If base is nil, a new Group is
+// used.
+func NewKeyAppenderGroup[K KeyAppender, V any](base Singleflighter[string, V]) *KeyerGroup[K, V] {
+	return newKeyerGroup(base, func(key K) (string, error) {
+		bp := keyBufPool.Get().(*[]byte) //nolint:forcetypeassert
+		defer keyBufPool.Put(bp)
+
+		*bp = key.AppendKey((*bp)[:0])
+
+		return string(*bp), nil
+	})
+}
+
+// NewStringerGroup returns a StringerGroup that serializes keys via their
+// String method and deduplicates on base. If base is nil, a new Group is
+// used.
+func NewStringerGroup[K fmt.Stringer, V any](base Singleflighter[string, V]) *StringerGroup[K, V] {
+	return newKeyerGroup(base, func(key K) (string, error) {
+		return key.String(), nil
+	})
+}
+
+// NewBinaryMarshalerGroup returns a KeyerGroup over keys implementing
+// encoding.BinaryMarshaler, such as protobuf or struct identifiers, and
+// deduplicates on base. Keys are serialized as the hex-encoded 128-bit
+// SHA-256 digest of their marshaled bytes. If marshaling a key fails, the
+// call fails with the marshaling error without executing fn. If base is
+// nil, a new Group is used.
+func NewBinaryMarshalerGroup[K encoding.BinaryMarshaler, V any](
+	base Singleflighter[string, V],
+) *KeyerGroup[K, V] {
+	return newKeyerGroup(base, func(key K) (string, error) {
+		b, err := key.MarshalBinary()
+		if err != nil {
+			return "", fmt.Errorf("singleflight: marshal key: %w", err)
+		}
+
+		sum := sha256.Sum256(b)
+
+		return hex.EncodeToString(sum[:len(keyDigest{})]), nil
+	})
+}
+
+// newKeyerGroup returns a KeyerGroup deduplicating on base by keyFn.
+func newKeyerGroup[K, V any](
+	base Singleflighter[string, V], keyFn func(K) (string, error),
+) *KeyerGroup[K, V] {
+	if base == nil {
+		base = &Group[string, V]{}
+	}
+
+	return &KeyerGroup[K, V]{
+		base:  base,
+		keyFn: keyFn,
+	}
+}
+
+// Do executes and deduplicates fn for the serialized form of key.
+//
+// Behavior matches the Do method of the underlying Singleflighter. If key
+// cannot be serialized, Do returns the serialization error.
+func (kg *KeyerGroup[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	k, err := kg.keyFn(key)
+	if err != nil {
+		return v, err, false
+	}
+
+	return kg.base.Do(k, fn)
+}
+
+// DoChan is the channel-based variant of Do.
+func (kg *KeyerGroup[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	k, err := kg.keyFn(key)
+	if err != nil {
+		ch := make(chan Result[V], 1)
+		ch <- Result[V]{Err: err}
+
+		return ch
+	}
+
+	return kg.base.DoChan(k, fn)
+}
+
+// DoContext is like Do, but stops waiting when ctx is done, returning
+// ctx.Err() while the execution continues for the other callers.
+func (kg *KeyerGroup[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return waitContext(ctx, kg.DoChan(key, fn))
+}
+
+// Forget forgets the flight of the serialized form of key and reports
+// whether there was one. Keys that cannot be serialized have no flight to
+// forget.
+func (kg *KeyerGroup[K, V]) Forget(key K) bool {
+	k, err := kg.keyFn(key)
+	if err != nil {
+		return false
+	}
+
+	return kg.base.Forget(k)
+}
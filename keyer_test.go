@@ -0,0 +1,206 @@
+package singleflight
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// searchQuery is not comparable, so it cannot be a Group key directly.
+type searchQuery struct {
+	terms []string
+	limit int
+}
+
+func (q searchQuery) SingleflightKey() string {
+	return strings.Join(q.terms, ",") + "|" + strconv.Itoa(q.limit)
+}
+
+func (q searchQuery) AppendKey(dst []byte) []byte {
+	for i, term := range q.terms {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, term...)
+	}
+	dst = append(dst, '|')
+
+	return strconv.AppendInt(dst, int64(q.limit), 10)
+}
+
+func TestKeyerGroup(t *testing.T) {
+	kg := NewKeyerGroup[searchQuery, int](nil)
+	keyerDedupe(t, kg)
+}
+
+func TestKeyAppenderGroup(t *testing.T) {
+	kg := NewKeyAppenderGroup[searchQuery, int](NewShardedGroup[string, int]())
+	keyerDedupe(t, kg)
+}
+
+func keyerDedupe(t *testing.T, kg *KeyerGroup[searchQuery, int]) {
+	t.Helper()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	queries := []searchQuery{
+		{terms: []string{"go", "singleflight"}, limit: 10},
+		{terms: []string{"go", "singleflight"}, limit: 10},
+		{terms: []string{"go", "singleflight"}, limit: 20},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(queries))
+	for _, q := range queries {
+		go func() {
+			defer wg.Done()
+			kg.Do(q, fn)
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	// equal serializations share a flight
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+
+	if res := <-kg.DoChan(queries[0], func() (int, error) { return 1, nil }); res.Val != 1 {
+		t.Fatalf("DoChan val=%d, want 1", res.Val)
+	}
+}
+
+// tenantKey is comparable and serializes itself for string-based options.
+type tenantKey struct {
+	tenant string
+	id     int
+}
+
+func (k tenantKey) SingleflightKey() string {
+	return k.tenant + ":" + strconv.Itoa(k.id)
+}
+
+func TestGroupKeyerKeyString(t *testing.T) {
+	g := NewGroup[tenantKey, int](WithRateLimit("acme:", 0.001, 1))
+	fn := func() (int, error) { return wantValueInt, nil }
+
+	if _, err, _ := g.Do(tenantKey{"acme", 1}, fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if _, err, _ := g.Do(tenantKey{"acme", 2}, fn); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err=%v, want %v", err, ErrRateLimited)
+	}
+	if _, err, _ := g.Do(tenantKey{"other", 1}, fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+}
+
+func TestStringerGroup(t *testing.T) {
+	sg := NewStringerGroup[*url.URL, int](nil)
+
+	release := make(chan struct{})
+	var calls int32
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	urls := []string{"https://example.com/a", "https://example.com/a", "https://example.com/b"}
+
+	var wg sync.WaitGroup
+	wg.Add(len(urls))
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			defer wg.Done()
+			sg.Do(u, fn)
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	// distinct pointers with equal String forms share a flight
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+}
+
+// orderID marshals itself into a binary form; zero IDs are invalid.
+type orderID struct {
+	shop uint16
+	seq  uint64
+}
+
+var errZeroOrderID = errors.New("zero order id")
+
+func (id orderID) MarshalBinary() ([]byte, error) {
+	if id == (orderID{}) {
+		return nil, errZeroOrderID
+	}
+
+	return binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint16(nil, id.shop), id.seq), nil
+}
+
+func TestBinaryMarshalerGroup(t *testing.T) {
+	bg := NewBinaryMarshalerGroup[orderID, int](nil)
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	ids := []orderID{{shop: 1, seq: 7}, {shop: 1, seq: 7}, {shop: 2, seq: 7}}
+
+	var wg sync.WaitGroup
+	wg.Add(len(ids))
+	for _, id := range ids {
+		go func() {
+			defer wg.Done()
+			bg.Do(id, fn)
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+
+	// marshaling errors surface to the caller without executing fn
+	if _, err, _ := bg.Do(orderID{}, fn); !errors.Is(err, errZeroOrderID) {
+		t.Fatalf("err=%v, want %v", err, errZeroOrderID)
+	}
+	if res := <-bg.DoChan(orderID{}, fn); !errors.Is(res.Err, errZeroOrderID) {
+		t.Fatalf("DoChan err=%v, want %v", res.Err, errZeroOrderID)
+	}
+	bg.Forget(orderID{})
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls after errors = %d, want 2", got)
+	}
+}
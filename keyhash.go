@@ -0,0 +1,55 @@
+package singleflight
+
+import "crypto/sha256"
+
+// keyDigest is the fixed-size digest a long key is tracked by.
+type keyDigest [16]byte
+
+// Tags prefixing the text a long key is hashed from, so that a string key
+// and a Keyer key of an interface key type never share a digest.
+const (
+	digestString byte = 's'
+	digestKeyer  byte = 'k'
+)
+
+// flightKey returns the key under which the flight of key in lane l is
+// tracked.
+//
+// If the group normalizes keys (see WithKeyNormalizer) and key is a string
+// (see WithStringKeys), the flight is tracked by the normalized form of key.
+// If the group hashes long keys (see WithLongKeyHashing) and key is a string
+// longer than the threshold in that form, or a Keyer whose SingleflightKey
+// is, the flight is tracked by a 128-bit SHA-256 digest of it instead. Keys
+// of other types are never hashed, as their textual form need not identify
+// them.
+func (g *Group[K, V]) flightKey(key K, l lane) flightKey[K] {
+	config := g.settings()
+	normalize, threshold := config.keyNormalizer, config.longKeyThreshold
+	if normalize == nil && threshold <= 0 {
+		return flightKey[K]{key: key, lane: l}
+	}
+
+	tag := digestString
+	s, ok := stringKey(config, key)
+	switch {
+	case ok && normalize != nil:
+		s = normalize(s)
+	case !ok:
+		normalize = nil
+		if k, keyer := any(key).(Keyer); keyer {
+			s, ok, tag = k.SingleflightKey(), true, digestKeyer
+		}
+	}
+
+	if ok && threshold > 0 && len(s) > threshold {
+		sum := sha256.Sum256(append([]byte{tag}, s...))
+
+		return flightKey[K]{digest: keyDigest(sum[:len(keyDigest{})]), lane: l}
+	}
+
+	if normalize != nil {
+		return flightKey[K]{text: s, lane: l}
+	}
+
+	return flightKey[K]{key: key, lane: l}
+}
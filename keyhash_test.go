@@ -0,0 +1,162 @@
+package singleflight
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupLongKeyHashing(t *testing.T) {
+	g := NewGroup[string, string](WithLongKeyHashing(16))
+
+	long := "https://example.com/" + strings.Repeat("a", 64)
+	other := long + "b"
+
+	release := make(chan struct{})
+	fn := func(v string) func() (string, error) {
+		return func() (string, error) {
+			<-release
+			return v, nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	got := make([]string, 3)
+	go func() { defer wg.Done(); got[0], _, _ = g.Do(long, fn("long")) }()
+	go func() { defer wg.Done(); got[1], _, _ = g.Do(other, fn("other")) }()
+	go func() { defer wg.Done(); got[2], _, _ = g.Do(keyA, fn("short")) }()
+	time.Sleep(sleepJoin)
+
+	g.mu.Lock()
+	for fk := range g.m {
+		if len(fk.key) > 16 {
+			t.Errorf("flight tracked by %d byte key, want digest", len(fk.key))
+		}
+	}
+	if len(g.m) != 3 {
+		t.Errorf("flights=%d, want 3", len(g.m))
+	}
+	g.mu.Unlock()
+
+	// joins the in-flight call of the same long key
+	ch := g.DoChan(long, fn("joined"))
+
+	close(release)
+	wg.Wait()
+
+	if res := <-ch; res.Val != "long" || !res.Shared {
+		t.Fatalf("joined val=%q shared=%v, want %q true", res.Val, res.Shared, "long")
+	}
+	if got[0] != "long" || got[1] != "other" || got[2] != "short" {
+		t.Fatalf("got=%q, want [long other short]", got)
+	}
+}
+
+func TestGroupLongKeyHashingForget(t *testing.T) {
+	g := NewGroup[string, int](WithLongKeyHashing(16))
+	long := strings.Repeat("k", 32)
+
+	release := make(chan struct{})
+	ch := g.DoChan(long, func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	time.Sleep(sleepJoin)
+
+	g.Forget(long)
+
+	v, _, shared := g.Do(long, func() (int, error) { return 2, nil })
+	if v != 2 || shared {
+		t.Fatalf("v=%d shared=%v after Forget, want 2 false", v, shared)
+	}
+
+	close(release)
+	<-ch
+}
+
+func TestGroupLongKeyHashingKeepsOriginalKey(t *testing.T) {
+	var seen string
+	g := NewGroup[string, int](
+		WithLongKeyHashing(8),
+		WithCostBudget(10, CostReject),
+		WithCostFn(func(key string) int64 {
+			seen = key
+			return 1
+		}),
+	)
+
+	long := "search:" + strings.Repeat("q", 32)
+	if _, err, _ := g.Do(long, func() (int, error) { return 1, nil }); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if seen != long {
+		t.Fatalf("cost fn saw %q, want %q", seen, long)
+	}
+}
+
+func TestGroupLongKeyHashingOtherKeys(t *testing.T) {
+	type pair struct{ a, b string }
+
+	// keys with equal textual forms are not hashed, so they stay distinct
+	g := NewGroup[pair, string](WithLongKeyHashing(2))
+
+	release := make(chan struct{})
+	ch := g.DoChan(pair{"x y", "z"}, func() (string, error) { <-release; return "first", nil })
+	time.Sleep(sleepJoin)
+
+	if v, _, shared := g.Do(pair{"x", "y z"}, func() (string, error) { return "second", nil }); v != "second" || shared {
+		t.Fatalf("v=%q shared=%v, want second false", v, shared)
+	}
+	close(release)
+	if res := <-ch; res.Val != "first" {
+		t.Fatalf("val=%q, want first", res.Val)
+	}
+}
+
+func TestGroupLongKeyHashingKeyer(t *testing.T) {
+	g := NewGroup[any, int](WithLongKeyHashing(4))
+	key := tenantKey{tenant: "acme", id: 42}
+
+	fk := g.flightKey(key, laneNormal)
+	if fk.digest == (keyDigest{}) {
+		t.Fatal("Keyer key not hashed, want digest")
+	}
+	if g.flightKey(key.SingleflightKey(), laneNormal) == fk {
+		t.Fatal("string key shares the digest of a Keyer key with its serialization")
+	}
+}
+
+func TestGroupLongKeyRetention(t *testing.T) {
+	long := strings.Repeat("k", 32)
+
+	for _, retain := range []bool{false, true} {
+		opts := []GroupConfigOption{WithLongKeyHashing(16)}
+		if retain {
+			opts = append(opts, WithLongKeyRetention())
+		}
+		g := NewGroup[string, int](opts...)
+
+		release := make(chan struct{})
+		ch := g.DoChan(long, func() (int, error) {
+			<-release
+			return 1, nil
+		})
+		time.Sleep(sleepJoin)
+
+		want := 0
+		if retain {
+			want = 1
+		}
+		if got := len(g.Keys()); got != want {
+			t.Errorf("retain=%v: len(Keys)=%d, want %d", retain, got, want)
+		}
+		if got := g.ForgetPrefix("k"); got != want {
+			t.Errorf("retain=%v: ForgetPrefix=%d, want %d", retain, got, want)
+		}
+
+		close(release)
+		<-ch
+	}
+}
@@ -0,0 +1,74 @@
+package singleflight
+
+import (
+	"context"
+	"strings"
+)
+
+// KeyspaceSeparator separates the name of a Keyspace from the keys within
+// it on the underlying group.
+const KeyspaceSeparator = ":"
+
+// Keyspace is a namespaced view of a string-keyed Singleflighter, see
+// NewKeyspace.
+//
+// Keys of a Keyspace are of their own type T, so keys of unrelated
+// keyspaces sharing one group cannot be mixed up at compile time, and
+// equal keys of different keyspaces never share a flight.
+type Keyspace[T ~string, V any] struct {
+	base   Singleflighter[string, V]
+	name   string
+	prefix string
+}
+
+// NewKeyspace returns the keyspace name of base. Keys are passed to base
+// as name, KeyspaceSeparator and the key, so options of base matching key
+// prefixes, such as WithRateLimit, can target a keyspace as a whole. If
+// base is nil, a new Group is used.
+//
+// NewKeyspace panics if name is empty or contains KeyspaceSeparator.
+func NewKeyspace[T ~string, V any](base Singleflighter[string, V], name string) *Keyspace[T, V] {
+	if name == "" || strings.Contains(name, KeyspaceSeparator) {
+		panic("singleflight: invalid keyspace name " + `"` + name + `"`)
+	}
+	if base == nil {
+		base = &Group[string, V]{}
+	}
+
+	return &Keyspace[T, V]{
+		base:   base,
+		name:   name,
+		prefix: name + KeyspaceSeparator,
+	}
+}
+
+// Name returns the name of the keyspace.
+func (ks *Keyspace[T, V]) Name() string {
+	return ks.name
+}
+
+// Do executes and deduplicates fn for key within the keyspace.
+//
+// Behavior matches the Do method of the underlying Singleflighter.
+func (ks *Keyspace[T, V]) Do(key T, fn func() (V, error)) (v V, err error, shared bool) {
+	return ks.base.Do(ks.prefix+string(key), fn)
+}
+
+// DoChan is the channel-based variant of Do.
+func (ks *Keyspace[T, V]) DoChan(key T, fn func() (V, error)) <-chan Result[V] {
+	return ks.base.DoChan(ks.prefix+string(key), fn)
+}
+
+// DoContext is like Do, but stops waiting when ctx is done, returning
+// ctx.Err() while the execution continues for the other callers.
+func (ks *Keyspace[T, V]) DoContext(
+	ctx context.Context, key T, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return waitContext(ctx, ks.DoChan(key, fn))
+}
+
+// Forget forgets the flight of key within the keyspace and reports whether
+// there was one.
+func (ks *Keyspace[T, V]) Forget(key T) bool {
+	return ks.base.Forget(ks.prefix + string(key))
+}
@@ -0,0 +1,86 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type (
+	profileKey string
+	sessionKey string
+)
+
+func TestKeyspace(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	profiles := NewKeyspace[profileKey](sg, "profiles")
+	sessions := NewKeyspace[sessionKey](sg, "sessions")
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() { defer wg.Done(); profiles.Do("42", fn) }()
+	go func() { defer wg.Done(); profiles.Do("42", fn) }()
+	go func() { defer wg.Done(); sessions.Do("42", fn) }()
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	// equal keys share a flight within a keyspace only
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+
+	if res := <-profiles.DoChan("42", func() (int, error) { return 1, nil }); res.Val != 1 {
+		t.Fatalf("DoChan val=%d, want 1", res.Val)
+	}
+}
+
+func TestKeyspaceNilBase(t *testing.T) {
+	doDedupe(t, NewKeyspace[profileKey, int](nil, "profiles"), "42")
+}
+
+func TestKeyspaceDoContext(t *testing.T) {
+	var g Group[string, int]
+	doContextStopsWaiting(t, NewKeyspace[profileKey](&g, "profiles"), "42")
+}
+
+func TestKeyspaceRateLimit(t *testing.T) {
+	g := NewGroup[string, int](WithRateLimit("profiles:", 0.001, 1))
+	profiles := NewKeyspace[profileKey](g, "profiles")
+	sessions := NewKeyspace[sessionKey](g, "sessions")
+	fn := func() (int, error) { return wantValueInt, nil }
+
+	if _, err, _ := profiles.Do("1", fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if _, err, _ := profiles.Do("2", fn); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err=%v, want %v", err, ErrRateLimited)
+	}
+	if _, err, _ := sessions.Do("1", fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+}
+
+func TestKeyspaceInvalidName(t *testing.T) {
+	for _, name := range []string{"", "a:b"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewKeyspace(%q) did not panic", name)
+				}
+			}()
+			NewKeyspace[profileKey](&Group[string, int]{}, name)
+		}()
+	}
+}
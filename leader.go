@@ -0,0 +1,50 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+)
+
+// LeaderErrorPolicy determines what callers that joined a flight receive
+// if the execution of the caller that started it fails, see
+// WithLeaderErrors.
+type LeaderErrorPolicy int
+
+const (
+	// LeaderErrorShare hands the error of the flight to every caller. This
+	// is the default.
+	LeaderErrorShare LeaderErrorPolicy = iota
+	// LeaderErrorFail hands a *LeaderFailedError to the callers that joined
+	// the flight, so they can decide whether to retry themselves.
+	LeaderErrorFail
+	// LeaderErrorRetry makes the callers that joined the flight via Do or
+	// its variants execute their own function once more: the first of them
+	// starts a new flight, the others join it. Callers waiting on a channel
+	// (DoChan, DoContext and their variants) receive a *LeaderFailedError
+	// instead, as do callers whose retried flight fails again.
+	LeaderErrorRetry
+)
+
+// leaderFailed reports whether the completed call c failed in a way its
+// leader error policy applies to.
+func (c *call[V]) leaderFailed() bool {
+	return c.leaderErrors != LeaderErrorShare && c.err != nil && c.err != errGoexit //nolint:errorlint
+}
+
+// rerun reports whether a caller that joined the completed call c via Do
+// re-executes its function due to LeaderErrorRetry, unless it already did
+// so.
+func (c *call[V]) rerun(retried bool) bool {
+	return !retried && c.leaderErrors == LeaderErrorRetry && c.leaderFailed()
+}
+
+// abdicated reports whether the completed call c failed because the context
+// of the caller leading it via DoContext was done, so callers that joined it
+// elect a new leader, see WithLeaderReelection.
+func (c *call[V]) abdicated() bool {
+	if !c.reelect || c.leader == nil || c.leader.Err() == nil {
+		return false
+	}
+
+	return errors.Is(c.err, context.Canceled) || errors.Is(c.err, context.DeadlineExceeded)
+}
@@ -0,0 +1,182 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupLeaderErrorsFail(t *testing.T) {
+	g := NewGroup[string, int](WithLeaderErrors(LeaderErrorFail))
+
+	errFn := errors.New("failed")
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return 0, errFn
+	}
+
+	leader := g.DoChan(keyA, fn)
+	time.Sleep(sleepJoin)
+	joined := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do(keyA, fn)
+		joined <- err
+	}()
+	time.Sleep(sleepJoin)
+	close(release)
+
+	if res := <-leader; !errors.Is(res.Err, errFn) {
+		t.Fatalf("leader err=%v, want %v", res.Err, errFn)
+	}
+
+	err := <-joined
+	var lfe *LeaderFailedError
+	if !errors.Is(err, ErrLeaderFailed) || errors.Is(err, errFn) || !errors.As(err, &lfe) || lfe.Err != errFn { //nolint:errorlint
+		t.Fatalf("waiter err=%v, want %v carrying %v", err, ErrLeaderFailed, errFn)
+	}
+}
+
+func TestGroupLeaderErrorsRetry(t *testing.T) {
+	g := NewGroup[string, int](WithLeaderErrors(LeaderErrorRetry))
+	leaderErrorsRetry(t, g, keyA)
+}
+
+func TestShardedGroupLeaderErrorsRetry(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithLeaderErrors(LeaderErrorRetry)))
+	leaderErrorsRetry(t, sg, keyB)
+}
+
+func leaderErrorsRetry[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	errFn := errors.New("failed")
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			return 0, errFn
+		}
+		time.Sleep(sleepJoin)
+		return wantValueInt, nil
+	}
+
+	leader := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, _ := d.Do(key, fn)
+			if err == nil && v != wantValueInt {
+				err = errors.New("unexpected value")
+			}
+			errs <- err
+		}()
+	}
+	waiter := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	if res := <-leader; !errors.Is(res.Err, errFn) {
+		t.Fatalf("leader err=%v, want %v", res.Err, errFn)
+	}
+	if res := <-waiter; !errors.Is(res.Err, ErrLeaderFailed) {
+		t.Fatalf("DoChan waiter err=%v, want %v", res.Err, ErrLeaderFailed)
+	}
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Do waiter err=%v, want retried %d", err, wantValueInt)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("calls=%d, want the failed execution and one retry", got)
+	}
+}
+
+func TestGroupLeaderReelection(t *testing.T) {
+	g := NewGroup[string, int](WithLeaderReelection())
+	leaderReelection(t, g, keyA)
+}
+
+func TestShardedGroupLeaderReelection(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithLeaderReelection()))
+	leaderReelection(t, sg, keyB)
+}
+
+type reelectionDoer[T ~string] interface {
+	doer[T, int]
+	contextDoer[T, int]
+}
+
+func leaderReelection[T ~string](t *testing.T, d reelectionDoer[T], key T) {
+	t.Helper()
+
+	var calls int32
+	ctx, cancel := context.WithCancel(t.Context())
+	leader := make(chan error, 1)
+	go func() {
+		// the work function uses the request context of the leader
+		_, err, _ := d.DoContext(ctx, key, func() (int, error) {
+			atomic.AddInt32(&calls, 1)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		leader <- err
+	}()
+	time.Sleep(sleepJoin)
+
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(sleepJoin)
+		return wantValueInt, nil
+	}
+	results := make(chan Result[int], 2)
+	go func() {
+		v, err, _ := d.Do(key, fn)
+		results <- Result[int]{Val: v, Err: err}
+	}()
+	go func() {
+		v, err, _ := d.DoContext(t.Context(), key, fn)
+		results <- Result[int]{Val: v, Err: err}
+	}()
+	time.Sleep(sleepJoin)
+	cancel()
+
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader err=%v, want %v", err, context.Canceled)
+	}
+	for range 2 {
+		if res := <-results; res.Val != wantValueInt || res.Err != nil {
+			t.Fatalf("waiter res=%+v, want %d from a new leader", res, wantValueInt)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("calls=%d, want the abandoned execution and one re-election", got)
+	}
+}
+
+func TestGroupLeaderReelectionDisabled(t *testing.T) {
+	var g Group[string, int]
+
+	ctx, cancel := context.WithCancel(t.Context())
+	go g.DoContext(ctx, keyA, func() (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	time.Sleep(sleepJoin)
+	time.AfterFunc(sleepJoin, cancel)
+
+	if _, err, _ := g.Do(keyA, func() (int, error) { return wantValueInt, nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("waiter err=%v, want %v by default", err, context.Canceled)
+	}
+}
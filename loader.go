@@ -0,0 +1,115 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBatchWindow is the time a Loader collects keys for a batch by
+// default.
+const DefaultBatchWindow = time.Millisecond
+
+// Batcher deduplicates batch loads of keys, see Group.DoBatch.
+type Batcher[K comparable, V any] interface {
+	DoBatch(keys []K, fn func(keys []K) (map[K]V, error)) map[K]Result[V]
+}
+
+// Loader collects the keys of individual Load calls arriving within a time
+// window into a single execution of a batch loader, in the manner of the
+// DataLoader pattern. Duplicate keys within a window are loaded once, and
+// keys already in flight on the underlying Batcher are joined.
+type Loader[K comparable, V any] struct {
+	group  Batcher[K, V]
+	fetch  func(keys []K) (map[K]V, error)
+	config LoaderConfig
+
+	mu    sync.Mutex
+	batch *loaderBatch[K, V]
+}
+
+// loaderBatch is the batch a Loader is collecting keys for.
+type loaderBatch[K comparable, V any] struct {
+	keys    []K
+	waiters map[K][]chan<- Result[V]
+	timer   *time.Timer
+}
+
+// NewLoader constructs a Loader executing fetch for batches of keys on
+// group, configured by opts. If group is nil, a new Group is used.
+func NewLoader[K comparable, V any](
+	group Batcher[K, V], fetch func(keys []K) (map[K]V, error), opts ...LoaderConfigOption,
+) *Loader[K, V] {
+	if group == nil {
+		group = &Group[K, V]{}
+	}
+
+	l := &Loader[K, V]{
+		group:  group,
+		fetch:  fetch,
+		config: LoaderConfig{window: DefaultBatchWindow},
+	}
+
+	for _, opt := range opts {
+		opt(&l.config)
+	}
+
+	return l
+}
+
+// Load adds key to the batch being collected and returns its value once
+// the batch is loaded. The batch is loaded when its window has passed or
+// it reaches the maximum batch size, whichever comes first.
+//
+// If ctx is done before the value is available, Load returns ctx.Err()
+// while the batch is loaded for its other callers.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	ch := make(chan Result[V], 1)
+
+	l.mu.Lock()
+	b := l.batch
+	if b == nil {
+		b = &loaderBatch[K, V]{waiters: make(map[K][]chan<- Result[V])}
+		b.timer = time.AfterFunc(l.config.window, func() { l.dispatch(b) })
+		l.batch = b
+	}
+
+	if _, ok := b.waiters[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.waiters[key] = append(b.waiters[key], ch)
+
+	// a full batch takes no further keys
+	full := l.config.maxBatch > 0 && len(b.keys) >= l.config.maxBatch
+	if full {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	if full && b.timer.Stop() {
+		go l.dispatch(b)
+	}
+
+	v, err, _ := waitContext(ctx, ch)
+
+	return v, err
+}
+
+// dispatch loads the batch b and hands every waiter the result of its key.
+func (l *Loader[K, V]) dispatch(b *loaderBatch[K, V]) {
+	l.mu.Lock()
+	if l.batch == b {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	results := l.group.DoBatch(b.keys, l.fetch)
+
+	for key, waiters := range b.waiters {
+		res := results[key]
+		res.Shared = res.Shared || len(waiters) > 1
+		for _, ch := range waiters {
+			ch <- res
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFetch returns a batch loader recording its batches, loading
+// every key as its length.
+func recordingFetch(mu *sync.Mutex, batches *[][]string) func([]string) (map[string]int, error) {
+	return func(keys []string) (map[string]int, error) {
+		mu.Lock()
+		*batches = append(*batches, slices.Clone(keys))
+		mu.Unlock()
+
+		vals := make(map[string]int, len(keys))
+		for _, key := range keys {
+			vals[key] = len(key)
+		}
+		return vals, nil
+	}
+}
+
+func TestLoaderBatchesWindow(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	l := NewLoader[string, int](nil, recordingFetch(&mu, &batches), WithBatchWindow(sleepJoin))
+
+	keys := []string{"a", "bb", "a", "ccc"}
+
+	var wg sync.WaitGroup
+	wg.Add(len(keys))
+	for _, key := range keys {
+		go func() {
+			defer wg.Done()
+			if v, err := l.Load(t.Context(), key); v != len(key) || err != nil {
+				t.Errorf("Load(%q)=%d,%v, want %d nil", key, v, err, len(key))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("batches=%v, want one batch of 3 distinct keys", batches)
+	}
+}
+
+func TestLoaderMaxBatchSize(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	l := NewLoader[string, int](nil, recordingFetch(&mu, &batches),
+		WithBatchWindow(time.Minute), WithMaxBatchSize(2))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for _, key := range []string{"a", "bb"} {
+		go func() {
+			defer wg.Done()
+			l.Load(t.Context(), key)
+		}()
+	}
+	wg.Wait()
+
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("batches=%v, want one full batch", batches)
+	}
+}
+
+func TestLoaderLoadContext(t *testing.T) {
+	l := NewLoader[string, int](nil, func(keys []string) (map[string]int, error) {
+		return nil, errors.New("unused")
+	}, WithBatchWindow(time.Minute))
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+
+	if _, err := l.Load(ctx, keyA); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,66 @@
+package singleflight
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logger logs completed executions, see WithLogger.
+type logger struct {
+	logger *slog.Logger
+	slow   time.Duration
+}
+
+// newLogger returns a logger logging to l, or nil if l is nil.
+func newLogger(l *slog.Logger, slowThreshold time.Duration) *logger {
+	if l == nil {
+		return nil
+	}
+
+	return &logger{logger: l, slow: slowThreshold}
+}
+
+// log logs the execution of the flight c of the key k that took d, if it
+// failed, panicked or was slow. The caller must not hold g.mu.
+func (g *Group[K, V]) log(l *logger, k string, d time.Duration, c *call[V]) {
+	level, msg := slog.LevelWarn, "singleflight: execution failed"
+	switch c.err.(type) { //nolint:errorlint
+	case nil:
+		if l.slow <= 0 || d <= l.slow {
+			return
+		}
+		msg = "singleflight: slow execution"
+	case *PanicError:
+		level, msg = slog.LevelError, "singleflight: execution panicked"
+	}
+
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	g.mu.Lock()
+	waiters := c.dups
+	g.mu.Unlock()
+
+	attrs := make([]slog.Attr, 0, 6)
+	if name := g.settings().name; name != "" {
+		attrs = append(attrs, slog.String("group", name))
+	}
+	attrs = append(attrs,
+		slog.String("key", k),
+		slog.Duration("duration", d),
+		slog.Int("waiters", waiters),
+	)
+	if g.shard > 0 {
+		attrs = append(attrs, slog.Int("shard", g.shard-1))
+	}
+	if pe, ok := c.err.(*PanicError); ok { //nolint:errorlint
+		attrs = append(attrs, slog.Any("panic", pe.Value))
+	} else if c.err != nil {
+		attrs = append(attrs, slog.Any("error", c.err))
+	}
+
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,57 @@
+package singleflight
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupLogger(t *testing.T) {
+	var buf bytes.Buffer
+	g := NewGroup[string, int](
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil)), sleepJoin),
+		WithRecoverPanics(),
+	)
+
+	g.Do(keyA, func() (int, error) { return wantValueInt, nil })
+	g.Do(keyA, func() (int, error) {
+		time.Sleep(2 * sleepJoin)
+		return wantValueInt, nil
+	})
+	g.Do(keyB, func() (int, error) { return 0, errors.New("upstream down") })
+	g.Do(keyB, func() (int, error) { panic("boom") })
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("logged %d records, want 3:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{
+		`level=WARN msg="singleflight: slow execution" key=` + keyA,
+		`level=WARN msg="singleflight: execution failed" key=` + keyB,
+		`level=ERROR msg="singleflight: execution panicked" key=` + keyB,
+	} {
+		if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "waiters=0") {
+			t.Fatalf("record %d=%q, want %q", i, lines[i], want)
+		}
+	}
+	if !strings.Contains(lines[1], `error="upstream down"`) || !strings.Contains(lines[2], "panic=boom") {
+		t.Fatalf("records lack error and panic:\n%s", buf.String())
+	}
+}
+
+func TestShardedGroupLogger(t *testing.T) {
+	var buf bytes.Buffer
+	sg := NewShardedGroup[string, int](WithGroupOptions(
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil)), 0),
+		WithName("users"),
+	))
+
+	sg.Do(keyA, func() (int, error) { return 0, errors.New("failed") })
+
+	if !strings.Contains(buf.String(), "group=users") || !strings.Contains(buf.String(), "shard=") {
+		t.Fatalf("record %q lacks the group name or shard", buf.String())
+	}
+}
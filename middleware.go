@@ -0,0 +1,25 @@
+package singleflight
+
+import "time"
+
+// Middleware decorates a Singleflighter with a layer of behavior, such as
+// caching, metrics or rate limiting, by wrapping the next layer.
+type Middleware[K comparable, V any] func(next Singleflighter[K, V]) Singleflighter[K, V]
+
+// Wrap composes base with the layers of mws. The first middleware is the
+// outermost layer, i.e. it sees every call first, and base the innermost.
+func Wrap[K comparable, V any](base Singleflighter[K, V], mws ...Middleware[K, V]) Singleflighter[K, V] {
+	for i := len(mws) - 1; i >= 0; i-- {
+		base = mws[i](base)
+	}
+
+	return base
+}
+
+// CacheMiddleware returns a Middleware caching the results of the next
+// layer for ttl, configured by opts, see NewCachedGroup.
+func CacheMiddleware[K comparable, V any](ttl time.Duration, opts ...CacheConfigOption) Middleware[K, V] {
+	return func(next Singleflighter[K, V]) Singleflighter[K, V] {
+		return NewCachedGroup(next, ttl, opts...)
+	}
+}
@@ -0,0 +1,52 @@
+package singleflight
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tracing is a Singleflighter layer recording the calls it sees.
+type tracing struct {
+	Singleflighter[string, int]
+
+	name  string
+	calls *[]string
+}
+
+func (l tracing) Do(key string, fn func() (int, error)) (int, error, bool) {
+	*l.calls = append(*l.calls, l.name)
+	return l.Singleflighter.Do(key, fn)
+}
+
+func TestWrap(t *testing.T) {
+	var calls []string
+	layer := func(name string) Middleware[string, int] {
+		return func(next Singleflighter[string, int]) Singleflighter[string, int] {
+			return tracing{Singleflighter: next, name: name, calls: &calls}
+		}
+	}
+
+	var executions int32
+	sf := Wrap[string, int](&Group[string, int]{},
+		layer("outer"), CacheMiddleware[string, int](time.Minute), layer("inner"))
+	fn := func() (int, error) {
+		atomic.AddInt32(&executions, 1)
+		return wantValueInt, nil
+	}
+
+	for range 2 {
+		if v, err, _ := sf.Do(keyA, fn); v != wantValueInt || err != nil {
+			t.Fatalf("v=%d err=%v, want %d", v, err, wantValueInt)
+		}
+	}
+
+	// the second call is served by the cache layer before reaching inner
+	if want := []string{"outer", "inner", "outer"}; !slices.Equal(calls, want) {
+		t.Fatalf("calls=%q, want %q", calls, want)
+	}
+	if executions != 1 {
+		t.Fatalf("executions=%d, want 1", executions)
+	}
+}
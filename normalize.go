@@ -0,0 +1,33 @@
+package singleflight
+
+import "golang.org/x/text/unicode/norm"
+
+// NormalizeNFC returns key in Unicode Normalization Form C, for use with
+// WithKeyNormalizer. Canonically equivalent keys, such as "é" precomposed
+// and "e" followed by a combining acute accent, normalize alike.
+func NormalizeNFC(key string) string {
+	return norm.NFC.String(key)
+}
+
+// NormalizeNFKC returns key in Unicode Normalization Form KC, for use with
+// WithKeyNormalizer. In addition to NormalizeNFC, compatibility
+// equivalents such as the ligature "ﬁ" and "fi" or full-width and ASCII
+// digits normalize alike.
+func NormalizeNFKC(key string) string {
+	return norm.NFKC.String(key)
+}
+
+// stringKey returns the string held by key if K is string, or if config
+// converts keys of type K to strings, see WithStringKeys. Keys of interface
+// types only qualify if they hold a plain string, so values of distinct
+// string types never share a normalized form.
+func stringKey[K comparable](config *GroupConfig, key K) (string, bool) {
+	if k, ok := any(key).(string); ok {
+		return k, true
+	}
+	if text, ok := config.stringKeys.(func(K) string); ok {
+		return text(key), true
+	}
+
+	return "", false
+}
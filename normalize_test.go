@@ -0,0 +1,152 @@
+package singleflight
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupKeyNormalizer(t *testing.T) {
+	g := NewGroup[string, int](WithKeyNormalizer(NormalizeNFC))
+	normalizedDedupe(t, g, "caf\u00e9", "cafe\u0301")
+}
+
+func TestShardedGroupKeyNormalizer(t *testing.T) {
+	sg := NewShardedGroup[string, int](
+		WithShardCount(64),
+		WithGroupOptions(WithKeyNormalizer(NormalizeNFKC)),
+	)
+	normalizedDedupe(t, sg, "\ufb01le-42", "file-\uff14\uff12")
+}
+
+func TestGroupKeyNormalizerDefinedStrings(t *testing.T) {
+	type name string
+
+	g := NewGroup[name, int](WithStringKeys[name](), WithKeyNormalizer(NormalizeNFC))
+	normalizedDedupe(t, g, "caf\u00e9", "cafe\u0301")
+}
+
+// formattedName is a defined string type formatting like another string.
+type formattedName string
+
+func (formattedName) Format(f fmt.State, _ rune) { fmt.Fprint(f, `"name"`) }
+
+func TestGroupKeyNormalizerFormattedStrings(t *testing.T) {
+	for name, g := range map[string]*Group[formattedName, string]{
+		"without WithStringKeys": NewGroup[formattedName, string](WithKeyNormalizer(strings.ToLower)),
+		"with WithStringKeys": NewGroup[formattedName, string](
+			WithStringKeys[formattedName](), WithKeyNormalizer(strings.ToLower),
+		),
+	} {
+		release := make(chan struct{})
+		ch := g.DoChan("a", func() (string, error) { <-release; return "a", nil })
+		time.Sleep(sleepJoin)
+
+		// keys formatting alike are still distinct keys
+		if v, _, shared := g.Do("b", func() (string, error) { return "b", nil }); v != "b" || shared {
+			t.Fatalf("%s: v=%q shared=%v, want b false", name, v, shared)
+		}
+		close(release)
+		<-ch
+	}
+}
+
+func TestGroupStringKeysOfAnotherType(t *testing.T) {
+	type name string
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewGroup with string keys of another key type did not panic")
+		}
+	}()
+	NewGroup[string, int](WithStringKeys[name]())
+}
+
+func TestGroupKeyNormalizerOtherKeys(t *testing.T) {
+	type pair struct{ a, b string }
+
+	// keys with equal textual forms are still distinct keys
+	g := NewGroup[pair, string](WithKeyNormalizer(strings.ToLower))
+
+	release := make(chan struct{})
+	ch := g.DoChan(pair{"x y", "z"}, func() (string, error) { <-release; return "first", nil })
+	time.Sleep(sleepJoin)
+
+	if v, _, shared := g.Do(pair{"x", "y z"}, func() (string, error) { return "second", nil }); v != "second" || shared {
+		t.Fatalf("v=%q shared=%v, want second false", v, shared)
+	}
+	close(release)
+	if res := <-ch; res.Val != "first" {
+		t.Fatalf("val=%q, want first", res.Val)
+	}
+}
+
+func TestStringKey(t *testing.T) {
+	type name string
+
+	var config GroupConfig
+	if _, ok := stringKey(&config, name("a")); ok {
+		t.Error("stringKey(name) ok without WithStringKeys, want not ok")
+	}
+	WithStringKeys[name]()(&config)
+	if s, ok := stringKey(&config, name("a\"b")); s != "a\"b" || !ok {
+		t.Errorf("stringKey(name)=%q, %v, want a\"b true", s, ok)
+	}
+	if s, ok := stringKey[any](&config, "a"); s != "a" || !ok {
+		t.Errorf("stringKey(any(string))=%q, %v, want a true", s, ok)
+	}
+	if _, ok := stringKey[any](&config, name("a")); ok {
+		t.Error("stringKey(any(name)) ok, want not ok")
+	}
+	if _, ok := stringKey(&config, 42); ok {
+		t.Error("stringKey(int) ok, want not ok")
+	}
+}
+
+func TestStringKeyAllocs(t *testing.T) {
+	type name string
+
+	var config GroupConfig
+	WithStringKeys[name]()(&config)
+	if allocs := testing.AllocsPerRun(100, func() { stringKey(&config, name("user:42")) }); allocs > 0 {
+		t.Fatalf("allocs per stringKey of defined string = %v, want 0", allocs)
+	}
+}
+
+func normalizedDedupe[T ~string](t *testing.T, d doer[T, int], a, b T) {
+	t.Helper()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); d.Do(a, fn) }()
+	time.Sleep(sleepJoin)
+	go func() { defer wg.Done(); d.Do(b, fn) }()
+	time.Sleep(sleepJoin)
+
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("underlying calls = %d, want 1", got)
+	}
+}
+
+func TestNormalizeForms(t *testing.T) {
+	if got, want := NormalizeNFC("\ufb01"), "\ufb01"; got != want {
+		t.Fatalf("NormalizeNFC=%q, want %q", got, want)
+	}
+	if got, want := NormalizeNFKC("\ufb01"), "fi"; got != want {
+		t.Fatalf("NormalizeNFKC=%q, want %q", got, want)
+	}
+}
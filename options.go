@@ -3,6 +3,8 @@ package singleflight
 import (
 	"hash"
 	"hash/fnv"
+	"log/slog"
+	"time"
 )
 
 const (
@@ -25,8 +27,11 @@ func newHash() hash.Hash64 {
 // It determines the hash function to use and the number of shards
 // across which requests will be distributed.
 type ShardConfig struct {
-	hashFn     NewHash
-	shardCount uint64
+	hashFn      NewHash
+	keyHash     any
+	shardPicker any
+	groupOpts   []GroupConfigOption
+	shardCount  uint64
 }
 
 // ShardConfigOption defines a functional option for configuring ShardConfig.
@@ -43,8 +48,780 @@ func WithShardCount(shardCount uint64) ShardConfigOption {
 
 // WithHashFn returns a ShardConfigOption that sets a custom hash function
 // for computing shard indices. By default, fnv.New64a is used.
+//
+// The hash function only applies to keys of type string, [16]byte, int,
+// int32, int64, uint, uint32 and uint64, which have a canonical byte form.
+// Keys of other types, including defined string and integer types, are
+// hashed via maphash.Comparable with a seed random per group, so their
+// shards differ across processes; configure a hash over them via
+// WithKeyHash instead.
 func WithHashFn(hashFn NewHash) ShardConfigOption {
 	return func(config *ShardConfig) {
 		config.hashFn = hashFn
 	}
 }
+
+// WithKeyHash returns a ShardConfigOption that maps keys to shards by the
+// 64-bit hash returned by hash, taken modulo the shard count, instead of
+// hashing them via the hash function of WithHashFn. It applies to sharded
+// groups, KeyedMutex and KeyedLimiter, which panic on construction unless
+// their keys are of type K, and takes precedence over key normalization, so
+// hash must map keys sharing a flight alike.
+func WithKeyHash[K comparable](hash func(key K) uint64) ShardConfigOption {
+	return func(config *ShardConfig) {
+		config.keyHash = hash
+	}
+}
+
+// WithShardPicker returns a ShardConfigOption that routes keys to the shard
+// whose index pick returns, taken modulo the shard count, without hashing
+// them, e.g. by a numeric ID or tenant index embedded in the key. It
+// applies to sharded groups, KeyedMutex and KeyedLimiter, which panic on
+// construction unless their keys are of type K, and takes precedence over
+// WithKeyHash and key normalization, so pick must route keys sharing a
+// flight alike.
+func WithShardPicker[K comparable](pick func(key K) uint64) ShardConfigOption {
+	return func(config *ShardConfig) {
+		config.shardPicker = pick
+	}
+}
+
+// TenantConfig configures the behavior of a TenantGroup and of every
+// Tenant it hands out.
+type TenantConfig struct {
+	weightFn    func(id string) int
+	quota       int
+	poolWorkers int
+}
+
+// TenantConfigOption defines a functional option for configuring TenantConfig.
+type TenantConfigOption = func(*TenantConfig)
+
+// WithTenantQuota returns a TenantConfigOption that caps the number of
+// flights a single tenant may execute concurrently. Starting a flight beyond
+// the cap fails with ErrTenantQuota, while joining an in-flight call is
+// always allowed. By default, tenants are not capped.
+func WithTenantQuota(quota int) TenantConfigOption {
+	return func(config *TenantConfig) {
+		config.quota = quota
+	}
+}
+
+// SchedulerConfig configures the behavior of a Scheduler.
+type SchedulerConfig struct {
+	jitter      float64
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+// SchedulerConfigOption defines a functional option for configuring
+// SchedulerConfig.
+type SchedulerConfigOption = func(*SchedulerConfig)
+
+// WithJitter returns a SchedulerConfigOption that delays every refresh by a
+// random duration of up to fraction times the time until the refresh, so
+// keys registered at the same time do not refresh in lockstep. By default,
+// refreshes are not jittered.
+func WithJitter(fraction float64) SchedulerConfigOption {
+	return func(config *SchedulerConfig) {
+		config.jitter = fraction
+	}
+}
+
+// WithFailureBackoff returns a SchedulerConfigOption that holds off the
+// refresh of a key after consecutive failures for base, doubling with every
+// further failure up to maximum, unless its schedule is due even later.
+// By default, failed refreshes are retried on schedule.
+func WithFailureBackoff(base, maximum time.Duration) SchedulerConfigOption {
+	return func(config *SchedulerConfig) {
+		config.backoffBase = base
+		config.backoffMax = maximum
+	}
+}
+
+// CacheConfig configures the behavior of a CachedGroup.
+type CacheConfig struct {
+	errorTTL   func(err error) time.Duration
+	staleFor   time.Duration
+	maxEntries int
+	onEvict    any
+}
+
+// CacheConfigOption defines a functional option for configuring
+// CacheConfig.
+type CacheConfigOption = func(*CacheConfig)
+
+// WithErrorTTL returns a CacheConfigOption that caches failed results for
+// ttl, separately from the TTL of successful results, so a key whose
+// loader keeps failing is not executed again by every wave of callers.
+// Cancellations and expired deadlines are never cached. By default, errors
+// are not cached.
+func WithErrorTTL(ttl time.Duration) CacheConfigOption {
+	return WithErrorTTLFunc(func(error) time.Duration {
+		return ttl
+	})
+}
+
+// WithErrorTTLFunc returns a CacheConfigOption that caches failed results
+// for the TTL returned by ttl for their error, e.g. to cache "not found"
+// longer than a timeout. Errors for which ttl returns zero are not cached.
+// Cancellations and expired deadlines are never cached.
+func WithErrorTTLFunc(ttl func(err error) time.Duration) CacheConfigOption {
+	return func(config *CacheConfig) {
+		config.errorTTL = ttl
+	}
+}
+
+// WithServeStale returns a CacheConfigOption that keeps the last successful
+// result of a key for up to maxStale after it expired. If refreshing the
+// key fails within that window, its callers receive the last successful
+// result marked as Result.Stale instead of the error. By default, failed
+// refreshes return their error.
+func WithServeStale(maxStale time.Duration) CacheConfigOption {
+	return func(config *CacheConfig) {
+		config.staleFor = maxStale
+	}
+}
+
+// WithMaxEntries returns a CacheConfigOption that bounds the number of
+// cached results at n. Once the cache is full, caching another result
+// evicts the least recently used one. By default, the number of cached
+// results is not bounded.
+func WithMaxEntries(n int) CacheConfigOption {
+	return func(config *CacheConfig) {
+		config.maxEntries = n
+	}
+}
+
+// WithOnEvict returns a CacheConfigOption that calls fn with the key of
+// every result evicted from the cache and the reason of the eviction, e.g.
+// to log or meter evictions. NewCachedGroup panics if K is not the key type
+// of the cache. fn is called synchronously, outside of the cache's lock.
+func WithOnEvict[K comparable](fn func(key K, reason EvictionReason)) CacheConfigOption {
+	return func(config *CacheConfig) {
+		config.onEvict = fn
+	}
+}
+
+// LoaderConfig configures the behavior of a Loader.
+type LoaderConfig struct {
+	window   time.Duration
+	maxBatch int
+}
+
+// LoaderConfigOption defines a functional option for configuring
+// LoaderConfig.
+type LoaderConfigOption = func(*LoaderConfig)
+
+// WithBatchWindow returns a LoaderConfigOption that collects the keys of a
+// batch for d after its first key. By default, batches are collected for
+// DefaultBatchWindow.
+func WithBatchWindow(d time.Duration) LoaderConfigOption {
+	return func(config *LoaderConfig) {
+		config.window = d
+	}
+}
+
+// WithMaxBatchSize returns a LoaderConfigOption that loads a batch as soon
+// as it holds n distinct keys, without waiting for its window to pass. By
+// default, the size of batches is not bounded.
+func WithMaxBatchSize(n int) LoaderConfigOption {
+	return func(config *LoaderConfig) {
+		config.maxBatch = n
+	}
+}
+
+// WatchdogConfig configures the behavior of a Watchdog.
+type WatchdogConfig struct {
+	interval   time.Duration
+	autoForget bool
+}
+
+// WatchdogConfigOption defines a functional option for configuring
+// WatchdogConfig.
+type WatchdogConfigOption = func(*WatchdogConfig)
+
+// WithScanInterval returns a WatchdogConfigOption that scans the group
+// every d. By default, the group is scanned every half threshold.
+func WithScanInterval(d time.Duration) WatchdogConfigOption {
+	return func(config *WatchdogConfig) {
+		config.interval = d
+	}
+}
+
+// WithAutoForget returns a WatchdogConfigOption that forgets the stuck
+// flights it reports, so new callers of their keys start a fresh execution
+// instead of joining them. Callers of a forgotten flight keep waiting for
+// it. By default, stuck flights are only reported.
+func WithAutoForget() WatchdogConfigOption {
+	return func(config *WatchdogConfig) {
+		config.autoForget = true
+	}
+}
+
+// GroupConfig configures the behavior of a Group.
+type GroupConfig struct {
+	deadlineAware *DeadlinePolicy
+	costFn        func(key string) int64
+	costBudget    *costBudget
+	workerPool    *fairPool
+	maxConcurrent *fairPool
+
+	executionTimeout time.Duration
+	retry            *RetryPolicy
+	hedgeAfter       time.Duration
+	coalesceWindow   time.Duration
+	maxShareDuration time.Duration
+	detachedRetain   time.Duration
+
+	rateLimits    rateLimits
+	loadShed      LoadShedPolicy
+	maxWaiters    int
+	priorityLanes bool
+	recoverPanics bool
+	pprofLabels   bool
+	runtimeTrace  bool
+	sharedErrors  bool
+	leaderErrors  LeaderErrorPolicy
+	reelection    bool
+	observer      func(key string, d time.Duration, err error)
+	logger        *logger
+	recorder      *flightRecorder
+	hooks         any
+	cloner        any
+	validator     any
+	name          string
+
+	keyNormalizer    func(string) string
+	stringKeys       any
+	longKeyThreshold int
+	longKeyRetention bool
+	maxKeyLen        int
+	resultLimit      *resultLimit
+}
+
+// GroupConfigOption defines a functional option for configuring GroupConfig.
+type GroupConfigOption = func(*GroupConfig)
+
+// WithRecoverPanics returns a GroupConfigOption that converts a panic of
+// the executed function into a *PanicError carrying the panic value and
+// stack, delivered to every caller of the flight as its error. By default,
+// the panic is re-raised in the goroutine of every caller waiting via Do
+// and crashes the program for callers waiting via DoChan.
+func WithRecoverPanics() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.recoverPanics = true
+	}
+}
+
+// WithProfilerLabels returns a GroupConfigOption that labels the goroutine
+// executing a flight with the key of the flight (ProfilerLabelKey) and the
+// name of the group (ProfilerLabelGroup, see WithName), so CPU and goroutine
+// profiles show which keys spend time in executions. Keys longer than 64
+// bytes are truncated; keys hashed due to WithLongKeyHashing are labeled
+// with their digest. Goroutines started by the work function inherit the
+// labels. By default, goroutines are not labeled.
+func WithProfilerLabels() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.pprofLabels = true
+	}
+}
+
+// WithRuntimeTrace returns a GroupConfigOption that traces every flight
+// started while runtime tracing is enabled (see runtime/trace) as a task
+// "singleflight" logging the key of the flight and the name of the group,
+// with a region "executing" on the goroutine executing the flight and a
+// region "waiting" on every caller waiting for it via Do, DoContext or
+// DoContextFunc, so go tool trace shows who led, who waited and for how
+// long. By default, flights are not traced.
+func WithRuntimeTrace() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.runtimeTrace = true
+	}
+}
+
+// WithSharedErrors returns a GroupConfigOption that wraps the error of a
+// failed flight in a *SharedError for every caller that joined the flight
+// instead of executing it, so errors.Is(err, ErrShared) tells "my call
+// failed" from "another caller's call failed on my behalf", e.g. to retry
+// or alert differently. The wrapped error still matches the error of the
+// flight via errors.Is and errors.As. By default, every caller receives the
+// error as is.
+func WithSharedErrors() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.sharedErrors = true
+	}
+}
+
+// WithLeaderErrors returns a GroupConfigOption that applies policy to the
+// callers that joined a flight whose execution failed, so a single
+// transient failure is not amplified to every waiter. By default, every
+// caller receives the error of the flight (LeaderErrorShare).
+func WithLeaderErrors(policy LeaderErrorPolicy) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.leaderErrors = policy
+	}
+}
+
+// WithLeaderReelection returns a GroupConfigOption that promotes a waiter
+// to leader when the flight fails with context.Canceled or
+// context.DeadlineExceeded after the context of the caller that started it
+// via DoContext is done, e.g. because fn used the request context of the
+// leader. Instead of receiving the error, callers that joined the flight
+// via Do or DoContext (and whose own context is not done) run their own
+// function again: the first of them starts a new flight, the others join
+// it. Callers waiting via DoChan receive the error. By default, every
+// caller receives the error of the flight.
+func WithLeaderReelection() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.reelection = true
+	}
+}
+
+// WithDetachedExecution returns a GroupConfigOption that detaches
+// executions from their callers: a flight whose callers all stopped waiting
+// (via DoContext, DoContextFunc or a Handle) is neither forgotten nor
+// canceled, so it runs to completion and new callers join it. Once it
+// completes without any caller left waiting, its result is retained for
+// retain, so the next caller receives it instead of starting a new
+// execution. Useful when the work itself is valuable regardless of who is
+// still waiting, e.g. warming a cache. By default, the flight is forgotten
+// once no caller is left, and the context of DoContextFunc is canceled.
+func WithDetachedExecution(retain time.Duration) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.detachedRetain = retain
+	}
+}
+
+// WithExecutionObserver returns a GroupConfigOption that reports every
+// completed flight to observe, with the textual form of its key, the time
+// since it started and its error, e.g. to record execution durations as
+// metrics. observe is called on the goroutine completing the flight before
+// its callers are released, and must not block.
+func WithExecutionObserver(observe func(key string, d time.Duration, err error)) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.observer = observe
+	}
+}
+
+// WithLogger returns a GroupConfigOption that logs structured records to
+// logger for failed executions at level Warn, panicking executions at
+// level Error, and, if slowThreshold is positive, executions taking longer
+// than slowThreshold at level Warn. Records carry the key, the duration of
+// the execution, the number of waiters and, for shards of a ShardedGroup,
+// the shard index. By default, nothing is logged.
+func WithLogger(logger *slog.Logger, slowThreshold time.Duration) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.logger = newLogger(logger, slowThreshold)
+	}
+}
+
+// WithFlightRecorder returns a GroupConfigOption that keeps the last n
+// completed flights of a group (key, duration, waiters, error and shard)
+// for postmortems, see Group.Recent. The shards of a ShardedGroup share
+// the records. By default, completed flights are not recorded.
+func WithFlightRecorder(n int) GroupConfigOption {
+	var r *flightRecorder
+	if n > 0 {
+		r = &flightRecorder{records: make([]CallRecord, n)}
+	}
+
+	return func(config *GroupConfig) {
+		config.recorder = r
+	}
+}
+
+// WithName returns a GroupConfigOption that names a group, so that its
+// statistics and log records carry a stable name, and integrations such as
+// sfprom and otelsingleflight label their metrics and traces with it. Pass
+// it via WithGroupOptions to name a ShardedGroup. By default, a group is
+// unnamed.
+func WithName(name string) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.name = name
+	}
+}
+
+// WithHooks returns a GroupConfigOption that reports the lifecycle events
+// of the flights of a group to hooks, e.g. for custom metrics, logging or
+// auditing. K must be the key type of the group; NewGroup and UpdateConfig
+// panic on hooks of another key type. By default, no hooks are called.
+func WithHooks[K comparable](hooks Hooks[K]) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.hooks = hooks
+	}
+}
+
+// WithCloner returns a GroupConfigOption that hands every caller of a
+// flight a copy of its value made by clone, so callers may modify the
+// results they receive, e.g. pointers, maps or slices, without racing each
+// other. The caller that executed the flight receives a copy as well, and
+// the value stays pristine for callers served later, e.g. within the
+// coalescing window. V must be the value type of the group; NewGroup and
+// UpdateConfig panic on cloners of another type. By default, every caller
+// receives the same value.
+func WithCloner[V any](clone func(V) V) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.cloner = clone
+	}
+}
+
+// WithValidator returns a GroupConfigOption that runs validate on the
+// output of every execution before it is shared, so a malformed payload
+// does not poison every caller of the flight. If validate returns an
+// error, every caller receives a *ValidationError carrying it instead of
+// the value, and the result is not retained for the coalescing window.
+// Validation runs within retries (see WithRetry), so invalid results are
+// retried like failed executions; to have waiters re-run instead, combine
+// it with WithLeaderErrors(LeaderErrorRetry). V must be the value type of
+// the group; NewGroup and UpdateConfig panic on validators of another type.
+// By default, results are not validated.
+func WithValidator[V any](validate func(v V, err error) error) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.validator = validate
+	}
+}
+
+// WithMaxWaiters returns a GroupConfigOption that caps the number of callers
+// allowed to wait on a single in-flight call. Once n callers are waiting on
+// a key, additional callers fail immediately with ErrTooManyWaiters instead
+// of joining. By default, the number of waiters is not capped.
+func WithMaxWaiters(n int) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.maxWaiters = n
+	}
+}
+
+// WithGroupOptions returns a ShardConfigOption that applies opts to every
+// shard of a ShardedGroup.
+func WithGroupOptions(opts ...GroupConfigOption) ShardConfigOption {
+	return func(config *ShardConfig) {
+		config.groupOpts = append(config.groupOpts, opts...)
+	}
+}
+
+// LoadShedPolicy defines the load thresholds above which a Group sheds
+// followers, i.e. callers that would join an in-flight call rather than
+// start a new one. A zero threshold is ignored.
+type LoadShedPolicy struct {
+	// MaxInFlight is the number of keys in flight at which followers
+	// are shed.
+	MaxInFlight int
+	// MaxWaiters is the number of waiters across all keys at which
+	// followers are shed.
+	MaxWaiters int
+}
+
+// overloaded reports whether the given load reaches any threshold of p.
+func (p LoadShedPolicy) overloaded(inFlight, waiters int) bool {
+	return (p.MaxInFlight > 0 && inFlight >= p.MaxInFlight) ||
+		(p.MaxWaiters > 0 && waiters >= p.MaxWaiters)
+}
+
+// WithLoadShedding returns a GroupConfigOption that sheds followers with
+// ErrLoadShed while the group is overloaded according to policy. Callers
+// starting a new flight are never shed, preserving capacity for flights
+// that can complete. By default, no load is shed.
+func WithLoadShedding(policy LoadShedPolicy) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.loadShed = policy
+	}
+}
+
+// WithTenantPool returns a TenantConfigOption that executes the flights of
+// all tenants on a shared pool with the given number of worker slots.
+// Flights that find every slot busy are queued and scheduled with weighted
+// fairness across tenants, so a burst from one tenant cannot starve the
+// flights of others. By default, flights execute without a pool.
+func WithTenantPool(workers int) TenantConfigOption {
+	return func(config *TenantConfig) {
+		config.poolWorkers = workers
+	}
+}
+
+// WithTenantWeight returns a TenantConfigOption that assigns every tenant
+// the scheduling weight returned by weightFn for its ID. A tenant with weight
+// 2 receives twice the pool slots of a tenant with weight 1 while both have
+// flights queued. Weights below 1 are treated as 1, which is also the
+// default. The weight only matters in combination with WithTenantPool.
+func WithTenantWeight(weightFn func(id string) int) TenantConfigOption {
+	return func(config *TenantConfig) {
+		config.weightFn = weightFn
+	}
+}
+
+// WithRateLimit returns a GroupConfigOption that limits executions of keys
+// starting with prefix to perSecond on average, with bursts of up to burst
+// executions. Executions beyond the budget fail with ErrRateLimited for
+// every caller of the flight; joining an in-flight call is not limited.
+//
+// Each prefix has an independent budget. A key is limited by the longest
+// matching prefix only; an empty prefix matches every key. Limiting a
+// prefix again replaces its previous limit. When used with
+// WithGroupOptions, the budget is shared by all shards.
+func WithRateLimit(prefix string, perSecond float64, burst int) GroupConfigOption {
+	limiter := &prefixLimiter{
+		prefix:    prefix,
+		perSecond: perSecond,
+		burst:     float64(burst),
+		tokens:    float64(burst),
+	}
+
+	return func(config *GroupConfig) {
+		config.rateLimits = config.rateLimits.with(limiter)
+	}
+}
+
+// WithHedging returns a GroupConfigOption that hedges against slow
+// executions: if an execution has not completed after delay, a second
+// execution of the work function is started, and the first successful
+// result wins. The context of the losing execution is canceled, so work
+// functions of DoContextFunc and DoChanHandle can abort it. By default,
+// executions are not hedged.
+func WithHedging(delay time.Duration) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.hedgeAfter = delay
+	}
+}
+
+// WithCoalesceWindow returns a GroupConfigOption that keeps the result of
+// a completed flight for window, so callers arriving shortly after the
+// flight completed receive its result as shared instead of starting a new
+// execution. Panics are not kept. By default, a completed flight is
+// forgotten immediately.
+func WithCoalesceWindow(window time.Duration) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.coalesceWindow = window
+	}
+}
+
+// WithMaxShareDuration returns a GroupConfigOption that stops new callers
+// from joining a flight once it has been running for longer than d. They
+// start a new execution instead, which new callers join from then on,
+// while the callers of the old flight keep waiting for its result. By
+// default, flights are joined for as long as they run.
+func WithMaxShareDuration(d time.Duration) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.maxShareDuration = d
+	}
+}
+
+// WithRetry returns a GroupConfigOption that retries failed executions
+// according to policy before their error is shared with the callers of the
+// flight, so callers do not each retry on their own. Retries are part of
+// the execution: callers joining meanwhile wait for the final outcome. By
+// default, failed executions are not retried.
+func WithRetry(policy RetryPolicy) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.retry = &policy
+	}
+}
+
+// WithExecutionTimeout returns a GroupConfigOption that gives up on
+// executions running longer than d: the callers of the flight fail with an
+// *ExecutionTimeoutError, which matches ErrExecutionTimeout, and the key is
+// forgotten, so new callers start a fresh execution instead of queueing
+// behind a stuck one. The timed-out work function keeps running until it
+// returns, and its result is discarded; it holds its slots of
+// WithMaxConcurrent, WithWorkerPool and WithCostBudget until then. By
+// default, executions are not timed out.
+func WithExecutionTimeout(d time.Duration) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.executionTimeout = d
+	}
+}
+
+// WithMaxConcurrent returns a GroupConfigOption that caps the number of
+// flights executing at once across all keys at n, so a stampede of distinct
+// keys cannot overwhelm a backend. Executions beyond the cap queue in
+// arrival order on the goroutine of their leader; their callers wait
+// meanwhile. An n of zero or less leaves executions uncapped, which is also
+// the default.
+//
+// When used with WithGroupOptions, the cap applies to all shards together.
+func WithMaxConcurrent(n int) GroupConfigOption {
+	var p *fairPool
+	if n > 0 {
+		p = newFairPool(n)
+	}
+
+	return func(config *GroupConfig) {
+		config.maxConcurrent = p
+	}
+}
+
+// WithWorkerPool returns a GroupConfigOption that executes the work
+// functions of flights on goroutines of their own rather than on the
+// goroutine of the calling leader, at most workers of them at once across
+// all keys. Executions beyond the cap queue in arrival order; their callers
+// wait meanwhile. A panic of a work function is raised in the leader as if
+// it ran on its goroutine. A workers of zero or less leaves work functions
+// on the goroutine of the leader, which is also the default.
+//
+// When used with WithGroupOptions, the cap is shared by all shards.
+func WithWorkerPool(workers int) GroupConfigOption {
+	var p *fairPool
+	if workers > 0 {
+		p = newFairPool(workers)
+	}
+
+	return func(config *GroupConfig) {
+		config.workerPool = p
+	}
+}
+
+// WithCostBudget returns a GroupConfigOption that caps the total cost of
+// concurrently executing flights at budget. Executions that would exceed
+// the remaining budget are queued or rejected with ErrCostBudget according
+// to policy; an execution costing more than budget always fails. The cost
+// of a flight is declared via DoWithCost or derived via WithCostFn. Queued
+// executions of flights started via DoContextFunc stop waiting once every
+// caller has gone away.
+//
+// When used with WithGroupOptions, the budget is shared by all shards.
+func WithCostBudget(budget int64, policy CostPolicy) GroupConfigOption {
+	b := &costBudget{
+		budget: budget,
+		policy: policy,
+	}
+
+	return func(config *GroupConfig) {
+		config.costBudget = b
+	}
+}
+
+// WithCostFn returns a GroupConfigOption that derives the cost of a flight
+// started by Do or DoChan from its key, see WithCostBudget. costFn is
+// called once per execution under a cost budget, by the leader of the
+// flight, and costs below 1 count as 1. By default, every flight costs 1.
+func WithCostFn(costFn func(key string) int64) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.costFn = costFn
+	}
+}
+
+// WithDeadlineAwareJoins returns a GroupConfigOption that makes DoContext
+// reject joins that are not expected to complete before the deadline of the
+// caller's context, returning ErrInsufficientDeadline immediately so the
+// caller can fall back instead of waiting to time out.
+//
+// The expected completion time is an exponential moving average of past
+// execution durations, tracked per key or per pattern as defined by policy,
+// for at most policy.MaxPatterns keys or patterns. By default, joins are
+// never rejected.
+func WithDeadlineAwareJoins(policy DeadlinePolicy) GroupConfigOption {
+	if policy.Smoothing <= 0 || policy.Smoothing > 1 {
+		policy.Smoothing = DefaultDeadlineSmoothing
+	}
+	if policy.MaxPatterns < 1 {
+		policy.MaxPatterns = DefaultDeadlinePatterns
+	}
+
+	return func(config *GroupConfig) {
+		config.deadlineAware = &policy
+	}
+}
+
+// WithPriorityLanes returns a GroupConfigOption that publishes the value of
+// a successful high-priority flight (see DoWithPriority) to the callers of a
+// still running normal-priority flight of the same key, so they do not
+// have to wait for the slower execution. By default, the lanes are
+// independent.
+//
+// To allow this, normal-priority executions run on a separate goroutine.
+func WithPriorityLanes() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.priorityLanes = true
+	}
+}
+
+// WithLongKeyHashing returns a GroupConfigOption that tracks flights of keys
+// longer than threshold bytes by a fixed-size 128-bit digest instead of the
+// key itself. This bounds the memory held by the group when keys are full
+// URLs or serialized query plans.
+//
+// Only string keys, including keys of defined string types configured via
+// WithStringKeys, and keys implementing Keyer, measured and hashed by their
+// SingleflightKey, are hashed; keys of other types are tracked as they are.
+// Digests are derived with SHA-256 truncated to 128 bits. Options that
+// operate on keys, such as WithRateLimit, still see the original key.
+//
+// The original key of a flight tracked by digest is not kept, unless
+// configured via WithLongKeyRetention, so such flights are skipped by Keys,
+// ForgetMatching and ForgetPrefix, are not reported to Hooks.OnForget, and
+// their EventForgotten and coalesced EventWaiterJoined events carry the
+// zero key. By default, keys are tracked as they are.
+func WithLongKeyHashing(threshold int) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.longKeyThreshold = threshold
+	}
+}
+
+// WithLongKeyRetention returns a GroupConfigOption that keeps the original
+// key of flights tracked by digest due to WithLongKeyHashing for as long as
+// the flight is tracked, so they are listed, matched and reported by their
+// key. This gives up the memory bound of WithLongKeyHashing in exchange. By
+// default, the original key is dropped.
+func WithLongKeyRetention() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.longKeyRetention = true
+	}
+}
+
+// WithKeyNormalizer returns a GroupConfigOption that deduplicates string
+// keys, including keys of defined string types configured via
+// WithStringKeys, by the result of normalize applied to them, so that keys
+// with equal normalized forms share a flight. Keys of other types are left
+// unchanged. NormalizeNFC and NormalizeNFKC are provided for user-supplied
+// strings. Options that operate on keys, such as WithRateLimit, still see
+// the original key.
+//
+// Sharded groups route normalized keys to shards by their normalized form,
+// unless keys are routed via WithKeyHash or WithShardPicker, which bypass
+// normalization: their hash or pick must then map keys with equal
+// normalized forms alike, or such keys land on different shards and do not
+// share a flight.
+func WithKeyNormalizer(normalize func(key string) string) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.keyNormalizer = normalize
+	}
+}
+
+// WithStringKeys returns a GroupConfigOption that treats keys of the
+// defined string type K as the strings they hold, so that WithKeyNormalizer
+// and WithLongKeyHashing apply to them like to string keys. NewGroup and
+// UpdateConfig panic if K is not the key type of the group. By default, only
+// keys of type string are treated as strings.
+func WithStringKeys[K ~string]() GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.stringKeys = func(key K) string { return string(key) }
+	}
+}
+
+// WithMaxKeyLen returns a GroupConfigOption that rejects keys whose textual
+// form is longer than n bytes with a *KeyTooLongError, which matches
+// ErrKeyTooLong. This catches keys built from unbounded input, such as an
+// entire request body, before they are held by the group. By default, key
+// lengths are not limited.
+func WithMaxKeyLen(n int) GroupConfigOption {
+	return func(config *GroupConfig) {
+		config.maxKeyLen = n
+	}
+}
+
+// WithMaxResultSize returns a GroupConfigOption that bounds the size of the
+// values produced by flights at maxBytes, as measured by sizer. Successful
+// results exceeding the bound are rejected or flagged with a
+// *ResultTooLargeError according to policy, so a single huge value is not
+// retained and shared by every waiter unnoticed. NewGroup and UpdateConfig
+// panic if V is not the value type of the group. By default, result sizes
+// are not bounded.
+func WithMaxResultSize[V any](
+	maxBytes int64, sizer func(v V) int64, policy ResultSizePolicy,
+) GroupConfigOption {
+	l := &resultLimit{sizer: sizer, max: maxBytes, policy: policy}
+
+	return func(config *GroupConfig) {
+		config.resultLimit = l
+	}
+}
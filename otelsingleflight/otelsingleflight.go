@@ -0,0 +1,241 @@
+// Package otelsingleflight instruments singleflight groups with
+// OpenTelemetry metrics and traces.
+package otelsingleflight
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// ScopeName is the instrumentation scope name of the meter and tracer.
+const ScopeName = "github.com/iwpnd/singleflightx/otelsingleflight"
+
+// Attribute keys recorded with every measurement.
+const (
+	// GroupKey is the name of the instrumented group.
+	GroupKey = attribute.Key("singleflight.group")
+	// OutcomeKey is the outcome of a call, see the Outcome constants.
+	OutcomeKey = attribute.Key("singleflight.outcome")
+)
+
+// Outcomes of a call, recorded as OutcomeKey.
+const (
+	// OutcomeExecuted is the outcome of a call that executed fn itself,
+	// even if its result was shared with other callers.
+	OutcomeExecuted = "executed"
+	// OutcomeShared is the outcome of a call served by a flight started by
+	// another caller.
+	OutcomeShared = "shared"
+	// OutcomeError is the outcome of a call that failed.
+	OutcomeError = "error"
+)
+
+// Group decorates a Singleflighter, recording the following metrics for
+// every call, attributed with the name of the group and the outcome of the
+// call:
+//
+//   - singleflight.calls: the number of calls
+//   - singleflight.shared: the number of calls served by a shared flight
+//   - singleflight.errors: the number of failed calls
+//   - singleflight.duration: the time callers waited for a result, in
+//     seconds
+//
+// Every call is traced as well, see DoContext. Group implements
+// Singleflighter.
+type Group[K comparable, V any] struct {
+	group singleflight.Singleflighter[K, V]
+	name  attribute.KeyValue
+
+	calls    metric.Int64Counter
+	shared   metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+
+	tracer  trace.Tracer
+	mu      sync.Mutex
+	flights map[K]*flight
+}
+
+// config configures a Group.
+type config struct {
+	meterProvider  metric.MeterProvider
+	tracerProvider trace.TracerProvider
+}
+
+// Option configures a Group.
+type Option = func(*config)
+
+// WithMeterProvider returns an Option that sets the MeterProvider the
+// metrics are recorded with. It defaults to the global MeterProvider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(config *config) {
+		config.meterProvider = provider
+	}
+}
+
+// WithTracerProvider returns an Option that sets the TracerProvider the
+// spans are recorded with. It defaults to the global TracerProvider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(config *config) {
+		config.tracerProvider = provider
+	}
+}
+
+// New returns a Group recording metrics and traces for the calls of group,
+// named name, configured by opts. If group is nil, a new singleflight.Group
+// is used. If name is empty, the name of group is used, if it has one (see
+// singleflight.WithName).
+func New[K comparable, V any](
+	group singleflight.Singleflighter[K, V], name string, opts ...Option,
+) (*Group[K, V], error) {
+	cfg := config{
+		meterProvider:  otel.GetMeterProvider(),
+		tracerProvider: otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if group == nil {
+		group = &singleflight.Group[K, V]{}
+	}
+	if named, ok := group.(interface{ Name() string }); ok && name == "" {
+		name = named.Name()
+	}
+
+	g := &Group[K, V]{
+		group:   group,
+		name:    GroupKey.String(name),
+		tracer:  cfg.tracerProvider.Tracer(ScopeName),
+		flights: make(map[K]*flight),
+	}
+	meter := cfg.meterProvider.Meter(ScopeName)
+
+	var err error
+	if g.calls, err = meter.Int64Counter("singleflight.calls",
+		metric.WithDescription("Calls of the group."),
+		metric.WithUnit("{call}"),
+	); err != nil {
+		return nil, err
+	}
+	if g.shared, err = meter.Int64Counter("singleflight.shared",
+		metric.WithDescription("Calls served by a flight started by another caller."),
+		metric.WithUnit("{call}"),
+	); err != nil {
+		return nil, err
+	}
+	if g.errors, err = meter.Int64Counter("singleflight.errors",
+		metric.WithDescription("Calls that failed."),
+		metric.WithUnit("{call}"),
+	); err != nil {
+		return nil, err
+	}
+	if g.duration, err = meter.Float64Histogram("singleflight.duration",
+		metric.WithDescription("Time callers waited for a result."),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Do calls Do of the underlying Singleflighter and records its outcome.
+// The call is traced as a root span, see DoContext.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	start := time.Now()
+	ctx, span := g.startCall(context.Background(), key)
+
+	var executed bool
+	v, err, shared = g.group.Do(key, g.traced(ctx, key, fn, &executed))
+	g.endCall(span, key, start, err, shared && !executed)
+
+	return v, err, shared
+}
+
+// DoChan calls DoChan of the underlying Singleflighter and records the
+// outcome once the result is available. The call is traced as a root span,
+// see DoContext.
+func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan singleflight.Result[V] {
+	return g.doChan(context.Background(), key, fn)
+}
+
+// DoContext is like Do, but stops waiting when ctx is done, returning
+// ctx.Err() while the execution continues for the other callers. The
+// outcome is recorded once the result is available either way.
+//
+// The call is traced as a span "singleflight.call", a child of the span of
+// ctx, recording the time waited for the result. The execution of fn is
+// traced as a span "singleflight.execute", a child of the call span of the
+// caller that executed it. Call spans of callers served by the flight of
+// another caller carry a link to its execution span, so deduplicated calls
+// remain visible in traces.
+func (g *Group[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	select {
+	case res := <-g.doChan(ctx, key, fn):
+		return res.Val, res.Err, res.Shared
+	case <-ctx.Done():
+		return v, ctx.Err(), false
+	}
+}
+
+// doChan implements DoChan and DoContext, tracing the call as a child of
+// the span of ctx.
+func (g *Group[K, V]) doChan(
+	ctx context.Context, key K, fn func() (V, error),
+) <-chan singleflight.Result[V] {
+	start := time.Now()
+	ctx, span := g.startCall(ctx, key)
+
+	var executed bool
+	base := g.group.DoChan(key, g.traced(ctx, key, fn, &executed))
+	ch := make(chan singleflight.Result[V], 1)
+
+	go func() {
+		res := <-base
+		g.endCall(span, key, start, res.Err, res.Shared && !executed)
+		ch <- res
+	}()
+
+	return ch
+}
+
+// Forget forgets the flight of key on the underlying Singleflighter and
+// reports whether there was one.
+func (g *Group[K, V]) Forget(key K) bool {
+	return g.group.Forget(key)
+}
+
+// record records a call that waited for wait with the outcome err and
+// shared.
+func (g *Group[K, V]) record(wait time.Duration, err error, shared bool) {
+	outcome := OutcomeExecuted
+	switch {
+	case err != nil:
+		outcome = OutcomeError
+	case shared:
+		outcome = OutcomeShared
+	}
+
+	ctx := context.Background()
+	attrs := metric.WithAttributes(g.name, OutcomeKey.String(outcome))
+
+	g.calls.Add(ctx, 1, attrs)
+	if shared {
+		g.shared.Add(ctx, 1, attrs)
+	}
+	if err != nil {
+		g.errors.Add(ctx, 1, attrs)
+	}
+	g.duration.Record(ctx, wait.Seconds(), attrs)
+}
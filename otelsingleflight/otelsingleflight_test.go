@@ -0,0 +1,58 @@
+package otelsingleflight
+
+import (
+	"errors"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+var _ singleflight.Singleflighter[string, int] = (*Group[string, int])(nil)
+
+func TestGroupMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	g, err := New[string, int](nil, "users",
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Do("user:1", func() (int, error) { return 1, nil })
+	<-g.DoChan("user:2", func() (int, error) { return 0, errors.New("failed") })
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			counts[m.Name] = make(map[string]int64)
+			for _, dp := range sum.DataPoints {
+				if v, _ := dp.Attributes.Value(GroupKey); v.AsString() != "users" {
+					t.Fatalf("%s attributes=%v, want group users", m.Name, dp.Attributes)
+				}
+				outcome, _ := dp.Attributes.Value(OutcomeKey)
+				counts[m.Name][outcome.AsString()] += dp.Value
+			}
+		}
+	}
+
+	if c := counts["singleflight.calls"]; c[OutcomeExecuted] != 1 || c[OutcomeError] != 1 {
+		t.Fatalf("calls=%v, want 1 executed, 1 error", c)
+	}
+	if c := counts["singleflight.errors"]; c[OutcomeError] != 1 {
+		t.Fatalf("errors=%v, want 1", c)
+	}
+	if c := counts["singleflight.shared"]; len(c) != 0 {
+		t.Fatalf("shared=%v, want none", c)
+	}
+}
@@ -0,0 +1,94 @@
+package otelsingleflight
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys recorded with call spans.
+const (
+	// SharedKey reports whether the caller was served by the flight of
+	// another caller instead of executing fn itself.
+	SharedKey = attribute.Key("singleflight.shared")
+	// WaitKey is the time the caller waited for the result, in seconds.
+	WaitKey = attribute.Key("singleflight.wait")
+)
+
+// flight tracks the execution span of the latest flight of a key while
+// callers of the key are waiting, so that callers served by it can link to
+// it.
+type flight struct {
+	callers int
+	exec    trace.SpanContext
+}
+
+// startCall starts the span of a call of key as a child of the span of ctx
+// and registers the caller as waiting for key.
+func (g *Group[K, V]) startCall(ctx context.Context, key K) (context.Context, trace.Span) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		f = &flight{}
+		g.flights[key] = f
+	}
+	f.callers++
+	g.mu.Unlock()
+
+	return g.tracer.Start(ctx, "singleflight.call", trace.WithAttributes(g.name))
+}
+
+// traced returns fn tracing its execution as a child of the span of ctx,
+// the context of the call that executes it, and reporting the execution via
+// executed.
+func (g *Group[K, V]) traced(
+	ctx context.Context, key K, fn func() (V, error), executed *bool,
+) func() (V, error) {
+	return func() (V, error) {
+		*executed = true
+
+		_, span := g.tracer.Start(ctx, "singleflight.execute", trace.WithAttributes(g.name))
+		defer span.End()
+
+		g.mu.Lock()
+		g.flights[key].exec = span.SpanContext()
+		g.mu.Unlock()
+
+		v, err := fn()
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return v, err
+	}
+}
+
+// endCall ends the span of a call of key started at start with the outcome
+// err, linking it to the execution span of the flight it was served by if
+// shared, and records the outcome.
+func (g *Group[K, V]) endCall(span trace.Span, key K, start time.Time, err error, shared bool) {
+	g.mu.Lock()
+	f := g.flights[key]
+	exec := f.exec
+	if f.callers--; f.callers == 0 {
+		delete(g.flights, key)
+	}
+	g.mu.Unlock()
+
+	wait := time.Since(start)
+	if shared && exec.IsValid() {
+		span.AddLink(trace.Link{SpanContext: exec})
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(SharedKey.Bool(shared), WaitKey.Float64(wait.Seconds()))
+	span.End()
+
+	g.record(wait, err, shared)
+}
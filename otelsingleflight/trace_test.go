@@ -0,0 +1,67 @@
+package otelsingleflight
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGroupTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	g, err := New[string, int](nil, "users",
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return 1, nil
+	}
+
+	const callers = 3
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.DoContext(t.Context(), "user:1", fn)
+		}()
+	}
+	time.Sleep(30 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	var exec sdktrace.ReadOnlySpan
+	var calls []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "singleflight.execute":
+			exec = span
+		case "singleflight.call":
+			calls = append(calls, span)
+		}
+	}
+	if exec == nil || len(calls) != callers {
+		t.Fatalf("spans=%v, want 1 execution and %d calls", recorder.Ended(), callers)
+	}
+
+	leaders, links := 0, 0
+	for _, call := range calls {
+		if exec.Parent().SpanID() == call.SpanContext().SpanID() {
+			leaders++
+		}
+		for _, link := range call.Links() {
+			if link.SpanContext.Equal(exec.SpanContext()) {
+				links++
+			}
+		}
+	}
+	if leaders != 1 || links != callers-1 {
+		t.Fatalf("leaders=%d links=%d, want 1 leader and %d waiters linked", leaders, links, callers-1)
+	}
+}
@@ -0,0 +1,113 @@
+package singleflight
+
+import "sync"
+
+// fairPool bounds the number of concurrently executing tasks.
+//
+// Tasks that find every slot busy are queued per lane. When a slot frees up,
+// it is handed to the head of the lane picked by smooth weighted round-robin,
+// so a burst of tasks on one lane cannot starve the other lanes; each lane
+// receives slots in proportion to its weight.
+type fairPool struct {
+	mu      sync.Mutex
+	lanes   map[string]*fairLane
+	slots   int
+	running int
+}
+
+// fairLane is the queue of tasks of a single lane waiting for a slot.
+type fairLane struct {
+	waiting []chan struct{}
+	weight  int
+	current int
+}
+
+// newFairPool returns a fairPool running at most slots tasks at once.
+func newFairPool(slots int) *fairPool {
+	return &fairPool{
+		lanes: make(map[string]*fairLane),
+		slots: slots,
+	}
+}
+
+// run executes task on the calling goroutine once a slot is available,
+// queueing it on lane with the given weight while all slots are busy.
+func (p *fairPool) run(lane string, weight int, task func()) {
+	p.acquire(lane, weight)
+	defer p.release()
+
+	task()
+}
+
+// acquire blocks until the caller holds a slot.
+func (p *fairPool) acquire(lane string, weight int) {
+	p.mu.Lock()
+
+	if p.running < p.slots {
+		p.running++
+		p.mu.Unlock()
+
+		return
+	}
+
+	l, ok := p.lanes[lane]
+	if !ok {
+		l = &fairLane{weight: max(weight, 1)}
+		p.lanes[lane] = l
+	}
+
+	ready := make(chan struct{})
+	l.waiting = append(l.waiting, ready)
+	p.mu.Unlock()
+
+	<-ready
+}
+
+// release hands the slot held by the caller to the next queued task, or
+// frees it if nothing is queued.
+func (p *fairPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ready := p.next(); ready != nil {
+		close(ready)
+		return
+	}
+
+	p.running--
+}
+
+// next dequeues the task that is to receive the next free slot, or returns
+// nil if no task is queued. The caller must hold p.mu.
+func (p *fairPool) next() chan struct{} {
+	var (
+		best  *fairLane
+		name  string
+		total int
+	)
+
+	for lane, l := range p.lanes {
+		l.current += l.weight
+		total += l.weight
+
+		if best == nil || l.current > best.current {
+			best, name = l, lane
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	best.current -= total
+
+	ready := best.waiting[0]
+	best.waiting[0] = nil
+	best.waiting = best.waiting[1:]
+
+	if len(best.waiting) == 0 {
+		delete(p.lanes, name)
+	}
+
+	return ready
+}
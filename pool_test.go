@@ -0,0 +1,107 @@
+package singleflight
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// queued returns the number of tasks queued on lane.
+func (p *fairPool) queued(lane string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if l, ok := p.lanes[lane]; ok {
+		return len(l.waiting)
+	}
+
+	return 0
+}
+
+func TestFairPoolWeightedFairness(t *testing.T) {
+	p := newFairPool(1)
+
+	// occupy the only slot so every following task is queued.
+	p.acquire("blocker", 1)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+
+	enqueue := func(lane string, weight, n int) {
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.run(lane, weight, func() {
+					mu.Lock()
+					order = append(order, lane)
+					mu.Unlock()
+				})
+			}()
+		}
+		for p.queued(lane) < n {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// a burst on lane "a" queued ahead of lane "b".
+	enqueue("a", 3, 8)
+	enqueue("b", 1, 4)
+
+	p.release()
+	wg.Wait()
+
+	// every cycle of total weight 4 grants "a" three and "b" one slot.
+	var a, b int
+	for _, lane := range order[:8] {
+		switch lane {
+		case "a":
+			a++
+		case "b":
+			b++
+		}
+	}
+	if a != 6 || b != 2 {
+		t.Fatalf("first 8 slots a=%d b=%d, want a=6 b=2 (order %v)", a, b, order)
+	}
+}
+
+func TestFairPoolBoundsConcurrency(t *testing.T) {
+	const slots = 2
+
+	p := newFairPool(slots)
+
+	var (
+		mu         sync.Mutex
+		running    int
+		maxRunning int
+		wg         sync.WaitGroup
+	)
+
+	wg.Add(numCallers * 2)
+	for i := range numCallers * 2 {
+		go func() {
+			defer wg.Done()
+			p.run(string(rune('a'+i%2)), 1, func() {
+				mu.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+
+				time.Sleep(sleepJoin / 3)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+			})
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning > slots {
+		t.Fatalf("max running = %d, want <= %d", maxRunning, slots)
+	}
+}
@@ -0,0 +1,83 @@
+package singleflight
+
+// Priority is the lane a call is deduplicated in.
+type Priority int
+
+const (
+	// PriorityNormal is the lane used by Do and DoChan.
+	PriorityNormal Priority = iota
+	// PriorityHigh is the lane of callers that must not wait behind a slow
+	// normal-priority flight. High-priority callers only share flights with
+	// each other.
+	PriorityHigh
+)
+
+// DoWithPriority executes and deduplicates fn for key within the lane of
+// the given priority.
+//
+// PriorityNormal behaves exactly like Do. PriorityHigh bypasses any
+// normal-priority flight for key and starts or joins a separate
+// high-priority flight, so interactive callers are never stuck behind a
+// slow batch call. If the group enables WithPriorityLanes and the
+// high-priority flight succeeds before the normal-priority flight, its
+// value is also published to the callers of the normal-priority flight.
+func (g *Group[K, V]) DoWithPriority(
+	key K, priority Priority, fn func() (V, error),
+) (v V, err error, shared bool) {
+	if priority < PriorityHigh {
+		return g.Do(key, fn)
+	}
+
+	return g.do(key, lanePriority, costDerived, func() (V, error) {
+		v, err := fn()
+		if err == nil && g.settings().priorityLanes {
+			g.publish(key, v)
+		}
+
+		return v, err
+	})
+}
+
+// DoWithPriority is the sharded variant of Group.DoWithPriority.
+//
+// Both lanes of a key live on the shard determined by key.
+func (sg *ShardedGroup[K, V]) DoWithPriority(
+	key K, priority Priority, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return sg.shards[sg.shardIndex(key)].DoWithPriority(key, priority, fn)
+}
+
+// publish hands v to the normal-priority flight of key, if one is running.
+func (g *Group[K, V]) publish(key K, v V) {
+	g.mu.Lock()
+	c, ok := g.m[g.flightKey(key, laneNormal)]
+	g.mu.Unlock()
+
+	if !ok || c.published == nil {
+		return
+	}
+
+	select {
+	case c.published <- v:
+	default:
+	}
+}
+
+// race runs fn for a normal-priority flight and returns its result, or the
+// value published by a high-priority flight of the same key if that arrives
+// first. In the latter case fn keeps running, but its result is discarded.
+//
+// A panic or runtime.Goexit in fn is recovered and returned as error, so
+// that it is handled like one of a flight executing on the calling
+// goroutine.
+func race[V any](published <-chan V, fn func() (V, error)) (V, error) {
+	done := make(chan Result[V], 1)
+	go capture(done, fn)
+
+	select {
+	case res := <-done:
+		return res.Val, res.Err
+	case v := <-published:
+		return v, nil
+	}
+}
@@ -0,0 +1,82 @@
+package singleflight
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type priorityDoer[T ~string, V any] interface {
+	DoWithPriority(T, Priority, func() (V, error)) (V, error, bool)
+}
+
+func TestGroupDoWithPriority(t *testing.T) {
+	g := NewGroup[string, int](WithPriorityLanes())
+	highPriorityBypasses(t, g, keyA)
+}
+
+func TestShardedGroupDoWithPriority(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithPriorityLanes()))
+	highPriorityBypasses(t, sg, keyB)
+}
+
+func highPriorityBypasses[T ~string](t *testing.T, d priorityDoer[T, int], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	var calls int32
+	batch := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 1, nil
+	}
+	interactive := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(sleepJoin)
+		return 2, nil
+	}
+
+	// a slow normal-priority flight with waiters
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	vals := make([]int, numCallers)
+	for i := range numCallers {
+		go func() {
+			defer wg.Done()
+			vals[i], _, _ = d.DoWithPriority(key, PriorityNormal, batch)
+		}()
+	}
+	time.Sleep(sleepJoin)
+
+	// high-priority callers share their own flight
+	var hwg sync.WaitGroup
+	hwg.Add(numCallers)
+	hvals := make([]int, numCallers)
+	for i := range numCallers {
+		go func() {
+			defer hwg.Done()
+			hvals[i], _, _ = d.DoWithPriority(key, PriorityHigh, interactive)
+		}()
+	}
+	hwg.Wait()
+
+	// the normal-priority waiters receive the published value
+	wg.Wait()
+
+	for i := range numCallers {
+		if hvals[i] != 2 || vals[i] != 2 {
+			t.Fatalf("high=%d normal=%d, want 2 and 2", hvals[i], vals[i])
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+}
+
+func TestGroupPriorityLanesGoexit(t *testing.T) {
+	g := NewGroup[string, int](WithPriorityLanes())
+	doGoexits(t, g, keyA)
+}
@@ -0,0 +1,52 @@
+package singleflight
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+)
+
+// Profiler label keys set on executing goroutines, see WithProfilerLabels.
+const (
+	// ProfilerLabelGroup is the profiler label carrying the name of the
+	// group, if it has one.
+	ProfilerLabelGroup = "singleflight.group"
+	// ProfilerLabelKey is the profiler label carrying the key of the flight.
+	ProfilerLabelKey = "singleflight.key"
+)
+
+// maxProfilerKeyLen is the length in bytes beyond which keys are truncated
+// in profiler labels, to keep profiles small.
+const maxProfilerKeyLen = 64
+
+// profiled runs exec with the task t on a goroutine labeled for profiles
+// with the name of g and the key of the flight fk, see WithProfilerLabels.
+// The context of a task with context carries the labels as well.
+func (g *Group[K, V]) profiled(fk flightKey[K], t task[V], exec func(task[V])) {
+	ctx := t.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	labels := []string{ProfilerLabelKey, profilerKey(fk.String())}
+	if name := g.settings().name; name != "" {
+		labels = append(labels, ProfilerLabelGroup, name)
+	}
+
+	pprof.Do(ctx, pprof.Labels(labels...), func(ctx context.Context) {
+		if t.work != nil {
+			t.ctx = ctx
+		}
+		exec(t)
+	})
+}
+
+// profilerKey returns the textual form k of a key, truncated to
+// maxProfilerKeyLen bytes.
+func profilerKey(k string) string {
+	if len(k) <= maxProfilerKeyLen {
+		return k
+	}
+
+	return strings.ToValidUTF8(k[:maxProfilerKeyLen], "") + "…"
+}
@@ -0,0 +1,42 @@
+package singleflight
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+func TestGroupProfilerLabels(t *testing.T) {
+	g := NewGroup[string, int](WithProfilerLabels(), WithName("users"))
+
+	var key, group string
+	_, err, _ := g.DoContextFunc(context.Background(), keyA, func(ctx context.Context) (int, error) {
+		key, _ = pprof.Label(ctx, ProfilerLabelKey)
+		group, _ = pprof.Label(ctx, ProfilerLabelGroup)
+		return wantValueInt, nil
+	})
+	if err != nil || key != keyA || group != "users" {
+		t.Fatalf("err=%v key=%q group=%q, want %q in users", err, key, group, keyA)
+	}
+
+	long := strings.Repeat("x", 2*maxProfilerKeyLen)
+	g.DoContextFunc(context.Background(), long, func(ctx context.Context) (int, error) {
+		key, _ = pprof.Label(ctx, ProfilerLabelKey)
+		return wantValueInt, nil
+	})
+	if key != long[:maxProfilerKeyLen]+"…" {
+		t.Fatalf("key=%q, want truncated to %d bytes", key, maxProfilerKeyLen)
+	}
+}
+
+func TestGroupProfilerLabelsDisabled(t *testing.T) {
+	var g Group[string, int]
+
+	g.DoContextFunc(context.Background(), keyA, func(ctx context.Context) (int, error) {
+		if key, ok := pprof.Label(ctx, ProfilerLabelKey); ok {
+			t.Errorf("key=%q, want no label by default", key)
+		}
+		return wantValueInt, nil
+	})
+}
@@ -0,0 +1,168 @@
+package singleflight
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Progress is a progress update published by the execution of a flight,
+// see ReportProgress.
+type Progress struct {
+	// Percent is the completion of the flight in percent, if known.
+	Percent float64
+	// Detail is an optional typed event describing the progress, e.g. the
+	// phase the execution is in.
+	Detail any
+	// Time is the time the progress was reported.
+	Time time.Time
+}
+
+// progressKey is the context key of the reporter of the progress of a
+// flight.
+type progressKey struct{}
+
+// ReportProgress publishes the progress p of the flight fn is executing
+// for, where ctx is the context passed to fn by DoContextFunc or
+// DoChanHandle, so callers waiting for a long load can tell it is still
+// making headway (see Group.Progress and Group.WatchProgress). Time
+// defaults to the current time. ReportProgress reports whether ctx belongs
+// to a flight.
+func ReportProgress(ctx context.Context, p Progress) bool {
+	report, ok := ctx.Value(progressKey{}).(func(Progress))
+	if !ok {
+		return false
+	}
+
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	report(p)
+
+	return true
+}
+
+// flightProgress is the progress of a flight and its watchers.
+type flightProgress struct {
+	latest   Progress
+	reported bool
+	done     bool
+	watchers []chan Progress
+}
+
+// publish records p and hands it to the watchers, replacing an update they
+// have not received yet. The caller must hold g.mu.
+func (fp *flightProgress) publish(p Progress) {
+	if fp.done {
+		return
+	}
+
+	fp.latest, fp.reported = p, true
+	for _, ch := range fp.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- p
+	}
+}
+
+// end closes the channels of the watchers once the flight completed. The
+// caller must hold g.mu.
+func (fp *flightProgress) end() {
+	if fp == nil {
+		return
+	}
+
+	fp.done = true
+	for _, ch := range fp.watchers {
+		close(ch)
+	}
+	fp.watchers = nil
+}
+
+// progressContext returns ctx carrying the reporter of the progress of the
+// flight c.
+func (g *Group[K, V]) progressContext(ctx context.Context, c *call[V]) context.Context {
+	return context.WithValue(ctx, progressKey{}, func(p Progress) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if c.progress == nil {
+			c.progress = &flightProgress{}
+		}
+		c.progress.publish(p)
+	})
+}
+
+// Progress returns the latest progress reported by the flight of key in
+// progress, if any, see ReportProgress.
+func (g *Group[K, V]) Progress(key K) (p Progress, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, ok := g.m[g.flightKey(key, laneNormal)]
+	if !ok || c.progress == nil || !c.progress.reported {
+		return p, false
+	}
+
+	return c.progress.latest, true
+}
+
+// WatchProgress observes the progress reported by the flight of key in
+// progress, see ReportProgress.
+//
+// The returned channel receives the latest progress, if any, and every
+// update reported afterwards until the flight completes or cancel is
+// called, after which it is closed. It is closed right away if no flight
+// of key is in progress. An update the watcher has not received yet is
+// replaced by the next one, so slow watchers never hold up the execution.
+func (g *Group[K, V]) WatchProgress(key K) (ch <-chan Progress, cancel func()) {
+	watcher := make(chan Progress, 1)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, ok := g.m[g.flightKey(key, laneNormal)]
+	if !ok {
+		close(watcher)
+		return watcher, func() {}
+	}
+
+	if c.progress == nil {
+		c.progress = &flightProgress{}
+	}
+	fp := c.progress
+	if fp.reported {
+		watcher <- fp.latest
+	}
+	fp.watchers = append(fp.watchers, watcher)
+
+	var once sync.Once
+
+	return watcher, func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+
+			if fp.done {
+				return
+			}
+			fp.watchers = slices.DeleteFunc(fp.watchers, func(other chan Progress) bool {
+				return other == watcher
+			})
+			close(watcher)
+		})
+	}
+}
+
+// Progress is the sharded variant of Group.Progress.
+func (sg *ShardedGroup[K, V]) Progress(key K) (p Progress, ok bool) {
+	return sg.shards[sg.shardIndex(key)].Progress(key)
+}
+
+// WatchProgress is the sharded variant of Group.WatchProgress.
+func (sg *ShardedGroup[K, V]) WatchProgress(key K) (ch <-chan Progress, cancel func()) {
+	return sg.shards[sg.shardIndex(key)].WatchProgress(key)
+}
@@ -0,0 +1,85 @@
+package singleflight
+
+import (
+	"context"
+	"testing"
+)
+
+// progressGroup is a group reporting the progress of its flights.
+type progressGroup interface {
+	DoContextFunc(ctx context.Context, key string, fn func(context.Context) (int, error)) (int, error, bool)
+	Progress(key string) (Progress, bool)
+	WatchProgress(key string) (<-chan Progress, func())
+}
+
+func TestGroupProgress(t *testing.T) {
+	var g Group[string, int]
+	progressReported(t, &g, keyA)
+}
+
+func TestShardedGroupProgress(t *testing.T) {
+	progressReported(t, NewShardedGroup[string, int](), keyA)
+}
+
+func progressReported(t *testing.T, g progressGroup, key string) {
+	t.Helper()
+
+	if _, ok := g.Progress(key); ok {
+		t.Fatal("progress reported without flight")
+	}
+	if ch, _ := g.WatchProgress(key); !isClosed(ch) {
+		t.Fatal("watch channel open without flight")
+	}
+
+	started := make(chan struct{})
+	step := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.DoContextFunc(context.Background(), key, func(ctx context.Context) (int, error) {
+			if !ReportProgress(ctx, Progress{Percent: 10, Detail: "fetching"}) {
+				t.Error("ReportProgress did not find flight")
+			}
+			close(started)
+			<-step
+			ReportProgress(ctx, Progress{Percent: 60})
+			<-step
+			return wantValueInt, nil
+		})
+	}()
+
+	<-started
+	p, ok := g.Progress(key)
+	if !ok || p.Percent != 10 || p.Detail != "fetching" || p.Time.IsZero() {
+		t.Fatalf("progress=%+v, %t, want 10%% fetching", p, ok)
+	}
+
+	ch, cancel := g.WatchProgress(key)
+	defer cancel()
+	if p := <-ch; p.Percent != 10 {
+		t.Fatalf("watched progress=%v, want latest 10%%", p.Percent)
+	}
+
+	step <- struct{}{}
+	if p := <-ch; p.Percent != 60 {
+		t.Fatalf("watched progress=%v, want 60%%", p.Percent)
+	}
+
+	close(step)
+	<-done
+	if _, ok := <-ch; ok {
+		t.Fatal("watch channel open after flight completed")
+	}
+	if ReportProgress(context.Background(), Progress{}) {
+		t.Fatal("ReportProgress found flight outside of one")
+	}
+}
+
+func isClosed(ch <-chan Progress) bool {
+	select {
+	case _, ok := <-ch:
+		return !ok
+	default:
+		return false
+	}
+}
@@ -0,0 +1,72 @@
+package singleflight
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// prefixLimiter is a token bucket limiting the executions of keys that
+// start with prefix.
+type prefixLimiter struct {
+	mu   sync.Mutex
+	last time.Time
+
+	prefix    string
+	perSecond float64
+	burst     float64
+	tokens    float64
+}
+
+// rateLimits is the set of prefix limiters configured for a group.
+type rateLimits []*prefixLimiter
+
+// with returns a copy of rl with l replacing the limiter of its prefix, if
+// any.
+func (rl rateLimits) with(l *prefixLimiter) rateLimits {
+	next := make(rateLimits, 0, len(rl)+1)
+	for _, other := range rl {
+		if other.prefix != l.prefix {
+			next = append(next, other)
+		}
+	}
+
+	return append(next, l)
+}
+
+// allow consumes a token from the limiter with the longest prefix matching
+// key and returns ErrRateLimited if that limiter has no token left.
+func (rl rateLimits) allow(key string) error {
+	var match *prefixLimiter
+	for _, l := range rl {
+		if strings.HasPrefix(key, l.prefix) && (match == nil || len(l.prefix) > len(match.prefix)) {
+			match = l
+		}
+	}
+
+	if match == nil || match.take(time.Now()) {
+		return nil
+	}
+
+	return ErrRateLimited
+}
+
+// take refills the bucket for the time elapsed since the last call and
+// consumes a token if one is available.
+func (l *prefixLimiter) take(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Seconds()
+		l.tokens = min(l.burst, l.tokens+elapsed*l.perSecond)
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+
+	return true
+}
@@ -0,0 +1,59 @@
+package singleflight
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPrefixLimiterTake(t *testing.T) {
+	l := &prefixLimiter{perSecond: 2, burst: 2, tokens: 2}
+	now := time.Now()
+
+	if !l.take(now) || !l.take(now) {
+		t.Fatal("expected burst of 2 to be allowed")
+	}
+	if l.take(now) {
+		t.Fatal("expected bucket to be exhausted")
+	}
+	if !l.take(now.Add(500 * time.Millisecond)) {
+		t.Fatal("expected a token to be refilled after 500ms at 2/s")
+	}
+	if l.take(now.Add(500 * time.Millisecond)) {
+		t.Fatal("expected bucket to be exhausted again")
+	}
+}
+
+func TestGroupRateLimit(t *testing.T) {
+	g := NewGroup[string, int](
+		WithRateLimit("search:", 0.001, 2),
+		WithRateLimit("search:slow:", 0.001, 1),
+	)
+	rateLimitPerPrefix(t, g)
+}
+
+func TestShardedGroupRateLimit(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(
+		WithRateLimit("search:", 0.001, 2),
+		WithRateLimit("search:slow:", 0.001, 1),
+	))
+	rateLimitPerPrefix(t, sg)
+}
+
+func rateLimitPerPrefix(t *testing.T, d doer[string, int]) {
+	t.Helper()
+
+	fn := func() (int, error) { return wantValueInt, nil }
+
+	for _, key := range []string{"search:a", "search:b", "search:slow:a", "users:a", "users:b"} {
+		if _, err, _ := d.Do(key, fn); err != nil {
+			t.Fatalf("Do(%q) err=%v, want nil", key, err)
+		}
+	}
+
+	for _, key := range []string{"search:c", "search:slow:b"} {
+		if _, err, _ := d.Do(key, fn); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Do(%q) err=%v, want %v", key, err, ErrRateLimited)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package singleflight
+
+import (
+	"context"
+	"io"
+)
+
+// readerChunkSize is the size of the chunks a shared body is read in.
+const readerChunkSize = 32 << 10
+
+// ReaderGroup deduplicates fetches returning an io.ReadCloser, such as the
+// body of an HTTP response or an object of a blob store. Handing a single
+// ReadCloser to several callers does not work, as every byte can only be
+// read once: ReaderGroup instead tees the body, so every caller receives a
+// reader of its own over the same underlying bytes.
+//
+// The body is read by the group as fast as it is delivered, independent of
+// the pace of the callers, and buffered until the fetch is complete, so that
+// callers joining late read it from its start; see StreamGroup, which
+// ReaderGroup is built on. A large body is therefore held in memory whole
+// while it is fetched. The zero value is ready to use. A ReaderGroup must
+// not be copied after first use.
+type ReaderGroup[K comparable] struct {
+	streams StreamGroup[K, []byte]
+}
+
+// DoReader executes fn and returns a reader of the body it returns, making
+// sure that only one fetch is in-flight for a given key at a time. If a
+// duplicate comes in, the duplicate caller receives a reader over the same
+// body, starting at its beginning. shared reports whether the caller joined
+// the fetch of another caller.
+//
+// DoReader returns once fn returned and the first bytes of the body are
+// available, or with the error of fn, or ctx.Err() if ctx is done first.
+// Errors reading the body are returned by Read, as is ctx.Err() once ctx
+// is done. The reader must be closed once done with it; the body returned
+// by fn is closed once it was read completely or every reader was closed.
+func (g *ReaderGroup[K]) DoReader(
+	ctx context.Context, key K, fn func() (io.ReadCloser, error),
+) (rc io.ReadCloser, err error, shared bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	ch := g.streams.DoStream(ctx, key, func(yield func([]byte) bool) error {
+		body, err := fn()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		return teeBody(body, yield)
+	})
+
+	r := &sharedReader{ctx: ctx, ch: ch, cancel: cancel}
+
+	item, ok := <-ch
+	switch {
+	case !ok && ctx.Err() != nil:
+		cancel()
+		return nil, ctx.Err(), false
+	case !ok:
+		r.err = io.EOF
+	case item.Err != nil:
+		cancel()
+		return nil, item.Err, item.Shared
+	default:
+		r.chunk = item.Val
+	}
+
+	return r, nil, item.Shared
+}
+
+// teeBody reads body in chunks of their own, passing them to yield until
+// body is exhausted or yield reports false.
+func teeBody(body io.Reader, yield func([]byte) bool) error {
+	for {
+		buf := make([]byte, readerChunkSize)
+		n, err := body.Read(buf)
+		if n > 0 && !yield(buf[:n]) {
+			return nil
+		}
+		if err == io.EOF { //nolint:errorlint
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sharedReader is the reader of a shared body of a caller, see
+// ReaderGroup.DoReader.
+type sharedReader struct {
+	ctx    context.Context //nolint:containedctx
+	ch     <-chan StreamItem[[]byte]
+	cancel context.CancelFunc
+	chunk  []byte
+	err    error
+}
+
+// Read implements io.Reader.
+func (r *sharedReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		item, ok := <-r.ch
+		switch {
+		case !ok:
+			r.err = r.closedErr()
+		case item.Err != nil:
+			r.err = item.Err
+		default:
+			r.chunk = item.Val
+		}
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+
+	return n, nil
+}
+
+// Close implements io.Closer. Reads after Close fail with
+// io.ErrClosedPipe.
+func (r *sharedReader) Close() error {
+	r.cancel()
+	r.chunk, r.err = nil, io.ErrClosedPipe
+
+	return nil
+}
+
+// closedErr returns the error of a read once the stream was closed:
+// ctx.Err() if the stream was cut short because ctx is done, io.EOF
+// otherwise.
+func (r *sharedReader) closedErr() error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+
+	return io.EOF
+}
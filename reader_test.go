@@ -0,0 +1,130 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// trackedBody is a body recording whether it was closed.
+type trackedBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestDoReader(t *testing.T) {
+	var g ReaderGroup[string]
+
+	want := strings.Repeat("singleflight", readerChunkSize/4)
+	body := &trackedBody{Reader: strings.NewReader(want)}
+
+	var calls int32
+	fn := func() (io.ReadCloser, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(sleepJoin)
+		return body, nil
+	}
+
+	var wg sync.WaitGroup
+	got := make([]string, 3)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc, err, _ := g.DoReader(context.Background(), keyA, fn)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer rc.Close()
+
+			b, err := io.ReadAll(rc)
+			if err != nil {
+				t.Error(err)
+			}
+			got[i] = string(b)
+		}()
+	}
+	wg.Wait()
+
+	for i, s := range got {
+		if s != want {
+			t.Fatalf("reader %d got %d bytes, want %d", i, len(s), len(want))
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+	if !body.closed.Load() {
+		t.Fatal("body not closed")
+	}
+}
+
+func TestDoReaderErrors(t *testing.T) {
+	var g ReaderGroup[string]
+
+	errFn := errors.New("failed")
+	if _, err, _ := g.DoReader(context.Background(), keyA, func() (io.ReadCloser, error) {
+		return nil, errFn
+	}); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+
+	errRead := errors.New("connection reset")
+	rc, err, _ := g.DoReader(context.Background(), keyB, func() (io.ReadCloser, error) {
+		return io.NopCloser(io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errRead))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(rc); !errors.Is(err, errRead) || string(b) != "partial" {
+		t.Fatalf("got %q, %v, want partial, %v", b, err, errRead)
+	}
+	rc.Close()
+	if _, err := rc.Read(make([]byte, 1)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("read after close err=%v, want %v", err, io.ErrClosedPipe)
+	}
+}
+
+func TestDoReaderClose(t *testing.T) {
+	var g ReaderGroup[string]
+
+	body := &trackedBody{Reader: infiniteReader{}}
+	rc, err, _ := g.DoReader(context.Background(), keyA, func() (io.ReadCloser, error) {
+		return body, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(rc, make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for !body.closed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("body not closed after every reader was closed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// infiniteReader is a reader of endless zeros.
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
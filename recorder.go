@@ -0,0 +1,104 @@
+package singleflight
+
+import (
+	"sync"
+	"time"
+)
+
+// CallRecord describes a completed flight, see WithFlightRecorder.
+type CallRecord struct {
+	// Key is the textual form of the key of the flight, see FlightInfo.
+	Key string `json:"key"`
+	// Lane is the lane of the flight: "normal", "fallback" or "priority".
+	Lane string `json:"lane"`
+	// Start is the time the flight started.
+	Start time.Time `json:"start"`
+	// Duration is the time the flight took to complete.
+	Duration time.Duration `json:"duration"`
+	// Waiters is the number of callers that joined the flight.
+	Waiters int `json:"waiters"`
+	// Shard is the index of the shard of a ShardedGroup the flight was in
+	// progress on. It is zero for flights of a Group.
+	Shard int `json:"shard"`
+	// Err is the error the flight completed with, if any.
+	Err error `json:"-"`
+	// Error is the message of Err, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// flightRecorder is a bounded ring buffer of the most recently completed
+// flights of a group, shared by the shards of a ShardedGroup.
+type flightRecorder struct {
+	mu      sync.Mutex
+	records []CallRecord
+	next    int
+	full    bool
+}
+
+// record adds rec, replacing the oldest record once the buffer is full.
+func (r *flightRecorder) record(rec CallRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	r.full = r.full || r.next == 0
+}
+
+// recent returns the records, most recent first.
+func (r *flightRecorder) recent() []CallRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.records)
+	}
+
+	records := make([]CallRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		records = append(records, r.records[(r.next-i+len(r.records))%len(r.records)])
+	}
+
+	return records
+}
+
+// recordCall records the completed call c, registered under fk, with the
+// flight recorder of g, if any. The caller must hold g.mu.
+func (g *Group[K, V]) recordCall(fk flightKey[K], c *call[V]) {
+	r := g.settings().recorder
+	if r == nil {
+		return
+	}
+
+	rec := CallRecord{
+		Key:      fk.String(),
+		Lane:     fk.lane.String(),
+		Start:    c.start,
+		Duration: time.Since(c.start),
+		Waiters:  c.dups,
+		Shard:    max(g.shard-1, 0),
+		Err:      c.err,
+	}
+	if c.err != nil {
+		rec.Error = c.err.Error()
+	}
+	r.record(rec)
+}
+
+// Recent returns the flights of g most recently completed, most recent
+// first, as recorded due to WithFlightRecorder. It returns nil if g does
+// not record flights.
+func (g *Group[K, V]) Recent() []CallRecord {
+	if r := g.settings().recorder; r != nil {
+		return r.recent()
+	}
+
+	return nil
+}
+
+// Recent returns the flights of all shards of sg most recently completed,
+// see Group.Recent.
+func (sg *ShardedGroup[K, V]) Recent() []CallRecord {
+	return sg.shards[0].Recent()
+}
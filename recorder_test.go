@@ -0,0 +1,50 @@
+package singleflight
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGroupFlightRecorder(t *testing.T) {
+	g := NewGroup[string, int](WithFlightRecorder(2))
+	flightRecorderKeepsLast(t, g, g.Recent)
+}
+
+func TestShardedGroupFlightRecorder(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithFlightRecorder(2)))
+	flightRecorderKeepsLast(t, sg, sg.Recent)
+}
+
+func flightRecorderKeepsLast(t *testing.T, d doer[string, int], recent func() []CallRecord) {
+	t.Helper()
+
+	if records := recent(); len(records) != 0 {
+		t.Fatalf("records=%+v, want none", records)
+	}
+
+	errFn := errors.New("failed")
+	d.Do(keyA, func() (int, error) { return wantValueInt, nil })
+	d.Do(keyB, func() (int, error) { return 0, errFn })
+
+	records := recent()
+	if len(records) != 2 || records[0].Key != keyB || records[1].Key != keyA {
+		t.Fatalf("records=%+v, want %s then %s", records, keyB, keyA)
+	}
+	if r := records[0]; !errors.Is(r.Err, errFn) || r.Error != "failed" || r.Lane != "normal" || r.Start.IsZero() {
+		t.Fatalf("record=%+v, want failed normal flight", r)
+	}
+
+	d.Do(keyA, func() (int, error) { return wantValueInt, nil })
+	if records := recent(); len(records) != 2 || records[0].Key != keyA || records[1].Key != keyB {
+		t.Fatalf("records=%+v, want %s then %s", records, keyA, keyB)
+	}
+}
+
+func TestGroupFlightRecorderDisabled(t *testing.T) {
+	var g Group[string, int]
+	g.Do(keyA, func() (int, error) { return wantValueInt, nil })
+
+	if records := g.Recent(); records != nil {
+		t.Fatalf("records=%+v, want nil without a recorder", records)
+	}
+}
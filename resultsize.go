@@ -0,0 +1,44 @@
+package singleflight
+
+// ResultSizePolicy determines what happens to a result exceeding the
+// maximum result size of a group, see WithMaxResultSize.
+type ResultSizePolicy int
+
+const (
+	// ResultSizeReject drops the value; every caller of the flight receives
+	// the zero value and a *ResultTooLargeError.
+	ResultSizeReject ResultSizePolicy = iota
+	// ResultSizeFlag delivers the value to every caller of the flight along
+	// with a *ResultTooLargeError, leaving the decision to the callers.
+	ResultSizeFlag
+)
+
+// resultLimit is the maximum result size of a group.
+type resultLimit struct {
+	sizer  any // func(V) int64 of the value type V of the group
+	max    int64
+	policy ResultSizePolicy
+}
+
+// checkResult applies the maximum result size of the group, if any, to the
+// result v, err of an execution.
+func (g *Group[K, V]) checkResult(v V, err error) (V, error) {
+	limit := g.settings().resultLimit
+	if limit == nil || err != nil {
+		return v, err
+	}
+
+	sizer, _ := limit.sizer.(func(V) int64) // checked by checkConfig
+	size := sizer(v)
+	if size <= limit.max {
+		return v, nil
+	}
+
+	err = &ResultTooLargeError{Size: size, Max: limit.max}
+	if limit.policy == ResultSizeReject {
+		var zero V
+		return zero, err
+	}
+
+	return v, err
+}
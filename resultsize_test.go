@@ -0,0 +1,66 @@
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGroupMaxResultSizeReject(t *testing.T) {
+	g := NewGroup[string, string](
+		WithMaxResultSize(8, func(v string) int64 { return int64(len(v)) }, ResultSizeReject),
+	)
+	maxResultSize(t, g, keyA, "")
+}
+
+func TestGroupMaxResultSizeFlag(t *testing.T) {
+	g := NewGroup[string, string](
+		WithMaxResultSize(8, func(v string) int64 { return int64(len(v)) }, ResultSizeFlag),
+	)
+	maxResultSize(t, g, keyA, strings.Repeat("v", 9))
+}
+
+func TestShardedGroupMaxResultSize(t *testing.T) {
+	sg := NewShardedGroup[string, string](WithGroupOptions(
+		WithMaxResultSize(8, func(v string) int64 { return int64(len(v)) }, ResultSizeReject),
+	))
+	maxResultSize(t, sg, keyB, "")
+}
+
+func maxResultSize[T ~string](t *testing.T, d doer[T, string], key T, wantLarge string) {
+	t.Helper()
+
+	small := strings.Repeat("v", 8)
+	if v, err, _ := d.Do(key, func() (string, error) { return small, nil }); v != small || err != nil {
+		t.Fatalf("v=%q err=%v, want %q nil", v, err, small)
+	}
+
+	v, err, _ := d.Do(key, func() (string, error) { return strings.Repeat("v", 9), nil })
+
+	var tooLarge *ResultTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, ErrResultTooLarge) {
+		t.Fatalf("err=%v, want %T matching %v", err, tooLarge, ErrResultTooLarge)
+	}
+	if tooLarge.Size != 9 || tooLarge.Max != 8 {
+		t.Fatalf("Size=%d Max=%d, want 9 8", tooLarge.Size, tooLarge.Max)
+	}
+	if v != wantLarge {
+		t.Fatalf("v=%q, want %q", v, wantLarge)
+	}
+
+	// errors of the work function are left alone
+	errFn := errors.New("failed")
+	if _, err, _ := d.Do(key, func() (string, error) { return strings.Repeat("v", 9), errFn }); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+}
+
+func TestGroupMaxResultSizeOfAnotherType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "func(string) int64") {
+			t.Fatalf("recovered %v, want panic naming the sizer", r)
+		}
+	}()
+	NewGroup[string, int](WithMaxResultSize(8, func(v string) int64 { return int64(len(v)) }, ResultSizeReject))
+}
@@ -0,0 +1,99 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures how failed executions are retried before their
+// error is shared with the callers of the flight, see WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of executions of a flight,
+	// including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubling with every
+	// further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries. If zero, the delay is only
+	// capped by the largest time.Duration.
+	MaxDelay time.Duration
+	// Jitter adds a random duration of up to Jitter times the delay to
+	// every delay, so flights failing together do not retry in lockstep.
+	Jitter float64
+	// Retryable reports whether an execution failing with err is retried.
+	// If nil, every error is retried. Cancellations and expired deadlines
+	// are never retried.
+	Retryable func(err error) bool
+}
+
+// retryable reports whether an execution failing with err is retried.
+func (p *RetryPolicy) retryable(err error) bool {
+	if err == errGoexit { //nolint:errorlint
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// delay returns the delay before the retry following the given number of
+// failed attempts. Delays that would overflow a time.Duration saturate at
+// its maximum.
+func (p *RetryPolicy) delay(failures int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	return jittered(backoff(p.BaseDelay, failures, p.MaxDelay), p.Jitter)
+}
+
+// backoff returns base doubled for every failure after the first, capped at
+// limit if limit is positive. Delays that would overflow a time.Duration
+// saturate at its maximum.
+func backoff(base time.Duration, failures int, limit time.Duration) time.Duration {
+	d := base
+	if shift := min(max(failures-1, 0), 62); d > math.MaxInt64>>shift {
+		d = math.MaxInt64
+	} else {
+		d <<= shift
+	}
+	if limit > 0 && d > limit {
+		d = limit
+	}
+
+	return d
+}
+
+// jittered returns d extended by a random jitter of up to the fraction
+// factor of d. Delays that would overflow a time.Duration saturate at its
+// maximum.
+func jittered(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 || d <= 0 {
+		return d
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(min(float64(d)*factor, 1<<62)) + 1))
+	if jitter > math.MaxInt64-d {
+		return math.MaxInt64
+	}
+
+	return d + jitter
+}
+
+// retrying returns fn wrapped to be retried according to p.
+func retrying[V any](p *RetryPolicy, fn func() (V, error)) func() (V, error) {
+	return func() (V, error) {
+		v, err := fn()
+		for failures := 1; err != nil && failures < p.MaxAttempts && p.retryable(err); failures++ {
+			time.Sleep(p.delay(failures))
+			v, err = fn()
+		}
+
+		return v, err
+	}
+}
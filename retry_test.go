@@ -0,0 +1,118 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupRetry(t *testing.T) {
+	g := NewGroup[string, int](WithRetry(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		Jitter:      0.5,
+	}))
+	retrySharesOutcome(t, g, keyA)
+}
+
+func TestShardedGroupRetry(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithRetry(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	})))
+	retrySharesOutcome(t, sg, keyB)
+}
+
+func retrySharesOutcome[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	var calls int32
+	errFn := errors.New("transient")
+	fn := func() (int, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			time.Sleep(sleepJoin / 3)
+			return 0, errFn
+		}
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	for range numCallers {
+		go func() {
+			defer wg.Done()
+			if v, err, _ := d.Do(key, fn); v != wantValueInt || err != nil {
+				t.Errorf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("calls=%d, want 3", got)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	p := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    4 * time.Millisecond,
+		Retryable:   func(err error) bool { return !errors.Is(err, errPermanent) },
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int32
+	}{
+		{name: "retryable", err: errors.New("transient"), want: 5},
+		{name: "not retryable", err: errPermanent, want: 1},
+		{name: "cancellation", err: context.Canceled, want: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			_, err := retrying(&p, func() (int, error) {
+				atomic.AddInt32(&calls, 1)
+				return 0, tc.err
+			})()
+			if !errors.Is(err, tc.err) || calls != tc.want {
+				t.Fatalf("err=%v calls=%d, want %v %d", err, calls, tc.err, tc.want)
+			}
+		})
+	}
+
+	// delays double up to MaxDelay
+	for i, want := range []time.Duration{1, 2, 4, 4} {
+		if got := p.delay(i + 1); got != want*time.Millisecond {
+			t.Fatalf("delay(%d)=%v, want %v", i+1, got, want*time.Millisecond)
+		}
+	}
+}
+
+func TestRetryPolicyDelayOverflow(t *testing.T) {
+	// uncapped delays saturate instead of wrapping around
+	p := RetryPolicy{BaseDelay: time.Second}
+	for failures := 2; failures <= 100; failures++ {
+		if got, prev := p.delay(failures), p.delay(failures-1); got < prev {
+			t.Fatalf("delay(%d)=%v, below delay(%d)=%v", failures, got, failures-1, prev)
+		}
+	}
+	if got := p.delay(100); got != math.MaxInt64 {
+		t.Fatalf("delay(100)=%v, want %v", got, time.Duration(math.MaxInt64))
+	}
+
+	p = RetryPolicy{BaseDelay: time.Hour, Jitter: 4}
+	for failures := 1; failures <= 100; failures++ {
+		if got := p.delay(failures); got < time.Hour {
+			t.Fatalf("delay(%d)=%v with jitter, want at least %v", failures, got, time.Hour)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Schedule determines when a key registered with a Scheduler is refreshed.
+// Cron schedules of common cron packages implement it.
+type Schedule interface {
+	// Next returns the time of the refresh following t.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a Schedule refreshing every interval d.
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+// every is the Schedule returned by Every.
+type every time.Duration
+
+// Next implements Schedule.
+func (d every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
+
+// Refresher is implemented by groups serving results without executing fn,
+// such as CachedGroup, so that a Scheduler refreshes keys through them.
+type Refresher[K comparable, V any] interface {
+	// Refresh executes and deduplicates fn for key like Do, but never
+	// serves a result without executing fn.
+	Refresh(key K, fn func() (V, error)) (v V, err error, shared bool)
+}
+
+// Scheduler keeps a set of registered keys refreshed through a group, each
+// by its own loader and on its own Schedule. Loaders typically write their
+// result to a cache as well; refreshes join flights of the same key
+// started by other callers of the group. Groups implementing Refresher,
+// such as CachedGroup, are refreshed via Refresh, so that they execute the
+// loader rather than serve its cached result.
+//
+// Keys are refreshed while the scheduler is started; see Start and Stop.
+type Scheduler[K comparable, V any] struct {
+	group  Singleflighter[K, V]
+	config SchedulerConfig
+
+	mu      sync.Mutex
+	entries map[K]*scheduledKey[V]
+	ctx     context.Context //nolint:containedctx
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// scheduledKey is a key registered with a Scheduler.
+type scheduledKey[V any] struct {
+	schedule Schedule
+	loader   func() (V, error)
+	cancel   context.CancelFunc
+}
+
+// NewScheduler constructs a Scheduler refreshing keys through group,
+// configured by opts.
+func NewScheduler[K comparable, V any](
+	group Singleflighter[K, V], opts ...SchedulerConfigOption,
+) *Scheduler[K, V] {
+	s := &Scheduler[K, V]{
+		group:   group,
+		entries: make(map[K]*scheduledKey[V]),
+	}
+
+	for _, opt := range opts {
+		opt(&s.config)
+	}
+
+	return s
+}
+
+// Register registers key to be refreshed by loader according to schedule,
+// replacing a previous registration of key. If the scheduler is started,
+// refreshing starts right away.
+func (s *Scheduler[K, V]) Register(key K, schedule Schedule, loader func() (V, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unregister(key)
+
+	e := &scheduledKey[V]{schedule: schedule, loader: loader}
+	s.entries[key] = e
+
+	if s.ctx != nil {
+		s.run(key, e)
+	}
+}
+
+// Unregister stops refreshing key. A refresh already running completes.
+func (s *Scheduler[K, V]) Unregister(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unregister(key)
+}
+
+// Start starts refreshing the registered keys. Starting a started
+// scheduler has no effect.
+func (s *Scheduler[K, V]) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		return
+	}
+
+	s.ctx, s.stop = context.WithCancel(context.Background())
+	for key, e := range s.entries {
+		s.run(key, e)
+	}
+}
+
+// Stop stops refreshing and waits for running refreshes to complete. The
+// scheduler may be started again.
+func (s *Scheduler[K, V]) Stop() {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.stop()
+		s.ctx, s.stop = nil, nil
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// unregister removes the registration of key. The caller must hold s.mu.
+func (s *Scheduler[K, V]) unregister(key K) {
+	if e, ok := s.entries[key]; ok {
+		if e.cancel != nil {
+			e.cancel()
+		}
+		delete(s.entries, key)
+	}
+}
+
+// run starts refreshing key. The caller must hold s.mu.
+func (s *Scheduler[K, V]) run(key K, e *scheduledKey[V]) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	e.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		failures := 0
+		timer := time.NewTimer(s.delay(time.Now(), e.schedule, failures))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			if _, err, _ := s.refresh(key, e.loader); err != nil {
+				failures++
+			} else {
+				failures = 0
+			}
+
+			timer.Reset(s.delay(time.Now(), e.schedule, failures))
+		}
+	}()
+}
+
+// refresh refreshes key by loader through the group, bypassing results it
+// serves without executing loader, see Refresher.
+func (s *Scheduler[K, V]) refresh(key K, loader func() (V, error)) (V, error, bool) {
+	if r, ok := s.group.(Refresher[K, V]); ok {
+		return r.Refresh(key, loader)
+	}
+
+	return s.group.Do(key, loader)
+}
+
+// delay returns the time from now until the next refresh on schedule after
+// the given number of consecutive failures.
+func (s *Scheduler[K, V]) delay(now time.Time, schedule Schedule, failures int) time.Duration {
+	d := max(schedule.Next(now).Sub(now), 0)
+
+	if failures > 0 && s.config.backoffBase > 0 {
+		d = max(d, backoff(s.config.backoffBase, failures, s.config.backoffMax))
+	}
+
+	return jittered(d, s.config.jitter)
+}
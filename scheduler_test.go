@@ -0,0 +1,130 @@
+package singleflight
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRefreshes(t *testing.T) {
+	var g Group[string, int]
+	s := NewScheduler[string, int](&g, WithJitter(0.1))
+
+	var loads atomic.Int32
+	s.Register(keyA, Every(sleepJoin/4), func() (int, error) {
+		loads.Add(1)
+		return wantValueInt, nil
+	})
+
+	time.Sleep(sleepJoin)
+	if got := loads.Load(); got != 0 {
+		t.Fatalf("loads before Start=%d, want 0", got)
+	}
+
+	s.Start()
+	s.Start()
+	time.Sleep(sleepJoin)
+	s.Stop()
+
+	stopped := loads.Load()
+	if stopped < 2 {
+		t.Fatalf("loads=%d, want at least 2", stopped)
+	}
+
+	time.Sleep(sleepJoin)
+	if got := loads.Load(); got != stopped {
+		t.Fatalf("loads after Stop=%d, want %d", got, stopped)
+	}
+
+	// restart, then unregister
+	s.Start()
+	defer s.Stop()
+	time.Sleep(sleepJoin)
+	s.Unregister(keyA)
+	unregistered := loads.Load()
+	if unregistered <= stopped {
+		t.Fatalf("loads after restart=%d, want more than %d", unregistered, stopped)
+	}
+
+	time.Sleep(sleepJoin)
+	if got := loads.Load(); got > unregistered+1 {
+		t.Fatalf("loads after Unregister=%d, want at most %d", got, unregistered+1)
+	}
+}
+
+func TestSchedulerFailureBackoff(t *testing.T) {
+	var g Group[string, int]
+	s := NewScheduler[string, int](&g, WithFailureBackoff(sleepJoin, 4*sleepJoin))
+
+	var loads atomic.Int32
+	s.Register(keyB, Every(time.Millisecond), func() (int, error) {
+		loads.Add(1)
+		return 0, errors.New("unavailable")
+	})
+
+	s.Start()
+	time.Sleep(2 * sleepJoin)
+	s.Stop()
+
+	// 1ms schedule, held off for 1x and 2x sleepJoin after failures
+	if got := loads.Load(); got < 1 || got > 3 {
+		t.Fatalf("loads=%d, want 1 to 3", got)
+	}
+}
+
+func TestSchedulerDelay(t *testing.T) {
+	s := NewScheduler[string, int](nil, WithFailureBackoff(time.Second, 5*time.Second))
+	now := time.Now()
+
+	for failures, want := range []time.Duration{
+		time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+	} {
+		if got := s.delay(now, Every(time.Millisecond), failures); got != want {
+			t.Errorf("delay after %d failures=%v, want %v", failures, got, want)
+		}
+	}
+
+	if got := s.delay(now, Every(time.Minute), 3); got != time.Minute {
+		t.Errorf("delay=%v, want schedule of %v", got, time.Minute)
+	}
+}
+
+func TestSchedulerDelayOverflow(t *testing.T) {
+	for name, s := range map[string]*Scheduler[string, int]{
+		"unbounded": NewScheduler[string, int](nil, WithFailureBackoff(time.Hour, 0), WithJitter(1)),
+		"bounded":   NewScheduler[string, int](nil, WithFailureBackoff(time.Hour, math.MaxInt64), WithJitter(1)),
+	} {
+		prev := time.Duration(0)
+		for _, failures := range []int{1, 10, 30, 40, 64, 1000} {
+			got := s.delay(time.Now(), Every(time.Millisecond), failures)
+			if got < prev {
+				t.Fatalf("%s: delay after %d failures=%v, want at least %v", name, failures, got, prev)
+			}
+			prev = got
+		}
+	}
+}
+
+func TestSchedulerRefreshesCachedGroup(t *testing.T) {
+	cg := NewCachedGroup[string, int](nil, time.Hour)
+	s := NewScheduler[string, int](cg)
+
+	var loads atomic.Int32
+	s.Register(keyA, Every(sleepJoin/4), func() (int, error) {
+		return int(loads.Add(1)), nil
+	})
+
+	s.Start()
+	time.Sleep(sleepJoin)
+	s.Stop()
+
+	got := loads.Load()
+	if got < 2 {
+		t.Fatalf("loads=%d, want at least 2 despite cached results", got)
+	}
+	if v, _, _ := cg.Do(keyA, func() (int, error) { return 0, nil }); v != int(got) {
+		t.Fatalf("cached v=%d, want latest refresh %d", v, got)
+	}
+}
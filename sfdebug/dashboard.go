@@ -0,0 +1,65 @@
+package sfdebug
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+// dashboardTemplate renders the dashboard page.
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+// Dashboard is an HTML page showing the snapshots of a Handler live: the
+// flights in progress and hottest keys, the dedupe ratio and the shard
+// heat of every registered group. The page polls the JSON snapshot, which
+// the Dashboard serves itself for requests with the query parameter
+// "format=json".
+type Dashboard struct {
+	handler *Handler
+
+	// Top is the number of hottest keys shown per group.
+	Top int
+	// Interval is the time between refreshes of the page.
+	Interval time.Duration
+}
+
+// Dashboard returns a Dashboard for the groups of h, showing the 20 hottest
+// keys per group and refreshing every second. Mount it next to pprof, e.g.
+// at DefaultPath + "/ui".
+func (h *Handler) Dashboard() *Dashboard {
+	return &Dashboard{
+		handler:  h,
+		Top:      20,
+		Interval: time.Second,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "json" {
+		d.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_ = dashboardTemplate.Execute(w, struct {
+		Top      int
+		Interval int64
+	}{
+		Top:      d.Top,
+		Interval: d.Interval.Milliseconds(),
+	})
+}
@@ -0,0 +1,39 @@
+package sfdebug
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// expvarGroups holds the groups published via PublishExpvar by name.
+var (
+	expvarMu     sync.Mutex
+	expvarGroups = make(map[string]Inspector)
+)
+
+// PublishExpvar publishes the GroupSnapshot of g under name via expvar, for
+// services that only serve the stock /debug/vars endpoint. The snapshot is
+// taken whenever the variable is read. Publishing again under the same name
+// replaces the group; PublishExpvar fails if name is in use by a variable
+// published otherwise.
+func PublishExpvar(name string, g Inspector) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	if _, ok := expvarGroups[name]; !ok {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("sfdebug: expvar %q is already in use", name)
+		}
+		expvar.Publish(name, expvar.Func(func() any {
+			expvarMu.Lock()
+			g := expvarGroups[name]
+			expvarMu.Unlock()
+
+			return snapshot(name, g)
+		}))
+	}
+	expvarGroups[name] = g
+
+	return nil
+}
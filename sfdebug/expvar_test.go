@@ -0,0 +1,70 @@
+package sfdebug
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// expvarNames numbers the names published by tests, which expvar never
+// releases, so tests may run repeatedly in one process.
+var expvarNames atomic.Int64
+
+// expvarName returns a name not published yet.
+func expvarName() string {
+	return fmt.Sprintf("singleflight-users-%d", expvarNames.Add(1))
+}
+
+func TestPublishExpvar(t *testing.T) {
+	name := expvarName()
+	sg := singleflight.NewShardedGroup[string, int](singleflight.WithShardCount(4))
+	if err := PublishExpvar(name, sg); err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	sg.DoChan("user:1", func() (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	var gs GroupSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &gs); err != nil {
+		t.Fatal(err)
+	}
+
+	if gs.Name != name || len(gs.Flights) != 1 || gs.Flights[0].Key != "user:1" {
+		t.Fatalf("snapshot=%+v, want flight user:1", gs)
+	}
+	if len(gs.Shards) != 4 || gs.Stats == nil || gs.Stats.Calls != 1 {
+		t.Fatalf("snapshot=%+v, want 4 shards and 1 call", gs)
+	}
+}
+
+func TestPublishExpvarAgain(t *testing.T) {
+	name := expvarName()
+	PublishExpvar(name, singleflight.NewShardedGroup[string, int](singleflight.WithShardCount(4)))
+
+	// publishing again replaces the group
+	if err := PublishExpvar(name, &singleflight.Group[string, int]{}); err != nil {
+		t.Fatalf("PublishExpvar again=%v, want nil", err)
+	}
+	var gs GroupSnapshot
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &gs); err != nil {
+		t.Fatal(err)
+	}
+	if len(gs.Shards) != 0 {
+		t.Fatalf("snapshot=%+v, want the replacing group without shards", gs)
+	}
+
+	other := expvarName()
+	expvar.NewInt(other)
+	if err := PublishExpvar(other, &singleflight.Group[string, int]{}); err == nil {
+		t.Fatal("PublishExpvar of a name in use by another variable=nil, want error")
+	}
+}
@@ -0,0 +1,172 @@
+// Package sfdebug exposes the flights in progress on singleflight groups
+// via HTTP, for inspection of live services, e.g. with the
+// singleflight-inspect command.
+package sfdebug
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// DefaultPath is the path the handler is conventionally mounted at.
+const DefaultPath = "/debug/singleflight"
+
+// Inspector is implemented by groups whose flights can be inspected, such
+// as Group and ShardedGroup.
+type Inspector interface {
+	Inflight() []singleflight.FlightInfo
+}
+
+// ShardInspector is implemented by sharded groups that additionally report
+// their per-shard load, such as ShardedGroup.
+type ShardInspector interface {
+	Inspector
+	ShardLoad() []int
+}
+
+// StatsInspector is implemented by groups that additionally report their
+// statistics, such as Group and ShardedGroup.
+type StatsInspector interface {
+	Inspector
+	Stats() singleflight.GroupStats
+}
+
+// RecentInspector is implemented by groups that additionally report their
+// most recently completed flights, such as Group and ShardedGroup.
+type RecentInspector interface {
+	Inspector
+	Recent() []singleflight.CallRecord
+}
+
+// Snapshot is the JSON document served by Handler.
+type Snapshot struct {
+	// Time is the time the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Groups holds a snapshot per registered group, ordered by name.
+	Groups []GroupSnapshot `json:"groups"`
+}
+
+// GroupSnapshot is the snapshot of a single group.
+type GroupSnapshot struct {
+	// Name is the name the group is registered with.
+	Name string `json:"name"`
+	// Flights are the flights in progress, hottest first.
+	Flights []singleflight.FlightInfo `json:"flights"`
+	// Shards is the number of flights in progress per shard, for groups
+	// implementing ShardInspector.
+	Shards []int `json:"shards,omitempty"`
+	// Stats are the statistics of groups implementing StatsInspector.
+	Stats *singleflight.GroupStats `json:"stats,omitempty"`
+	// DedupeRatio is the dedupe ratio of Stats.
+	DedupeRatio float64 `json:"dedupeRatio,omitempty"`
+	// Recent are the most recently completed flights, most recent first,
+	// for groups implementing RecentInspector and recording flights (see
+	// singleflight.WithFlightRecorder).
+	Recent []singleflight.CallRecord `json:"recent,omitempty"`
+}
+
+// Handler serves a Snapshot of the registered groups as JSON. The query
+// parameter "group" restricts the snapshot to the named group.
+//
+// The zero value is ready to use.
+type Handler struct {
+	mu     sync.RWMutex
+	groups map[string]Inspector
+}
+
+// NewHandler returns a Handler serving the given groups by name.
+func NewHandler(groups map[string]Inspector) *Handler {
+	h := &Handler{}
+	for name, g := range groups {
+		h.Register(name, g)
+	}
+
+	return h
+}
+
+// Register adds g to the snapshots served by h under name, replacing a
+// group previously registered under the same name.
+func (h *Handler) Register(name string, g Inspector) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.groups == nil {
+		h.groups = make(map[string]Inspector)
+	}
+	h.groups[name] = g
+}
+
+// Unregister removes the group registered under name.
+func (h *Handler) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.groups, name)
+}
+
+// Snapshot returns a snapshot of the registered groups. If name is not
+// empty, only the group registered under name is included.
+func (h *Handler) Snapshot(name string) Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	snap := Snapshot{
+		Time:   time.Now(),
+		Groups: make([]GroupSnapshot, 0, len(h.groups)),
+	}
+
+	for n, g := range h.groups {
+		if name != "" && n != name {
+			continue
+		}
+
+		snap.Groups = append(snap.Groups, snapshot(n, g))
+	}
+
+	slices.SortFunc(snap.Groups, func(a, b GroupSnapshot) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return snap
+}
+
+// snapshot returns the snapshot of the group g registered under name.
+func snapshot(name string, g Inspector) GroupSnapshot {
+	gs := GroupSnapshot{
+		Name:    name,
+		Flights: g.Inflight(),
+	}
+	if sg, ok := g.(ShardInspector); ok {
+		gs.Shards = sg.ShardLoad()
+	}
+	if sg, ok := g.(StatsInspector); ok {
+		stats := sg.Stats()
+		gs.Stats, gs.DedupeRatio = &stats, stats.DedupeRatio()
+	}
+	if rg, ok := g.(RecentInspector); ok {
+		gs.Recent = rg.Recent()
+	}
+
+	return gs
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	_ = json.NewEncoder(w).Encode(h.Snapshot(r.URL.Query().Get("group")))
+}
@@ -0,0 +1,137 @@
+package sfdebug
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+func TestHandler(t *testing.T) {
+	var g singleflight.Group[string, int]
+	sg := singleflight.NewShardedGroup[string, int](singleflight.WithShardCount(4))
+
+	h := NewHandler(map[string]Inspector{"users": &g})
+	h.Register("search", sg)
+
+	release := make(chan struct{})
+	defer close(release)
+	fn := func() (int, error) {
+		<-release
+		return 1, nil
+	}
+	g.DoChan("user:1", fn)
+	g.DoChan("user:1", fn)
+	sg.DoChan("q", fn)
+	time.Sleep(30 * time.Millisecond)
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	snap := get(t, srv.URL)
+	if len(snap.Groups) != 2 {
+		t.Fatalf("groups=%+v, want 2", snap.Groups)
+	}
+
+	search, users := snap.Groups[0], snap.Groups[1]
+	if search.Name != "search" || len(search.Flights) != 1 || len(search.Shards) != 4 {
+		t.Fatalf("search=%+v, want 1 flight on 4 shards", search)
+	}
+	if shard := search.Flights[0].Shard; search.Shards[shard] != 1 {
+		t.Fatalf("flight on shard %d, load=%v", shard, search.Shards)
+	}
+	if users.Name != "users" || len(users.Flights) != 1 || users.Flights[0].Waiters != 1 || users.Shards != nil {
+		t.Fatalf("users=%+v, want 1 flight with 1 waiter and no shards", users)
+	}
+
+	if snap := get(t, srv.URL+"?group=users"); len(snap.Groups) != 1 || snap.Groups[0].Name != "users" {
+		t.Fatalf("groups=%+v, want users only", snap.Groups)
+	}
+
+	h.Unregister("users")
+	if snap := get(t, srv.URL); len(snap.Groups) != 1 {
+		t.Fatalf("groups=%+v, want 1 after Unregister", snap.Groups)
+	}
+
+	resp, err := http.Post(srv.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status=%d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func get(t *testing.T, url string) Snapshot {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var snap Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		t.Fatal(err)
+	}
+
+	return snap
+}
+
+func TestHandlerRecent(t *testing.T) {
+	g := singleflight.NewGroup[string, int](singleflight.WithFlightRecorder(8))
+	g.Do("user:1", func() (int, error) { return 0, errors.New("not found") })
+
+	srv := httptest.NewServer(NewHandler(map[string]Inspector{"users": g}))
+	defer srv.Close()
+
+	snap := get(t, srv.URL)
+	if len(snap.Groups) != 1 {
+		t.Fatalf("groups=%+v, want users", snap.Groups)
+	}
+	if recent := snap.Groups[0].Recent; len(recent) != 1 || recent[0].Key != "user:1" || recent[0].Error != "not found" {
+		t.Fatalf("recent=%+v, want failed user:1", recent)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	var g singleflight.Group[string, int]
+	g.Do("user:1", func() (int, error) { return 1, nil })
+
+	h := NewHandler(map[string]Inspector{"users": &g})
+	d := h.Dashboard()
+	d.Top = 5
+
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("Content-Type=%q, want text/html", ct)
+	}
+	if !strings.Contains(string(body), "const top =  5 ;") {
+		t.Fatalf("page does not configure top keys:\n%s", body)
+	}
+
+	snap := get(t, srv.URL+"?format=json")
+	if len(snap.Groups) != 1 || snap.Groups[0].Stats == nil || snap.Groups[0].Stats.Calls != 1 {
+		t.Fatalf("groups=%+v, want users with 1 call", snap.Groups)
+	}
+}
@@ -0,0 +1,27 @@
+package sfdist
+
+import "encoding/json"
+
+// Codec encodes the values shared across processes, see WithCodec. Any
+// encoding with the signatures of encoding/json fits, e.g. protobuf or
+// msgpack via a small adapter.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSON is the Codec encoding values as JSON, the default.
+var JSON Codec = jsonCodec{}
+
+// jsonCodec is the Codec of package encoding/json.
+type jsonCodec struct{}
+
+// Marshal implements Codec.
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
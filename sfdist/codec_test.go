@@ -0,0 +1,79 @@
+package sfdist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// user is a value shared across processes.
+type user struct {
+	Name   string
+	Groups map[int]string
+	TTL    time.Duration
+}
+
+// prefixCodec is a Codec encoding values as JSON behind a version prefix,
+// which it refuses to decode values without.
+type prefixCodec struct{}
+
+func (prefixCodec) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+
+	return append([]byte("v1:"), data...), err
+}
+
+func (prefixCodec) Unmarshal(data []byte, v any) error {
+	data, ok := bytes.CutPrefix(data, []byte("v1:"))
+	if !ok {
+		return errors.New("missing version prefix")
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func TestCodecs(t *testing.T) {
+	want := user{Name: "alice", Groups: map[int]string{1: "admin"}, TTL: time.Minute}
+
+	for name, codec := range map[string]Codec{"json": JSON, "prefix": prefixCodec{}} {
+		data, err := encodeResult(codec, want, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := decodeResult[user](codec, data)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %+v, %v, want %+v", name, got, err, want)
+		}
+
+		data, _ = encodeResult(codec, user{}, errors.New("not found"))
+		_, err = decodeResult[user](codec, data)
+		if !errors.Is(err, ErrRemote) || err.Error() != "not found" {
+			t.Fatalf("%s: err=%v, want remote not found", name, err)
+		}
+	}
+}
+
+func TestGroupCodec(t *testing.T) {
+	groups := make([]*Group[user], 2)
+	backend := newMemoryBackend()
+	for i := range groups {
+		groups[i] = New[user](backend, WithCodec(prefixCodec{}))
+	}
+
+	release := make(chan struct{})
+	go groups[0].Do("alice", func() (user, error) {
+		<-release
+		return user{Name: "alice"}, nil
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	ch := groups[1].DoChan("alice", func() (user, error) { return user{}, errors.New("executed") })
+	close(release)
+
+	if res := <-ch; res.Val.Name != "alice" || res.Err != nil || !res.Shared {
+		t.Fatalf("got %+v, want alice from the other process", res)
+	}
+}
@@ -0,0 +1,240 @@
+package sfdist
+
+import (
+	"context"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// Defaults of the configuration of a Group.
+const (
+	// DefaultPrefix is the prefix of the keys on the backend.
+	DefaultPrefix = "singleflight:"
+	// DefaultClaimTTL is the time a leader may execute the function before
+	// its claim expires and another process takes over.
+	DefaultClaimTTL = 30 * time.Second
+	// DefaultResultTTL is the time a published result is kept, so callers
+	// arriving shortly after the flight completed receive it.
+	DefaultResultTTL = time.Second
+)
+
+// config configures a Group.
+type config struct {
+	prefix    string
+	claimTTL  time.Duration
+	resultTTL time.Duration
+	codec     Codec
+	groupOpts []singleflight.GroupConfigOption
+}
+
+// Option configures a Group.
+type Option = func(*config)
+
+// WithPrefix returns an Option that sets the prefix of the keys on the
+// backend, e.g. to separate groups sharing a backend. It defaults to
+// DefaultPrefix.
+func WithPrefix(prefix string) Option {
+	return func(config *config) {
+		config.prefix = prefix
+	}
+}
+
+// WithClaimTTL returns an Option that sets the time a leader may execute
+// the function before its claim expires and another process takes over.
+// Set it above the longest expected execution. It defaults to
+// DefaultClaimTTL.
+func WithClaimTTL(ttl time.Duration) Option {
+	return func(config *config) {
+		config.claimTTL = ttl
+	}
+}
+
+// WithResultTTL returns an Option that sets the time a published result is
+// kept, so callers arriving shortly after the flight completed receive it
+// instead of starting a new one. It defaults to DefaultResultTTL.
+func WithResultTTL(ttl time.Duration) Option {
+	return func(config *config) {
+		config.resultTTL = ttl
+	}
+}
+
+// WithCodec returns an Option that sets the codec of the values shared
+// across processes. It defaults to JSON.
+func WithCodec(codec Codec) Option {
+	return func(config *config) {
+		config.codec = codec
+	}
+}
+
+// WithGroupOptions returns an Option that configures the local group
+// callers within a process are deduplicated by, e.g. with
+// singleflight.WithName.
+func WithGroupOptions(opts ...singleflight.GroupConfigOption) Option {
+	return func(config *config) {
+		config.groupOpts = append(config.groupOpts, opts...)
+	}
+}
+
+// outcome is the value of a flight of the local group, recording whether
+// it was executed by another process.
+type outcome[V any] struct {
+	val    V
+	remote bool
+}
+
+// Group deduplicates executions across the processes sharing a Backend.
+// Callers within a process share one flight of the local group; its leader
+// claims the key on the backend and executes the function if it succeeds,
+// or waits for the result published by the process holding the claim.
+// Errors of other processes are delivered as RemoteError.
+//
+// Coordination is best effort: if the backend fails, or a result cannot be
+// encoded or decoded, the function is executed locally. Group implements
+// singleflight.Singleflighter.
+type Group[V any] struct {
+	backend Backend
+	local   *singleflight.Group[string, outcome[V]]
+	cfg     config
+}
+
+// New returns a Group coordinating executions via backend, configured by
+// opts.
+func New[V any](backend Backend, opts ...Option) *Group[V] {
+	cfg := config{
+		prefix:    DefaultPrefix,
+		claimTTL:  DefaultClaimTTL,
+		resultTTL: DefaultResultTTL,
+		codec:     JSON,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Group[V]{
+		backend: backend,
+		local:   singleflight.NewGroup[string, outcome[V]](cfg.groupOpts...),
+		cfg:     cfg,
+	}
+}
+
+// Do executes and returns the results of fn, making sure that only one
+// execution is in-flight for a given key across processes at a time.
+// shared reports whether v was given to multiple callers, or executed by
+// another process.
+func (g *Group[V]) Do(key string, fn func() (V, error)) (v V, err error, shared bool) {
+	out, err, shared := g.local.Do(key, func() (outcome[V], error) {
+		return g.execute(context.Background(), key, func(context.Context) (V, error) {
+			return fn()
+		})
+	})
+
+	return out.val, err, shared || out.remote
+}
+
+// DoChan is like Do but returns a channel that will receive the results
+// when they are ready.
+func (g *Group[V]) DoChan(key string, fn func() (V, error)) <-chan singleflight.Result[V] {
+	ch := make(chan singleflight.Result[V], 1)
+
+	go func() {
+		v, err, shared := g.Do(key, fn)
+		ch <- singleflight.Result[V]{Val: v, Err: err, Shared: shared}
+	}()
+
+	return ch
+}
+
+// DoContextFunc is like Do, but stops waiting once ctx is done, returning
+// ctx.Err(). fn receives a context that is canceled once every caller of
+// the process has gone away, which stops waiting on the backend as well,
+// see singleflight.Group.DoContextFunc.
+func (g *Group[V]) DoContextFunc(
+	ctx context.Context, key string, fn func(ctx context.Context) (V, error),
+) (v V, err error, shared bool) {
+	out, err, shared := g.local.DoContextFunc(ctx, key, func(ctx context.Context) (outcome[V], error) {
+		return g.execute(ctx, key, fn)
+	})
+
+	return out.val, err, shared || out.remote
+}
+
+// Forget forgets the local flight of key, see singleflight.Group.Forget.
+// Flights of other processes are not affected.
+func (g *Group[V]) Forget(key string) bool {
+	return g.local.Forget(key)
+}
+
+// Stats returns the statistics of the local group, see
+// singleflight.Group.Stats.
+func (g *Group[V]) Stats() singleflight.GroupStats {
+	return g.local.Stats()
+}
+
+// execute executes fn for key once across processes, or receives the
+// result of the process that does.
+func (g *Group[V]) execute(
+	ctx context.Context, key string, fn func(context.Context) (V, error),
+) (outcome[V], error) {
+	bk := g.cfg.prefix + key
+
+	for {
+		claimed, err := g.backend.Claim(ctx, bk, g.cfg.claimTTL)
+		if err != nil {
+			return g.run(ctx, fn)
+		}
+		if claimed {
+			return g.lead(ctx, bk, fn)
+		}
+
+		result, ok, err := g.backend.Await(ctx, bk)
+		if ctx.Err() != nil {
+			return outcome[V]{}, ctx.Err()
+		}
+		if err != nil {
+			return g.run(ctx, fn)
+		}
+		if !ok {
+			// The claim was released or expired, take over.
+			continue
+		}
+
+		v, err := decodeResult[V](g.cfg.codec, result)
+		if _, remote := err.(*RemoteError); err != nil && !remote { //nolint:errorlint
+			return g.run(ctx, fn)
+		}
+
+		return outcome[V]{val: v, remote: true}, err
+	}
+}
+
+// lead executes fn for the claimed key bk and publishes its result.
+func (g *Group[V]) lead(ctx context.Context, bk string, fn func(context.Context) (V, error)) (outcome[V], error) {
+	published := false
+	defer func() {
+		if !published {
+			// fn panicked, was canceled or the result could not be
+			// encoded.
+			g.backend.Release(context.WithoutCancel(ctx), bk) //nolint:errcheck
+		}
+	}()
+
+	v, err := fn(ctx)
+	if ctx.Err() != nil {
+		// Every caller of the process has gone away, callers on other
+		// processes must not receive the cancellation.
+		return outcome[V]{val: v}, err
+	}
+
+	if result, encErr := encodeResult(g.cfg.codec, v, err); encErr == nil {
+		published = g.backend.Publish(context.WithoutCancel(ctx), bk, result, g.cfg.resultTTL) == nil
+	}
+
+	return outcome[V]{val: v}, err
+}
+
+// run executes fn locally, without coordination.
+func (g *Group[V]) run(ctx context.Context, fn func(context.Context) (V, error)) (outcome[V], error) {
+	v, err := fn(ctx)
+	return outcome[V]{val: v}, err
+}
@@ -0,0 +1,213 @@
+package sfdist
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+var _ singleflight.Singleflighter[string, int] = (*Group[int])(nil)
+
+// memoryBackend is a Backend shared by the groups of a test, standing in
+// for the processes of a fleet.
+type memoryBackend struct {
+	mu      sync.Mutex
+	keys    map[string]*memoryKey
+	changed chan struct{}
+}
+
+// memoryKey is the state of a key of a memoryBackend.
+type memoryKey struct {
+	result  []byte
+	expires time.Time
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{keys: make(map[string]*memoryKey), changed: make(chan struct{})}
+}
+
+// lookup returns the live state of key. The caller must hold b.mu.
+func (b *memoryBackend) lookup(key string) (*memoryKey, bool) {
+	k, ok := b.keys[key]
+	if ok && time.Now().After(k.expires) {
+		delete(b.keys, key)
+		return nil, false
+	}
+
+	return k, ok
+}
+
+// notify wakes the waiters. The caller must hold b.mu.
+func (b *memoryBackend) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *memoryBackend) Claim(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.lookup(key); ok {
+		return false, nil
+	}
+	b.keys[key] = &memoryKey{expires: time.Now().Add(ttl)}
+
+	return true, nil
+}
+
+func (b *memoryBackend) Publish(_ context.Context, key string, result []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.keys[key] = &memoryKey{result: result, expires: time.Now().Add(ttl)}
+	b.notify()
+
+	return nil
+}
+
+func (b *memoryBackend) Release(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.keys, key)
+	b.notify()
+
+	return nil
+}
+
+func (b *memoryBackend) Await(ctx context.Context, key string) ([]byte, bool, error) {
+	for {
+		b.mu.Lock()
+		k, ok := b.lookup(key)
+		changed := b.changed
+		b.mu.Unlock()
+
+		switch {
+		case !ok:
+			return nil, false, nil
+		case k.result != nil:
+			return k.result, true, nil
+		}
+
+		select {
+		case <-changed:
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// processes returns n groups sharing backend, standing in for n processes.
+func processes(n int, backend Backend, opts ...Option) []*Group[int] {
+	groups := make([]*Group[int], n)
+	for i := range groups {
+		groups[i] = New[int](backend, opts...)
+	}
+
+	return groups
+}
+
+func TestGroup(t *testing.T) {
+	groups := processes(3, newMemoryBackend())
+
+	var executions atomic.Int32
+	fn := func() (int, error) {
+		executions.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	for i := range 9 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, s := groups[i%len(groups)].Do("answer", fn)
+			if v != 42 || err != nil {
+				t.Errorf("got %d, %v, want 42", v, err)
+			}
+			if s {
+				shared.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions=%d, want 1 across processes", got)
+	}
+	if got := shared.Load(); got != 9 {
+		t.Fatalf("shared=%d, want 9", got)
+	}
+}
+
+func TestGroupRemoteErrors(t *testing.T) {
+	groups := processes(2, newMemoryBackend())
+
+	errFn := errors.New("backend down")
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err, _ := groups[0].Do("answer", func() (int, error) {
+			<-release
+			return 0, errFn
+		})
+		if !errors.Is(err, errFn) {
+			t.Errorf("leader err=%v, want %v", err, errFn)
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	ch := groups[1].DoChan("answer", func() (int, error) {
+		t.Error("executed on waiting process")
+		return 0, nil
+	})
+	close(release)
+	<-done
+
+	res := <-ch
+	var remote *RemoteError
+	if !errors.As(res.Err, &remote) || !errors.Is(res.Err, ErrRemote) || remote.Message != errFn.Error() {
+		t.Fatalf("err=%v, want remote %v", res.Err, errFn)
+	}
+}
+
+func TestGroupTakesOver(t *testing.T) {
+	groups := processes(2, newMemoryBackend(), WithClaimTTL(time.Minute))
+
+	started := make(chan struct{})
+	go func() {
+		defer func() { recover() }() //nolint:errcheck
+		groups[0].Do("answer", func() (int, error) {
+			close(started)
+			time.Sleep(10 * time.Millisecond)
+			panic("boom")
+		})
+	}()
+
+	<-started
+	v, err, _ := groups[1].Do("answer", func() (int, error) { return 42, nil })
+	if v != 42 || err != nil {
+		t.Fatalf("got %d, %v, want 42 after the leader released its claim", v, err)
+	}
+}
+
+func TestGroupDoContextFunc(t *testing.T) {
+	groups := processes(1, newMemoryBackend())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err, _ := groups[0].DoContextFunc(ctx, "answer", func(ctx context.Context) (int, error) {
+		return 42, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,98 @@
+// Package sfdist deduplicates executions across processes: a Backend, such
+// as Redis (see package sfredis), elects one process per key to execute
+// the function, and shares its encoded result with the callers waiting on
+// other processes. Callers within a process are deduplicated by a local
+// singleflight.Group first, so every process waits on the backend once per
+// key.
+package sfdist
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Backend coordinates the flights of a key across processes. A key is in
+// one of three states: free, claimed by a leader executing the function, or
+// holding the published result of the last flight until it expires.
+type Backend interface {
+	// Claim claims the free key for ttl and reports whether it did. It
+	// reports false if the key is claimed or holds a result.
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Publish stores the result of the claimed key for ttl, ending the
+	// claim, and hands it to the callers waiting for it. It returns
+	// ErrClaimLost, leaving the key alone, if the claim expired in the
+	// meantime.
+	Publish(ctx context.Context, key string, result []byte, ttl time.Duration) error
+	// Release frees the claimed key without a result, e.g. because the
+	// function panicked, so waiting callers retry. It returns ErrClaimLost,
+	// leaving the key alone, if the claim expired in the meantime.
+	Release(ctx context.Context, key string) error
+	// Await waits for the result of the key. ok is false if the key is or
+	// becomes free without a result, i.e. its claim was released or
+	// expired, in which case the caller retries to claim it.
+	Await(ctx context.Context, key string) (result []byte, ok bool, err error)
+}
+
+// ErrClaimLost is returned by a Backend publishing or releasing a key whose
+// claim expired, so the key may be claimed by another process.
+var ErrClaimLost = errors.New("sfdist: claim lost")
+
+// ErrRemote is the sentinel error of errors of executions on other
+// processes, see RemoteError.
+var ErrRemote = errors.New("sfdist: remote execution failed")
+
+// RemoteError is the error of an execution on another process, of which
+// only the message is shared. It unwraps to ErrRemote.
+type RemoteError struct {
+	// Message is the message of the error.
+	Message string
+}
+
+// Error implements error.
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns ErrRemote.
+func (e *RemoteError) Unwrap() error {
+	return ErrRemote
+}
+
+// Result kinds of an encoded result, see encodeResult.
+const (
+	resultValue byte = iota
+	resultError
+)
+
+// encodeResult encodes the value or error of an execution as published via
+// a Backend.
+func encodeResult[V any](codec Codec, v V, err error) ([]byte, error) {
+	if err != nil {
+		return append([]byte{resultError}, err.Error()...), nil
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{resultValue}, data...), nil
+}
+
+// decodeResult decodes a result encoded by encodeResult.
+func decodeResult[V any](codec Codec, result []byte) (v V, err error) {
+	if len(result) == 0 {
+		return v, errors.New("sfdist: empty result")
+	}
+
+	switch result[0] {
+	case resultValue:
+		err = codec.Unmarshal(result[1:], &v)
+		return v, err
+	case resultError:
+		return v, &RemoteError{Message: string(result[1:])}
+	default:
+		return v, errors.New("sfdist: unknown result kind")
+	}
+}
@@ -0,0 +1,305 @@
+package sfetcd
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeEtcd is an in-memory etcd server implementing the subset of the KV,
+// lease and watch APIs used by a Backend: single-key ranges, puts, and
+// transactions comparing create revisions, leases expiring and revoking
+// their keys, and watches of single keys from a revision on.
+type fakeEtcd struct {
+	pb.UnimplementedKVServer
+	pb.UnimplementedLeaseServer
+	pb.UnimplementedWatchServer
+
+	mu      sync.Mutex
+	rev     int64
+	kvs     map[string]*mvccpb.KeyValue
+	events  []*mvccpb.Event
+	changed chan struct{} // closed and replaced on every event
+	leases  map[int64]*fakeLease
+	leaseID int64
+}
+
+// fakeLease is a lease of a fakeEtcd.
+type fakeLease struct {
+	keys  map[string]struct{}
+	timer *time.Timer
+}
+
+// newFakeClient serves a fakeEtcd for the test and returns a client of it.
+func newFakeClient(t *testing.T) *clientv3.Client {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	etcd := &fakeEtcd{
+		kvs:     make(map[string]*mvccpb.KeyValue),
+		changed: make(chan struct{}),
+		leases:  make(map[int64]*fakeLease),
+	}
+	srv := grpc.NewServer()
+	pb.RegisterKVServer(srv, etcd)
+	pb.RegisterLeaseServer(srv, etcd)
+	pb.RegisterWatchServer(srv, etcd)
+	go srv.Serve(l) //nolint:errcheck
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{l.Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		srv.Stop()
+	})
+
+	return client
+}
+
+// header returns the response header at the current revision. The caller
+// must hold e.mu.
+func (e *fakeEtcd) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{Revision: e.rev}
+}
+
+// emit records ev at the current revision and wakes up the watches. The
+// caller must hold e.mu.
+func (e *fakeEtcd) emit(ev *mvccpb.Event) {
+	e.events = append(e.events, ev)
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// put puts value at key, attached to lease unless ignoreLease is set. The
+// caller must hold e.mu.
+func (e *fakeEtcd) put(key, value []byte, lease int64, ignoreLease bool) error {
+	prev, exists := e.kvs[string(key)]
+	if ignoreLease {
+		lease = 0
+		if exists {
+			lease = prev.Lease
+		}
+	}
+	if _, ok := e.leases[lease]; lease != 0 && !ok {
+		return status.Error(codes.NotFound, "etcdserver: requested lease not found")
+	}
+
+	e.rev++
+	kv := &mvccpb.KeyValue{Key: key, Value: value, Lease: lease, CreateRevision: e.rev, ModRevision: e.rev, Version: 1}
+	if exists {
+		kv.CreateRevision, kv.Version = prev.CreateRevision, prev.Version+1
+		if l, ok := e.leases[prev.Lease]; ok {
+			delete(l.keys, string(key))
+		}
+	}
+	if l, ok := e.leases[lease]; ok {
+		l.keys[string(key)] = struct{}{}
+	}
+	e.kvs[string(key)] = kv
+	e.emit(&mvccpb.Event{Type: mvccpb.PUT, Kv: kv})
+
+	return nil
+}
+
+// revoke revokes lease, deleting its keys. The caller must hold e.mu.
+func (e *fakeEtcd) revoke(lease int64) bool {
+	l, ok := e.leases[lease]
+	if !ok {
+		return false
+	}
+
+	l.timer.Stop()
+	delete(e.leases, lease)
+	for key := range l.keys {
+		e.rev++
+		delete(e.kvs, key)
+		e.emit(&mvccpb.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(key), ModRevision: e.rev}})
+	}
+
+	return true
+}
+
+func (e *fakeEtcd) Range(_ context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	resp := &pb.RangeResponse{Header: e.header()}
+	if kv, ok := e.kvs[string(req.Key)]; ok {
+		resp.Kvs, resp.Count = []*mvccpb.KeyValue{kv}, 1
+	}
+
+	return resp, nil
+}
+
+func (e *fakeEtcd) Put(_ context.Context, req *pb.PutRequest) (*pb.PutResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.put(req.Key, req.Value, req.Lease, req.IgnoreLease); err != nil {
+		return nil, err
+	}
+
+	return &pb.PutResponse{Header: e.header()}, nil
+}
+
+func (e *fakeEtcd) Txn(_ context.Context, req *pb.TxnRequest) (*pb.TxnResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	succeeded := true
+	for _, cmp := range req.Compare {
+		if cmp.Target != pb.Compare_CREATE || cmp.Result != pb.Compare_EQUAL {
+			return nil, status.Error(codes.Unimplemented, "fake etcd: unsupported comparison")
+		}
+		var created int64
+		if kv, ok := e.kvs[string(cmp.Key)]; ok {
+			created = kv.CreateRevision
+		}
+		succeeded = succeeded && created == cmp.GetCreateRevision()
+	}
+
+	ops := req.Failure
+	if succeeded {
+		ops = req.Success
+	}
+	for _, op := range ops {
+		put := op.GetRequestPut()
+		if put == nil {
+			return nil, status.Error(codes.Unimplemented, "fake etcd: unsupported operation")
+		}
+		if err := e.put(put.Key, put.Value, put.Lease, put.IgnoreLease); err != nil {
+			return nil, err
+		}
+	}
+
+	return &pb.TxnResponse{Header: e.header(), Succeeded: succeeded}, nil
+}
+
+func (e *fakeEtcd) LeaseGrant(_ context.Context, req *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.leaseID++
+	id := e.leaseID
+	e.leases[id] = &fakeLease{
+		keys: make(map[string]struct{}),
+		timer: time.AfterFunc(time.Duration(req.TTL)*time.Second, func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.revoke(id)
+		}),
+	}
+
+	return &pb.LeaseGrantResponse{Header: e.header(), ID: id, TTL: req.TTL}, nil
+}
+
+func (e *fakeEtcd) LeaseRevoke(_ context.Context, req *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.revoke(req.ID) {
+		return nil, status.Error(codes.NotFound, "etcdserver: requested lease not found")
+	}
+
+	return &pb.LeaseRevokeResponse{Header: e.header()}, nil
+}
+
+func (e *fakeEtcd) Watch(stream pb.Watch_WatchServer) error {
+	var (
+		sendMu sync.Mutex
+		wg     sync.WaitGroup
+		nextID int64
+	)
+	send := func(resp *pb.WatchResponse) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+
+		return stream.Send(resp)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer func() { cancel(); wg.Wait() }()
+
+	cancels := make(map[int64]context.CancelFunc)
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+
+		if create := req.GetCreateRequest(); create != nil {
+			id := nextID
+			nextID++
+			wctx, wcancel := context.WithCancel(ctx)
+			cancels[id] = wcancel
+
+			e.mu.Lock()
+			header := e.header()
+			e.mu.Unlock()
+			if err := send(&pb.WatchResponse{Header: header, WatchId: id, Created: true}); err != nil {
+				return err
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e.watch(wctx, id, create.Key, create.StartRevision, send)
+			}()
+		}
+		if c := req.GetCancelRequest(); c != nil {
+			if wcancel, ok := cancels[c.WatchId]; ok {
+				wcancel()
+				delete(cancels, c.WatchId)
+			}
+		}
+	}
+}
+
+// watch sends the events of key from revision rev on as the watch id until
+// ctx is done.
+func (e *fakeEtcd) watch(
+	ctx context.Context, id int64, key []byte, rev int64, send func(*pb.WatchResponse) error,
+) {
+	next := 0
+	for {
+		e.mu.Lock()
+		var events []*mvccpb.Event
+		for ; next < len(e.events); next++ {
+			if ev := e.events[next]; string(ev.Kv.Key) == string(key) && ev.Kv.ModRevision >= rev {
+				events = append(events, ev)
+			}
+		}
+		header, changed := e.header(), e.changed
+		e.mu.Unlock()
+
+		if len(events) > 0 {
+			if send(&pb.WatchResponse{Header: header, WatchId: id, Events: events}) != nil {
+				return
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
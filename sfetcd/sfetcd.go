@@ -0,0 +1,161 @@
+// Package sfetcd implements an sfdist.Backend on etcd, deduplicating
+// executions across the processes sharing an etcd cluster.
+//
+// A key is claimed by creating it attached to a lease of the claim TTL, so
+// the claim ends with the leader's lease. The leader replaces the claim by
+// the result, attached to a lease of the result TTL, and the waiting
+// processes receive it by watching the key.
+package sfetcd
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// Markers prefixing the values of a key.
+const (
+	pending = "p"
+	result  = "r"
+)
+
+// Backend is an sfdist.Backend on etcd.
+type Backend struct {
+	client *clientv3.Client
+
+	mu     sync.Mutex
+	claims map[string]clientv3.LeaseID
+}
+
+var _ sfdist.Backend = (*Backend)(nil)
+
+// New returns a Backend on client.
+func New(client *clientv3.Client) *Backend {
+	return &Backend{client: client, claims: make(map[string]clientv3.LeaseID)}
+}
+
+// Claim implements sfdist.Backend.
+func (b *Backend) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	lease, err := b.client.Grant(ctx, leaseSeconds(ttl))
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := b.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, pending, clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil || !resp.Succeeded {
+		b.client.Revoke(context.WithoutCancel(ctx), lease.ID) //nolint:errcheck
+		return false, err
+	}
+
+	b.mu.Lock()
+	b.claims[key] = lease.ID
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// Publish implements sfdist.Backend.
+func (b *Backend) Publish(ctx context.Context, key string, res []byte, ttl time.Duration) error {
+	claim := b.claim(key)
+	if ttl <= 0 {
+		// Waiters watching the key receive the result as it is put, before
+		// the claim is revoked and the key deleted.
+		if _, err := b.client.Put(ctx, key, result+string(res), clientv3.WithIgnoreLease()); err != nil {
+			return err
+		}
+		return b.revoke(ctx, claim)
+	}
+
+	lease, err := b.client.Grant(ctx, leaseSeconds(ttl))
+	if err != nil {
+		return err
+	}
+	if _, err := b.client.Put(ctx, key, result+string(res), clientv3.WithLease(lease.ID)); err != nil {
+		return err
+	}
+
+	return b.revoke(ctx, claim)
+}
+
+// Release implements sfdist.Backend.
+func (b *Backend) Release(ctx context.Context, key string) error {
+	return b.revoke(ctx, b.claim(key))
+}
+
+// Await implements sfdist.Backend.
+func (b *Backend) Await(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := b.client.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, false, nil
+	}
+	if res, ok := parse(resp.Kvs[0].Value); ok {
+		return res, true, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for wresp := range b.client.Watch(ctx, key, clientv3.WithRev(resp.Header.Revision+1)) {
+		if err := wresp.Err(); err != nil {
+			return nil, false, err
+		}
+		for _, ev := range wresp.Events {
+			if ev.Type == clientv3.EventTypeDelete {
+				return nil, false, nil
+			}
+			if res, ok := parse(ev.Kv.Value); ok {
+				return res, true, nil
+			}
+		}
+	}
+
+	return nil, false, ctx.Err()
+}
+
+// claim returns and forgets the lease of the claim of key.
+func (b *Backend) claim(key string) clientv3.LeaseID {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lease := b.claims[key]
+	delete(b.claims, key)
+
+	return lease
+}
+
+// revoke revokes lease, if any.
+func (b *Backend) revoke(ctx context.Context, lease clientv3.LeaseID) error {
+	if lease == clientv3.NoLease {
+		return nil
+	}
+
+	_, err := b.client.Revoke(ctx, lease)
+
+	return err
+}
+
+// parse returns the result held by the value, if any.
+func parse(value []byte) ([]byte, bool) {
+	if len(value) == 0 || string(value[:1]) != result {
+		return nil, false
+	}
+
+	return value[1:], true
+}
+
+// leaseSeconds returns ttl as the TTL of a lease, rounded up to full
+// seconds.
+func leaseSeconds(ttl time.Duration) int64 {
+	return max(int64(math.Ceil(ttl.Seconds())), 1)
+}
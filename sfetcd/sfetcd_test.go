@@ -0,0 +1,133 @@
+package sfetcd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// newClient returns a client of the etcd cluster at the comma-separated
+// endpoints in SFETCD_ENDPOINTS, or of a fakeEtcd if it is not set.
+func newClient(t *testing.T) *clientv3.Client {
+	t.Helper()
+
+	endpoints := os.Getenv("SFETCD_ENDPOINTS")
+	if endpoints == "" {
+		return newFakeClient(t)
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(endpoints, ","),
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestBackend(t *testing.T) {
+	client := newClient(t)
+	prefix := sfdist.WithPrefix("sfetcd-test/" + t.Name() + "/" + time.Now().Format(time.RFC3339Nano) + "/")
+
+	var executions atomic.Int32
+	fn := func() (int, error) {
+		executions.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		g := sfdist.New[int](New(client), prefix)
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err, _ := g.Do("answer", fn); v != 42 || err != nil {
+					t.Errorf("got %d, %v, want 42", v, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions=%d, want 1 across processes", got)
+	}
+}
+
+func TestBackendAwait(t *testing.T) {
+	b := New(newClient(t))
+	ctx := context.Background()
+	key := "sfetcd-test/" + t.Name() + "/" + time.Now().Format(time.RFC3339Nano)
+
+	if _, ok, err := b.Await(ctx, key); ok || err != nil {
+		t.Fatalf("Await of free key=%t, %v, want not ok", ok, err)
+	}
+
+	if claimed, err := b.Claim(ctx, key, time.Minute); !claimed || err != nil {
+		t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+	}
+	if claimed, err := b.Claim(ctx, key, time.Minute); claimed || err != nil {
+		t.Fatalf("Claim of claimed key=%t, %v, want not claimed", claimed, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Release(ctx, key)
+	}()
+	if _, ok, err := b.Await(ctx, key); ok || err != nil {
+		t.Fatalf("Await of released key=%t, %v, want not ok", ok, err)
+	}
+
+	b.Claim(ctx, key, time.Minute)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Publish(ctx, key, []byte("result"), time.Minute)
+	}()
+	if res, ok, err := b.Await(ctx, key); string(res) != "result" || !ok || err != nil {
+		t.Fatalf("Await=%q, %t, %v, want result", res, ok, err)
+	}
+	if res, ok, _ := b.Await(ctx, key); string(res) != "result" || !ok {
+		t.Fatalf("Await of published key=%q, %t, want result", res, ok)
+	}
+}
+
+func TestBackendClaimExpiry(t *testing.T) {
+	b := New(newClient(t))
+	ctx := context.Background()
+	key := "sfetcd-test/" + t.Name() + "/" + time.Now().Format(time.RFC3339Nano)
+
+	// the claim ends with its lease, rounded up to a second
+	b.Claim(ctx, key, time.Millisecond)
+	if _, ok, err := b.Await(ctx, key); ok || err != nil {
+		t.Fatalf("Await of expired claim=%t, %v, want not ok", ok, err)
+	}
+	if claimed, _ := b.Claim(ctx, key, time.Minute); !claimed {
+		t.Fatal("expired claim not claimable")
+	}
+}
+
+func TestLeaseSeconds(t *testing.T) {
+	for ttl, want := range map[time.Duration]int64{
+		0:                       1,
+		500 * time.Millisecond:  1,
+		time.Second:             1,
+		1500 * time.Millisecond: 2,
+	} {
+		if got := leaseSeconds(ttl); got != want {
+			t.Errorf("leaseSeconds(%v)=%d, want %d", ttl, got, want)
+		}
+	}
+}
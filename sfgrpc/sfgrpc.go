@@ -0,0 +1,148 @@
+// Package sfgrpc deduplicates gRPC calls with singleflight groups.
+package sfgrpc
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// DefaultVaryMetadata are the outgoing metadata keys that distinguish
+// otherwise identical calls, unless configured otherwise via
+// WithVaryMetadata, so calls with different credentials never share a
+// response.
+var DefaultVaryMetadata = []string{"authorization"}
+
+// config configures an Interceptor.
+type config struct {
+	vary      []string
+	groupOpts []singleflight.GroupConfigOption
+}
+
+// Option configures an Interceptor.
+type Option = func(*config)
+
+// WithVaryMetadata returns an Option that sets the outgoing metadata keys
+// that distinguish otherwise identical calls, in addition to their method
+// and request. They default to DefaultVaryMetadata.
+func WithVaryMetadata(keys ...string) Option {
+	return func(config *config) {
+		config.vary = keys
+	}
+}
+
+// WithGroupOptions returns an Option that configures the group calls are
+// coalesced through, e.g. with singleflight.WithName.
+func WithGroupOptions(opts ...singleflight.GroupConfigOption) Option {
+	return func(config *config) {
+		config.groupOpts = append(config.groupOpts, opts...)
+	}
+}
+
+// Interceptor coalesces concurrent identical unary calls of idempotent
+// methods into one outbound call: while a call is in flight, identical
+// calls wait for its response instead of being sent, and every caller
+// receives a copy of it.
+//
+// Calls are identical if they have the same method, deterministically
+// marshaled request and values of the vary metadata (see
+// WithVaryMetadata). The outbound call is made with the call options of
+// the caller that started it, so options receiving data, such as
+// grpc.Header, are only filled for that caller. It is canceled once the
+// contexts of every caller are done.
+type Interceptor struct {
+	methods []string
+	group   *singleflight.Group[string, proto.Message]
+	cfg     config
+}
+
+// NewInterceptor returns an Interceptor coalescing calls of the full
+// methods in methods, e.g. "/users.v1.Users/GetUser", configured by opts.
+// Calls of other methods are passed through.
+func NewInterceptor(methods []string, opts ...Option) *Interceptor {
+	cfg := config{vary: DefaultVaryMetadata}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Interceptor{
+		methods: methods,
+		group:   singleflight.NewGroup[string, proto.Message](cfg.groupOpts...),
+		cfg:     cfg,
+	}
+}
+
+// Unary is a grpc.UnaryClientInterceptor, installed e.g. via
+// grpc.WithUnaryInterceptor.
+func (i *Interceptor) Unary(
+	ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+) error {
+	in, ok := req.(proto.Message)
+	out, okReply := reply.(proto.Message)
+	if !ok || !okReply || !slices.Contains(i.methods, method) {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	key, err := i.key(ctx, method, in)
+	if err != nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	res, err, _ := i.group.DoContextFunc(ctx, key, func(ctx context.Context) (proto.Message, error) {
+		res := out.ProtoReflect().New().Interface()
+		if err := invoker(ctx, method, req, res, cc, opts...); err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	proto.Reset(out)
+	proto.Merge(out, res)
+
+	return nil
+}
+
+// key returns the key identifying the call of method with req among
+// concurrent calls.
+func (i *Interceptor) key(ctx context.Context, method string, req proto.Message) (string, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(method)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for _, name := range i.cfg.vary {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(md.Get(name), ", "))
+	}
+	b.WriteByte('\n')
+	b.Write(body)
+
+	return b.String(), nil
+}
+
+// Inflight returns the calls in flight, see singleflight.Group.Inflight.
+func (i *Interceptor) Inflight() []singleflight.FlightInfo {
+	return i.group.Inflight()
+}
+
+// Stats returns the statistics of the coalesced calls, see
+// singleflight.Group.Stats.
+func (i *Interceptor) Stats() singleflight.GroupStats {
+	return i.group.Stats()
+}
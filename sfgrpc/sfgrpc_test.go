@@ -0,0 +1,88 @@
+package sfgrpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const getMethod = "/users.v1.Users/GetUser"
+
+var _ grpc.UnaryClientInterceptor = (*Interceptor)(nil).Unary
+
+// countingInvoker returns an invoker counting its calls, replying with the
+// request after a delay.
+func countingInvoker() (grpc.UnaryInvoker, *atomic.Int32) {
+	var calls atomic.Int32
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		reply.(*wrapperspb.StringValue).Value = "user " + req.(*wrapperspb.StringValue).GetValue()
+		return nil
+	}, &calls
+}
+
+// unaryCalls makes n concurrent calls of method via i, returning their
+// replies.
+func unaryCalls(
+	t *testing.T, i *Interceptor, invoker grpc.UnaryInvoker, method string, n int, ctxOf func(int) context.Context,
+) []string {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	replies := make([]string, n)
+	for n := range replies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := &wrapperspb.StringValue{}
+			if err := i.Unary(ctxOf(n), method, wrapperspb.String("42"), reply, nil, invoker); err != nil {
+				t.Error(err)
+			}
+			replies[n] = reply.GetValue()
+		}()
+	}
+	wg.Wait()
+
+	return replies
+}
+
+func TestInterceptor(t *testing.T) {
+	i := NewInterceptor([]string{getMethod})
+	invoker, calls := countingInvoker()
+
+	replies := unaryCalls(t, i, invoker, getMethod, 5, func(int) context.Context { return context.Background() })
+	for _, reply := range replies {
+		if reply != "user 42" {
+			t.Fatalf("reply=%q, want user 42", reply)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+}
+
+func TestInterceptorPassesThrough(t *testing.T) {
+	i := NewInterceptor([]string{getMethod})
+	invoker, calls := countingInvoker()
+
+	unaryCalls(t, i, invoker, "/users.v1.Users/DeleteUser", 2, func(int) context.Context { return context.Background() })
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls of method not allowed=%d, want 2", got)
+	}
+
+	calls.Store(0)
+	unaryCalls(t, i, invoker, getMethod, 2, func(n int) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", []string{"alice", "bob"}[n])
+	})
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls with distinct credentials=%d, want 2", got)
+	}
+}
@@ -0,0 +1,127 @@
+package sfhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// Handler is an http.Handler middleware collapsing concurrent identical
+// requests into one execution of the next handler, whose captured response
+// is written to every one of them, like an origin shield without a CDN.
+//
+// Requests are identical if they have the same method, host, URL and values
+// of the vary headers (see WithVaryHeaders), or key (see WithKeyFunc). Only
+// requests without a body whose method is idempotent are collapsed (see
+// WithMethods); others are served by the next handler directly. The
+// response is buffered completely before it is written, so the next
+// handler cannot flush or hijack the connection.
+//
+// The execution is canceled once every waiting client has gone away.
+// Requests rejected by the group, e.g. if configured via WithGroupOptions
+// with singleflight.WithMaxWaiters, are answered with status 503. A
+// panic of the next handler is raised for every request it was shared
+// with, see http.Handler.
+type Handler struct {
+	next  http.Handler
+	group *singleflight.Group[string, *captured]
+	cfg   config
+}
+
+// NewHandler returns a Handler collapsing the requests of next, configured
+// by opts.
+func NewHandler(next http.Handler, opts ...Option) *Handler {
+	cfg := newConfig(opts)
+	groupOpts := append([]singleflight.GroupConfigOption{singleflight.WithRecoverPanics()}, cfg.groupOpts...)
+
+	return &Handler{
+		next:  next,
+		group: singleflight.NewGroup[string, *captured](groupOpts...),
+		cfg:   cfg,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !coalescable(r, h.cfg.methods) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	res, err, _ := h.group.DoContextFunc(r.Context(), h.cfg.key(r),
+		func(ctx context.Context) (*captured, error) {
+			c := &captured{header: make(http.Header)}
+			h.next.ServeHTTP(c, r.WithContext(ctx))
+
+			return c, nil
+		})
+
+	var perr *singleflight.PanicError
+	switch {
+	case errors.As(err, &perr):
+		if perr.Value == http.ErrAbortHandler {
+			panic(http.ErrAbortHandler)
+		}
+		panic(perr)
+	case err != nil && r.Context().Err() == nil:
+		// The group rejected the request, e.g. due to WithMaxWaiters.
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		// The client has gone away.
+		return
+	}
+
+	res.writeTo(w)
+}
+
+// Inflight returns the requests in flight, see singleflight.Group.Inflight.
+func (h *Handler) Inflight() []singleflight.FlightInfo {
+	return h.group.Inflight()
+}
+
+// Stats returns the statistics of the collapsed requests, see
+// singleflight.Group.Stats.
+func (h *Handler) Stats() singleflight.GroupStats {
+	return h.group.Stats()
+}
+
+// captured is a response captured from a handler, implementing
+// http.ResponseWriter.
+type captured struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter.
+func (c *captured) Header() http.Header {
+	return c.header
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (c *captured) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (c *captured) Write(p []byte) (int, error) {
+	c.WriteHeader(http.StatusOK)
+
+	return c.body.Write(p)
+}
+
+// writeTo writes the captured response to w.
+func (c *captured) writeTo(w http.ResponseWriter) {
+	header := w.Header()
+	for name, values := range c.header {
+		header[name] = append([]string(nil), values...)
+	}
+	w.WriteHeader(max(c.status, http.StatusOK))
+	w.Write(c.body.Bytes()) //nolint:errcheck
+}
@@ -0,0 +1,74 @@
+package sfhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingHandler returns a handler counting its requests, responding to
+// each after delay.
+func countingHandler(delay time.Duration) (http.Handler, *atomic.Int32) {
+	var hits atomic.Int32
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(delay)
+		w.Header().Set("X-Answer", "42")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "answer "+r.URL.Query().Get("q"))
+	}), &hits
+}
+
+func TestHandler(t *testing.T) {
+	next, hits := countingHandler(30 * time.Millisecond)
+	srv := httptest.NewServer(NewHandler(next))
+	t.Cleanup(srv.Close)
+
+	resps := roundTrips(t, srv.Client(), 5, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/answer?q=life", nil)
+		return req
+	})
+
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("hits=%d, want 1", got)
+	}
+	for _, resp := range resps {
+		if resp == nil {
+			t.FailNow()
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTeapot || resp.Header.Get("X-Answer") != "42" || string(body) != "answer life" {
+			t.Fatalf("got %d %v %q, want captured response", resp.StatusCode, resp.Header, body)
+		}
+	}
+
+	hits.Store(0)
+	roundTrips(t, srv.Client(), 2, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/answer", nil)
+		return req
+	})
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("DELETE hits=%d, want 2", got)
+	}
+}
+
+func TestHandlerKeyFunc(t *testing.T) {
+	next, hits := countingHandler(30 * time.Millisecond)
+	srv := httptest.NewServer(NewHandler(next, WithKeyFunc(func(r *http.Request) string {
+		return r.URL.Path
+	})))
+	t.Cleanup(srv.Close)
+
+	roundTrips(t, srv.Client(), 3, func(i int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/answer?q="+string(rune('a'+i)), nil)
+		return req
+	})
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("hits=%d, want 1 for requests with the same key", got)
+	}
+}
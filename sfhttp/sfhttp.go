@@ -0,0 +1,119 @@
+// Package sfhttp deduplicates HTTP traffic with singleflight groups:
+// Transport coalesces concurrent identical outgoing requests, Handler
+// concurrent identical incoming requests.
+package sfhttp
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// DefaultVaryHeaders are the request headers that distinguish otherwise
+// identical requests, unless configured otherwise via WithVaryHeaders, so
+// requests differing in credentials or content negotiation never share a
+// response.
+var DefaultVaryHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Authorization",
+	"Cookie",
+	"Range",
+}
+
+// DefaultMethods are the methods of requests that are coalesced, unless
+// configured otherwise via WithMethods.
+var DefaultMethods = []string{http.MethodGet, http.MethodHead}
+
+// config configures a Transport or Handler.
+type config struct {
+	vary      []string
+	methods   []string
+	key       func(r *http.Request) string
+	groupOpts []singleflight.GroupConfigOption
+}
+
+// Option configures a Transport or Handler.
+type Option = func(*config)
+
+// WithVaryHeaders returns an Option that sets the request headers that
+// distinguish otherwise identical requests, in addition to their method and
+// URL. They default to DefaultVaryHeaders.
+func WithVaryHeaders(headers ...string) Option {
+	return func(config *config) {
+		config.vary = headers
+	}
+}
+
+// WithKeyFunc returns an Option that sets the function deriving the key of
+// a request, replacing the default of its method, host, URL and vary
+// headers. Requests with the same key share a response, so the key must
+// capture everything the response depends on.
+func WithKeyFunc(key func(r *http.Request) string) Option {
+	return func(config *config) {
+		config.key = key
+	}
+}
+
+// WithMethods returns an Option that sets the methods of requests that are
+// coalesced. Requests with other methods are passed through. They default
+// to DefaultMethods; only add methods that are idempotent and safe.
+func WithMethods(methods ...string) Option {
+	return func(config *config) {
+		config.methods = methods
+	}
+}
+
+// WithGroupOptions returns an Option that configures the group requests
+// are coalesced through, e.g. with singleflight.WithName.
+func WithGroupOptions(opts ...singleflight.GroupConfigOption) Option {
+	return func(config *config) {
+		config.groupOpts = append(config.groupOpts, opts...)
+	}
+}
+
+// newConfig returns the configuration of opts.
+func newConfig(opts []Option) config {
+	cfg := config{vary: DefaultVaryHeaders, methods: DefaultMethods}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.key == nil {
+		vary := cfg.vary
+		cfg.key = func(r *http.Request) string {
+			return requestKey(r, vary)
+		}
+	}
+
+	return cfg
+}
+
+// requestKey returns the key identifying r among concurrent requests: its
+// method, host, URL and the values of the headers in vary.
+func requestKey(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	if r.URL.Host == "" {
+		// Incoming requests carry the host separately.
+		b.WriteString(r.Host)
+	}
+	b.WriteString(r.URL.String())
+	for _, name := range vary {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteString(": ")
+		b.WriteString(strings.Join(r.Header.Values(name), ", "))
+	}
+
+	return b.String()
+}
+
+// coalescable reports whether r may share a response with identical
+// requests: its method is one of methods and it has no body.
+func coalescable(r *http.Request, methods []string) bool {
+	return slices.Contains(methods, r.Method) && (r.Body == nil || r.Body == http.NoBody)
+}
@@ -0,0 +1,108 @@
+package sfhttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// Transport is an http.RoundTripper coalescing concurrent identical
+// requests: while a request is in flight, identical requests wait for its
+// response instead of being sent, and every caller receives a response of
+// its own with the same status, headers and body.
+//
+// Requests are identical if they have the same method, URL and values of
+// the vary headers (see WithVaryHeaders), or key (see WithKeyFunc). Only
+// requests without a body whose method is idempotent are coalesced (see
+// WithMethods); others are passed through. The body of a shared response is
+// read completely before it is handed to the callers.
+//
+// A request is canceled once the contexts of every caller are done;
+// callers going away earlier receive their context's error while the
+// others keep waiting.
+type Transport struct {
+	base  http.RoundTripper
+	group *singleflight.Group[string, *response]
+	cfg   config
+}
+
+// NewTransport returns a Transport sending requests via base, configured by
+// opts. If base is nil, http.DefaultTransport is used.
+func NewTransport(base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	cfg := newConfig(opts)
+
+	return &Transport{
+		base:  base,
+		group: singleflight.NewGroup[string, *response](cfg.groupOpts...),
+		cfg:   cfg,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !coalescable(req, t.cfg.methods) {
+		return t.base.RoundTrip(req)
+	}
+
+	res, err, _ := t.group.DoContextFunc(req.Context(), t.cfg.key(req),
+		func(ctx context.Context) (*response, error) {
+			return t.fetch(req.WithContext(ctx))
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.replicate(req), nil
+}
+
+// fetch sends req and reads its response.
+func (t *Transport) fetch(req *http.Request) (*response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response{resp: resp, body: body}, nil
+}
+
+// Inflight returns the requests in flight, see singleflight.Group.Inflight.
+func (t *Transport) Inflight() []singleflight.FlightInfo {
+	return t.group.Inflight()
+}
+
+// Stats returns the statistics of the coalesced requests, see
+// singleflight.Group.Stats.
+func (t *Transport) Stats() singleflight.GroupStats {
+	return t.group.Stats()
+}
+
+// response is a response shared among identical requests, with its body
+// read completely.
+type response struct {
+	resp *http.Response
+	body []byte
+}
+
+// replicate returns a copy of the response for req, with headers and body
+// of its own.
+func (r *response) replicate(req *http.Request) *http.Response {
+	resp := *r.resp
+	resp.Header = r.resp.Header.Clone()
+	resp.Trailer = r.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(r.body))
+	resp.Request = req
+
+	return &resp
+}
@@ -0,0 +1,107 @@
+package sfhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer returns a server counting its requests, responding to
+// each after delay.
+func countingServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		time.Sleep(delay)
+		w.Header().Set("X-Answer", "42")
+		w.WriteHeader(http.StatusTeapot)
+		io.WriteString(w, "answer "+r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &hits
+}
+
+// roundTrips sends n concurrent requests built by newReq via client.
+func roundTrips(t *testing.T, client *http.Client, n int, newReq func(i int) *http.Request) []*http.Response {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	resps := make([]*http.Response, n)
+	for i := range resps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Do(newReq(i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resps[i] = resp
+		}()
+	}
+	wg.Wait()
+
+	return resps
+}
+
+func TestTransport(t *testing.T) {
+	srv, hits := countingServer(t, 30*time.Millisecond)
+	client := &http.Client{Transport: NewTransport(srv.Client().Transport)}
+
+	resps := roundTrips(t, client, 5, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/answer", nil)
+		return req
+	})
+
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("hits=%d, want 1", got)
+	}
+	for _, resp := range resps {
+		if resp == nil {
+			t.FailNow()
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTeapot || resp.Header.Get("X-Answer") != "42" || string(body) != "answer " {
+			t.Fatalf("got %d %v %q, want replicated response", resp.StatusCode, resp.Header, body)
+		}
+	}
+	resps[0].Header.Set("X-Answer", "mutated")
+	if resps[0].Header.Get("X-Answer") != "mutated" || resps[1].Header.Get("X-Answer") != "42" {
+		t.Fatal("responses share headers")
+	}
+}
+
+func TestTransportPassesThrough(t *testing.T) {
+	srv, hits := countingServer(t, 30*time.Millisecond)
+	client := &http.Client{Transport: NewTransport(srv.Client().Transport)}
+
+	roundTrips(t, client, 2, func(int) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+		return req
+	})
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("POST hits=%d, want 2", got)
+	}
+
+	hits.Store(0)
+	resps := roundTrips(t, client, 2, func(i int) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Authorization", []string{"alice", "bob"}[i])
+		return req
+	})
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("hits with distinct credentials=%d, want 2", got)
+	}
+	if body, _ := io.ReadAll(resps[1].Body); string(body) != "answer bob" {
+		t.Fatalf("body=%q, want answer bob", body)
+	}
+}
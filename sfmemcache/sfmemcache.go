@@ -0,0 +1,242 @@
+// Package sfmemcache implements an sfdist.Backend on memcached,
+// deduplicating executions across the processes sharing a memcached
+// cluster.
+//
+// A key is claimed via add with the claim TTL, storing a token unique to
+// the claim. The leader replaces the claim by the result, stored for the
+// result TTL, via compare-and-swap, so it leaves the key alone once its
+// claim expired. The waiting processes poll for the result, as memcached
+// has no notifications. Memcached expires
+// items in full seconds, so TTLs are rounded up to full seconds.
+package sfmemcache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// DefaultPollInterval is the interval waiting processes check the key at,
+// unless configured otherwise via WithPollInterval.
+const DefaultPollInterval = 50 * time.Millisecond
+
+// maxKeyLength is the length of the longest key memcached accepts.
+const maxKeyLength = 250
+
+// Markers prefixing the values of a key.
+const (
+	pending  = "p"
+	result   = "r"
+	released = "x"
+)
+
+// Client is the subset of the methods of *memcache.Client used by a
+// Backend.
+type Client interface {
+	Add(item *memcache.Item) error
+	Set(item *memcache.Item) error
+	Get(key string) (*memcache.Item, error)
+	CompareAndSwap(item *memcache.Item) error
+	Delete(key string) error
+}
+
+var _ Client = (*memcache.Client)(nil)
+
+// config configures a Backend.
+type config struct {
+	pollInterval time.Duration
+}
+
+// Option configures a Backend.
+type Option = func(*config)
+
+// WithPollInterval returns an Option that sets the interval waiting
+// processes check the key at. It defaults to DefaultPollInterval.
+func WithPollInterval(d time.Duration) Option {
+	return func(config *config) {
+		config.pollInterval = d
+	}
+}
+
+// Backend is an sfdist.Backend on memcached.
+type Backend struct {
+	client Client
+	cfg    config
+
+	mu     sync.Mutex
+	claims map[string]string
+}
+
+var _ sfdist.Backend = (*Backend)(nil)
+
+// New returns a Backend on client, usually a *memcache.Client, configured
+// by opts.
+func New(client Client, opts ...Option) *Backend {
+	cfg := config{pollInterval: DefaultPollInterval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Backend{client: client, cfg: cfg, claims: make(map[string]string)}
+}
+
+// Claim implements sfdist.Backend.
+func (b *Backend) Claim(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	k := itemKey(key)
+	claim := pending + rand.Text()
+
+	err := b.client.Add(&memcache.Item{
+		Key:        k,
+		Value:      []byte(claim),
+		Expiration: expiration(ttl),
+	})
+	if errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	b.claims[k] = claim
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// Publish implements sfdist.Backend.
+func (b *Backend) Publish(_ context.Context, key string, res []byte, ttl time.Duration) error {
+	k := itemKey(key)
+
+	return b.swap(k, b.claim(k), append([]byte(result), res...), ttl)
+}
+
+// Release implements sfdist.Backend. The claim is replaced by a marker of
+// its release before the key is deleted, as memcached cannot delete a key
+// conditionally.
+func (b *Backend) Release(_ context.Context, key string) error {
+	k := itemKey(key)
+
+	claim := b.claim(k)
+	if claim == "" {
+		return nil
+	}
+	if err := b.swap(k, claim, []byte(released), time.Second); err != nil {
+		return err
+	}
+
+	err := b.client.Delete(k)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil
+	}
+
+	return err
+}
+
+// Await implements sfdist.Backend.
+func (b *Backend) Await(ctx context.Context, key string) ([]byte, bool, error) {
+	k := itemKey(key)
+
+	ticker := time.NewTicker(b.cfg.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		item, err := b.client.Get(k)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if len(item.Value) > 0 && string(item.Value[:1]) == result {
+			return item.Value[1:], true, nil
+		}
+		if string(item.Value) == released {
+			return nil, false, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// claim returns and forgets the claim of the item key k.
+func (b *Backend) claim(k string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	claim := b.claims[k]
+	delete(b.claims, k)
+
+	return claim
+}
+
+// swap replaces the claim of the item key k by value for ttl, failing with
+// sfdist.ErrClaimLost if k no longer holds the claim.
+func (b *Backend) swap(k, claim string, value []byte, ttl time.Duration) error {
+	item, err := b.client.Get(k)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return sfdist.ErrClaimLost
+	}
+	if err != nil {
+		return err
+	}
+	if claim == "" || string(item.Value) != claim {
+		return sfdist.ErrClaimLost
+	}
+
+	item.Value = value
+	item.Expiration = expiration(ttl)
+
+	err = b.client.CompareAndSwap(item)
+	if errors.Is(err, memcache.ErrCASConflict) || errors.Is(err, memcache.ErrNotStored) {
+		return sfdist.ErrClaimLost
+	}
+
+	return err
+}
+
+// itemKey returns key if memcached accepts it, or its SHA-256 digest
+// otherwise.
+func itemKey(key string) string {
+	if legalKey(key) {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// legalKey reports whether memcached accepts key: at most 250 bytes and no
+// whitespace or control characters.
+func legalKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := range len(key) {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+// expiration returns ttl as the expiration of an item, rounded up to full
+// seconds.
+func expiration(ttl time.Duration) int32 {
+	return int32(max(math.Ceil(ttl.Seconds()), 1))
+}
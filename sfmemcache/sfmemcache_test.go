@@ -0,0 +1,260 @@
+package sfmemcache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// memoryClient is an in-memory Client honoring the expiration of items.
+// As the CAS ID of an item is unexported, CompareAndSwap succeeds if the
+// item was not stored since the last Get of its key.
+type memoryClient struct {
+	mu      sync.Mutex
+	items   map[string]memoryItem
+	version uint64
+	got     map[string]uint64
+}
+
+// memoryItem is an item of a memoryClient.
+type memoryItem struct {
+	value   []byte
+	expires time.Time
+	version uint64
+}
+
+// get returns the unexpired item of key. The caller must hold c.mu.
+func (c *memoryClient) get(key string) (memoryItem, bool) {
+	item, ok := c.items[key]
+	if !ok || time.Now().After(item.expires) {
+		return memoryItem{}, false
+	}
+
+	return item, true
+}
+
+// put stores item. The caller must hold c.mu.
+func (c *memoryClient) put(item *memcache.Item) {
+	if c.items == nil {
+		c.items = make(map[string]memoryItem)
+	}
+	c.version++
+	c.items[item.Key] = memoryItem{
+		value:   item.Value,
+		expires: time.Now().Add(time.Duration(item.Expiration) * time.Second),
+		version: c.version,
+	}
+}
+
+// expire expires the item of key.
+func (c *memoryClient) expire(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+}
+
+func (c *memoryClient) Add(item *memcache.Item) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.get(item.Key); ok {
+		return memcache.ErrNotStored
+	}
+	c.put(item)
+
+	return nil
+}
+
+func (c *memoryClient) Set(item *memcache.Item) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(item)
+
+	return nil
+}
+
+func (c *memoryClient) Get(key string) (*memcache.Item, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.get(key)
+	if !ok {
+		return nil, memcache.ErrCacheMiss
+	}
+	if c.got == nil {
+		c.got = make(map[string]uint64)
+	}
+	c.got[key] = item.version
+
+	return &memcache.Item{Key: key, Value: item.value}, nil
+}
+
+func (c *memoryClient) CompareAndSwap(item *memcache.Item) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, ok := c.get(item.Key)
+	if !ok {
+		return memcache.ErrNotStored
+	}
+	if c.got[item.Key] != stored.version {
+		return memcache.ErrCASConflict
+	}
+	c.put(item)
+
+	return nil
+}
+
+func (c *memoryClient) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.get(key); !ok {
+		return memcache.ErrCacheMiss
+	}
+	delete(c.items, key)
+
+	return nil
+}
+
+func TestBackend(t *testing.T) {
+	client := new(memoryClient)
+
+	var executions atomic.Int32
+	fn := func() (int, error) {
+		executions.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		g := sfdist.New[int](New(client, WithPollInterval(10*time.Millisecond)))
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err, _ := g.Do("answer", fn); v != 42 || err != nil {
+					t.Errorf("got %d, %v, want 42", v, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions=%d, want 1 across processes", got)
+	}
+}
+
+func TestBackendAwait(t *testing.T) {
+	b := New(new(memoryClient), WithPollInterval(10*time.Millisecond))
+	ctx := context.Background()
+
+	if _, ok, err := b.Await(ctx, "free"); ok || err != nil {
+		t.Fatalf("Await of free key=%t, %v, want not ok", ok, err)
+	}
+	if err := b.Release(ctx, "free"); err != nil {
+		t.Fatalf("Release of free key=%v, want nil", err)
+	}
+
+	if claimed, err := b.Claim(ctx, "answer", time.Minute); !claimed || err != nil {
+		t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+	}
+	if claimed, err := b.Claim(ctx, "answer", time.Minute); claimed || err != nil {
+		t.Fatalf("Claim of claimed key=%t, %v, want not claimed", claimed, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Release(ctx, "answer")
+	}()
+	if _, ok, err := b.Await(ctx, "answer"); ok || err != nil {
+		t.Fatalf("Await of released key=%t, %v, want not ok", ok, err)
+	}
+
+	b.Claim(ctx, "answer", time.Minute)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Publish(ctx, "answer", []byte("result"), time.Minute)
+	}()
+	if res, ok, err := b.Await(ctx, "answer"); string(res) != "result" || !ok || err != nil {
+		t.Fatalf("Await=%q, %t, %v, want result", res, ok, err)
+	}
+	if res, ok, _ := b.Await(ctx, "answer"); string(res) != "result" || !ok {
+		t.Fatalf("Await of published key=%q, %t, want result", res, ok)
+	}
+}
+
+func TestBackendClaimLost(t *testing.T) {
+	client := new(memoryClient)
+	stale, leader := New(client), New(client)
+	ctx := context.Background()
+
+	settle := map[string]func() error{
+		"Release": func() error { return stale.Release(ctx, "answer") },
+		"Publish": func() error { return stale.Publish(ctx, "answer", []byte("stale"), time.Minute) },
+	}
+	for name, settle := range settle {
+		if claimed, err := stale.Claim(ctx, "answer", time.Second); !claimed || err != nil {
+			t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+		}
+		client.expire("answer")
+		if claimed, err := leader.Claim(ctx, "answer", time.Minute); !claimed || err != nil {
+			t.Fatalf("Claim of expired key=%t, %v, want claimed", claimed, err)
+		}
+
+		if err := settle(); !errors.Is(err, sfdist.ErrClaimLost) {
+			t.Fatalf("%s of expired claim=%v, want ErrClaimLost", name, err)
+		}
+		if err := leader.Publish(ctx, "answer", []byte("result"), time.Minute); err != nil {
+			t.Fatalf("Publish after stale %s=%v, want nil", name, err)
+		}
+		if res, ok, _ := leader.Await(ctx, "answer"); string(res) != "result" || !ok {
+			t.Fatalf("Await after stale %s=%q, %t, want result", name, res, ok)
+		}
+		client.expire("answer")
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	for _, key := range []string{"singleflight:user:42", strings.Repeat("k", maxKeyLength)} {
+		if got := itemKey(key); got != key {
+			t.Errorf("itemKey(%q)=%q, want unchanged", key, got)
+		}
+	}
+
+	for _, key := range []string{"", "user 42", "user\n42", strings.Repeat("k", maxKeyLength+1)} {
+		got := itemKey(key)
+		if !legalKey(got) {
+			t.Errorf("itemKey(%q)=%q, want legal key", key, got)
+		}
+		if got == itemKey(key+"x") {
+			t.Errorf("itemKey(%q) collides with itemKey(%q)", key, key+"x")
+		}
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	for ttl, want := range map[time.Duration]int32{
+		0:                       1,
+		100 * time.Millisecond:  1,
+		time.Second:             1,
+		1500 * time.Millisecond: 2,
+		30 * time.Second:        30,
+	} {
+		if got := expiration(ttl); got != want {
+			t.Errorf("expiration(%v)=%d, want %d", ttl, got, want)
+		}
+	}
+}
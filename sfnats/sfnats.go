@@ -0,0 +1,136 @@
+// Package sfnats implements an sfdist.Backend on a NATS JetStream key-value
+// bucket, deduplicating executions across the processes connected to a
+// NATS cluster.
+//
+// A key is claimed by creating it in the bucket with the claim TTL as its
+// per-key TTL, so the bucket must be created with LimitMarkerTTL set
+// (requires NATS server 2.11 or later). The leader replaces the claim by
+// the result, which the waiting processes receive by watching the key, and
+// deletes it once the result TTL has passed.
+package sfnats
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// Markers prefixing the values of a key.
+const (
+	pending = "p"
+	result  = "r"
+)
+
+// Backend is an sfdist.Backend on a JetStream key-value bucket.
+type Backend struct {
+	kv jetstream.KeyValue
+
+	mu     sync.Mutex
+	claims map[string]uint64
+}
+
+var _ sfdist.Backend = (*Backend)(nil)
+
+// New returns a Backend on the bucket kv.
+func New(kv jetstream.KeyValue) *Backend {
+	return &Backend{kv: kv, claims: make(map[string]uint64)}
+}
+
+// Claim implements sfdist.Backend.
+func (b *Backend) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	k := bucketKey(key)
+
+	rev, err := b.kv.Create(ctx, k, []byte(pending), jetstream.KeyTTL(ttl))
+	if errors.Is(err, jetstream.ErrKeyExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	b.claims[k] = rev
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// Publish implements sfdist.Backend.
+func (b *Backend) Publish(ctx context.Context, key string, res []byte, ttl time.Duration) error {
+	k := bucketKey(key)
+
+	rev, err := b.kv.Update(ctx, k, append([]byte(result), res...), b.claim(k))
+	if err != nil {
+		return err
+	}
+
+	time.AfterFunc(ttl, func() {
+		b.kv.Delete(context.Background(), k, jetstream.LastRevision(rev)) //nolint:errcheck
+	})
+
+	return nil
+}
+
+// Release implements sfdist.Backend.
+func (b *Backend) Release(ctx context.Context, key string) error {
+	k := bucketKey(key)
+
+	return b.kv.Delete(ctx, k, jetstream.LastRevision(b.claim(k)))
+}
+
+// Await implements sfdist.Backend.
+func (b *Backend) Await(ctx context.Context, key string) ([]byte, bool, error) {
+	w, err := b.kv.Watch(ctx, bucketKey(key))
+	if err != nil {
+		return nil, false, err
+	}
+	defer w.Stop() //nolint:errcheck
+
+	claimed := false
+	for {
+		select {
+		case entry, ok := <-w.Updates():
+			switch {
+			case !ok:
+				return nil, false, ctx.Err()
+			case entry == nil && !claimed:
+				// The key holds no value.
+				return nil, false, nil
+			case entry == nil:
+				continue
+			case entry.Operation() != jetstream.KeyValuePut:
+				return nil, false, nil
+			}
+
+			value := entry.Value()
+			if len(value) > 0 && string(value[:1]) == result {
+				return value[1:], true, nil
+			}
+			claimed = true
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// claim returns and forgets the revision of the claim of the bucket key k.
+func (b *Backend) claim(k string) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rev := b.claims[k]
+	delete(b.claims, k)
+
+	return rev
+}
+
+// bucketKey returns key encoded as a valid key of a bucket.
+func bucketKey(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
@@ -0,0 +1,176 @@
+package sfnats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// memoryKV is an in-memory jetstream.KeyValue implementing the methods used
+// by Backend. Revisions are not checked and claims do not expire.
+type memoryKV struct {
+	jetstream.KeyValue
+
+	mu       sync.Mutex
+	rev      uint64
+	entries  map[string]*entry
+	watchers map[string][]chan jetstream.KeyValueEntry
+}
+
+func newMemoryKV() *memoryKV {
+	return &memoryKV{
+		entries:  make(map[string]*entry),
+		watchers: make(map[string][]chan jetstream.KeyValueEntry),
+	}
+}
+
+// set records e for key and hands it to the watchers. The caller must hold
+// kv.mu.
+func (kv *memoryKV) set(key string, value []byte, op jetstream.KeyValueOp) uint64 {
+	kv.rev++
+	e := &entry{key: key, value: value, rev: kv.rev, op: op}
+	kv.entries[key] = e
+	for _, ch := range kv.watchers[key] {
+		ch <- e
+	}
+
+	return e.rev
+}
+
+func (kv *memoryKV) Create(_ context.Context, key string, value []byte, _ ...jetstream.KVCreateOpt) (uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if e, ok := kv.entries[key]; ok && e.op == jetstream.KeyValuePut {
+		return 0, fmt.Errorf("%w: key exists", jetstream.ErrKeyExists)
+	}
+
+	return kv.set(key, value, jetstream.KeyValuePut), nil
+}
+
+func (kv *memoryKV) Update(_ context.Context, key string, value []byte, _ uint64) (uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	return kv.set(key, value, jetstream.KeyValuePut), nil
+}
+
+func (kv *memoryKV) Delete(_ context.Context, key string, _ ...jetstream.KVDeleteOpt) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.set(key, nil, jetstream.KeyValueDelete)
+
+	return nil
+}
+
+func (kv *memoryKV) Watch(_ context.Context, key string, _ ...jetstream.WatchOpt) (jetstream.KeyWatcher, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	ch := make(chan jetstream.KeyValueEntry, 16)
+	if e, ok := kv.entries[key]; ok {
+		ch <- e
+	}
+	ch <- nil
+	kv.watchers[key] = append(kv.watchers[key], ch)
+
+	return watcher{ch: ch}, nil
+}
+
+// watcher is a jetstream.KeyWatcher of a memoryKV.
+type watcher struct {
+	ch chan jetstream.KeyValueEntry
+}
+
+func (w watcher) Updates() <-chan jetstream.KeyValueEntry { return w.ch }
+func (w watcher) Stop() error                             { return nil }
+
+// entry is a jetstream.KeyValueEntry of a memoryKV.
+type entry struct {
+	key   string
+	value []byte
+	rev   uint64
+	op    jetstream.KeyValueOp
+}
+
+func (e *entry) Bucket() string                  { return "singleflight" }
+func (e *entry) Key() string                     { return e.key }
+func (e *entry) Value() []byte                   { return e.value }
+func (e *entry) Revision() uint64                { return e.rev }
+func (e *entry) Created() time.Time              { return time.Time{} }
+func (e *entry) Delta() uint64                   { return 0 }
+func (e *entry) Operation() jetstream.KeyValueOp { return e.op }
+
+func TestBackend(t *testing.T) {
+	kv := newMemoryKV()
+
+	var executions atomic.Int32
+	fn := func() (int, error) {
+		executions.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		g := sfdist.New[int](New(kv))
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err, _ := g.Do("user:42", fn); v != 42 || err != nil {
+					t.Errorf("got %d, %v, want 42", v, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions=%d, want 1 across processes", got)
+	}
+}
+
+func TestBackendAwait(t *testing.T) {
+	b := New(newMemoryKV())
+	ctx := context.Background()
+
+	if _, ok, err := b.Await(ctx, "user:42"); ok || err != nil {
+		t.Fatalf("Await of free key=%t, %v, want not ok", ok, err)
+	}
+
+	if claimed, err := b.Claim(ctx, "user:42", time.Minute); !claimed || err != nil {
+		t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+	}
+	if claimed, err := b.Claim(ctx, "user:42", time.Minute); claimed || err != nil {
+		t.Fatalf("Claim of claimed key=%t, %v, want not claimed", claimed, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Release(ctx, "user:42")
+	}()
+	if _, ok, err := b.Await(ctx, "user:42"); ok || err != nil {
+		t.Fatalf("Await of released key=%t, %v, want not ok", ok, err)
+	}
+
+	b.Claim(ctx, "user:42", time.Minute)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Publish(ctx, "user:42", []byte("result"), time.Minute)
+	}()
+	if res, ok, err := b.Await(ctx, "user:42"); string(res) != "result" || !ok || err != nil {
+		t.Fatalf("Await=%q, %t, %v, want result", res, ok, err)
+	}
+	if res, ok, _ := b.Await(ctx, "user:42"); string(res) != "result" || !ok {
+		t.Fatalf("Await of published key=%q, %t, want result", res, ok)
+	}
+}
@@ -0,0 +1,110 @@
+// Package sfnet deduplicates network lookups with singleflight groups.
+package sfnet
+
+import (
+	"context"
+	"net"
+	"slices"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// HostResolver looks up hosts, such as net.Resolver.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// config configures a Resolver.
+type config struct {
+	ttl       time.Duration
+	groupOpts []singleflight.GroupConfigOption
+}
+
+// Option configures a Resolver.
+type Option = func(*config)
+
+// WithTTL returns an Option that keeps the result of a lookup for ttl, so
+// lookups arriving shortly after it completed receive its result instead of
+// starting a new one, see singleflight.WithCoalesceWindow. Keep it short:
+// it is meant to absorb bursts, not to replace the TTL of the records. By
+// default, results are only shared among concurrent lookups.
+func WithTTL(ttl time.Duration) Option {
+	return func(config *config) {
+		config.ttl = ttl
+	}
+}
+
+// WithGroupOptions returns an Option that configures the groups lookups are
+// deduplicated through, e.g. with singleflight.WithName.
+func WithGroupOptions(opts ...singleflight.GroupConfigOption) Option {
+	return func(config *config) {
+		config.groupOpts = append(config.groupOpts, opts...)
+	}
+}
+
+// Resolver deduplicates concurrent lookups of the same name, absorbing the
+// DNS stampedes of connection storms: while a lookup is in flight,
+// identical lookups wait for its result instead of querying again.
+//
+// Every caller receives a slice of its own. A lookup is canceled once the
+// contexts of every caller are done.
+type Resolver struct {
+	base  HostResolver
+	hosts *singleflight.Group[string, []string]
+	ips   *singleflight.Group[ipLookup, []net.IP]
+}
+
+// ipLookup is the key of a lookup via LookupIP.
+type ipLookup struct {
+	network string
+	host    string
+}
+
+// NewResolver returns a Resolver looking up names via base, configured by
+// opts. If base is nil, net.DefaultResolver is used.
+func NewResolver(base HostResolver, opts ...Option) *Resolver {
+	if base == nil {
+		base = net.DefaultResolver
+	}
+
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	groupOpts := append([]singleflight.GroupConfigOption{singleflight.WithCoalesceWindow(cfg.ttl)}, cfg.groupOpts...)
+
+	return &Resolver{
+		base:  base,
+		hosts: singleflight.NewGroup[string, []string](groupOpts...),
+		ips:   singleflight.NewGroup[ipLookup, []net.IP](groupOpts...),
+	}
+}
+
+// LookupHost looks up host, see net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err, _ := r.hosts.DoContextFunc(ctx, host, func(ctx context.Context) ([]string, error) {
+		return r.base.LookupHost(ctx, host)
+	})
+
+	return slices.Clone(addrs), err
+}
+
+// LookupIP looks up host for network, see net.Resolver.LookupIP.
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	key := ipLookup{network: network, host: host}
+	ips, err, _ := r.ips.DoContextFunc(ctx, key, func(ctx context.Context) ([]net.IP, error) {
+		return r.base.LookupIP(ctx, network, host)
+	})
+	if ips == nil {
+		return nil, err
+	}
+
+	clones := make([]net.IP, len(ips))
+	for i, ip := range ips {
+		clones[i] = slices.Clone(ip)
+	}
+
+	return clones, err
+}
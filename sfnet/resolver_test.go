@@ -0,0 +1,97 @@
+package sfnet
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingResolver is a HostResolver counting its lookups.
+type countingResolver struct {
+	lookups atomic.Int32
+}
+
+func (r *countingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups.Add(1)
+	time.Sleep(30 * time.Millisecond)
+	return []string{"192.0.2.1", "192.0.2.2"}, nil
+}
+
+func (r *countingResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	r.lookups.Add(1)
+	time.Sleep(30 * time.Millisecond)
+	return []net.IP{net.ParseIP("192.0.2.1")}, nil
+}
+
+// lookups runs n concurrent lookups.
+func lookups(n int, lookup func()) {
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lookup()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestResolver(t *testing.T) {
+	base := &countingResolver{}
+	r := NewResolver(base)
+
+	var mu sync.Mutex
+	var results [][]string
+	lookups(5, func() {
+		addrs, err := r.LookupHost(context.Background(), "example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		results = append(results, addrs)
+		mu.Unlock()
+	})
+	if got := base.lookups.Load(); got != 1 {
+		t.Fatalf("lookups=%d, want 1", got)
+	}
+
+	results[0][0] = "mutated"
+	if results[1][0] != "192.0.2.1" {
+		t.Fatal("callers share the result slice")
+	}
+
+	base.lookups.Store(0)
+	lookups(3, func() {
+		ips, err := r.LookupIP(context.Background(), "ip4", "example.com")
+		if err != nil || len(ips) != 1 {
+			t.Errorf("ips=%v, err=%v", ips, err)
+		}
+	})
+	if got := base.lookups.Load(); got != 1 {
+		t.Fatalf("IP lookups=%d, want 1", got)
+	}
+}
+
+func TestResolverTTL(t *testing.T) {
+	base := &countingResolver{}
+	r := NewResolver(base, WithTTL(time.Second))
+
+	for range 3 {
+		if _, err := r.LookupHost(context.Background(), "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := base.lookups.Load(); got != 1 {
+		t.Fatalf("sequential lookups within TTL=%d, want 1", got)
+	}
+
+	if _, err := r.LookupHost(context.Background(), "example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if got := base.lookups.Load(); got != 2 {
+		t.Fatalf("lookups=%d, want 2 for another host", got)
+	}
+}
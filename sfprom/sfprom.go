@@ -0,0 +1,147 @@
+// Package sfprom exports the statistics of singleflight groups as
+// Prometheus metrics.
+package sfprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// DefaultNamespace is the namespace of the exported metrics, unless
+// configured otherwise via WithNamespace.
+const DefaultNamespace = "singleflight"
+
+// StatsSource is implemented by groups reporting their statistics, such as
+// Group and ShardedGroup.
+type StatsSource interface {
+	Stats() singleflight.GroupStats
+}
+
+// Collector is a prometheus.Collector exporting the statistics of a group,
+// labeled with the name of the group: call, execution and outcome counts,
+// the dedupe ratio, the number of flights in progress and their waiters,
+// and a histogram of execution durations.
+//
+// Execution durations are recorded via ObserveExecution, which is installed
+// on the group via singleflight.WithExecutionObserver.
+type Collector struct {
+	source    StatsSource
+	durations prometheus.Histogram
+
+	calls       *prometheus.Desc
+	executions  *prometheus.Desc
+	shared      *prometheus.Desc
+	errors      *prometheus.Desc
+	panics      *prometheus.Desc
+	forgets     *prometheus.Desc
+	dedupeRatio *prometheus.Desc
+	inFlight    *prometheus.Desc
+	waiters     *prometheus.Desc
+}
+
+// config configures a Collector.
+type config struct {
+	namespace string
+	buckets   []float64
+}
+
+// Option configures a Collector.
+type Option = func(*config)
+
+// WithNamespace returns an Option that sets the namespace of the exported
+// metrics. It defaults to DefaultNamespace.
+func WithNamespace(namespace string) Option {
+	return func(config *config) {
+		config.namespace = namespace
+	}
+}
+
+// WithBuckets returns an Option that sets the buckets of the execution
+// duration histogram, in seconds. They default to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(config *config) {
+		config.buckets = buckets
+	}
+}
+
+// NewCollector returns a Collector exporting the statistics of source,
+// labeled with group="name", configured by opts. If name is empty, the name
+// of source is used, see singleflight.WithName.
+func NewCollector(name string, source StatsSource, opts ...Option) *Collector {
+	if name == "" {
+		name = source.Stats().Name
+	}
+
+	cfg := config{namespace: DefaultNamespace, buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	labels := prometheus.Labels{"group": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(cfg.namespace, "", metric), help, nil, labels)
+	}
+
+	return &Collector{
+		source: source,
+		durations: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Name:        "execution_duration_seconds",
+			Help:        "Duration of executions of work functions.",
+			ConstLabels: labels,
+			Buckets:     cfg.buckets,
+		}),
+		calls:       desc("calls_total", "Calls that started or joined a flight."),
+		executions:  desc("executions_total", "Flights started."),
+		shared:      desc("shared_calls_total", "Calls served by a flight started by another caller."),
+		errors:      desc("errors_total", "Flights that completed with an error."),
+		panics:      desc("panics_total", "Flights whose work function panicked."),
+		forgets:     desc("forgets_total", "Flights and completed results forgotten."),
+		dedupeRatio: desc("dedupe_ratio", "Fraction of calls served by a flight started by another caller."),
+		inFlight:    desc("in_flight", "Flights in progress."),
+		waiters:     desc("waiters", "Callers waiting on a flight they joined."),
+	}
+}
+
+// ObserveExecution records the duration d of an execution in the
+// histogram. Its signature matches singleflight.WithExecutionObserver.
+func (c *Collector) ObserveExecution(_ string, d time.Duration, _ error) {
+	c.durations.Observe(d.Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.calls, c.executions, c.shared, c.errors, c.panics, c.forgets,
+		c.dedupeRatio, c.inFlight, c.waiters,
+	} {
+		ch <- desc
+	}
+	c.durations.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.source.Stats()
+
+	counter := func(desc *prometheus.Desc, v uint64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v))
+	}
+	gauge := func(desc *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
+	}
+
+	counter(c.calls, s.Calls)
+	counter(c.executions, s.Executions)
+	counter(c.shared, s.Shared)
+	counter(c.errors, s.Errors)
+	counter(c.panics, s.Panics)
+	counter(c.forgets, s.Forgets)
+	gauge(c.dedupeRatio, s.DedupeRatio())
+	gauge(c.inFlight, float64(s.InFlight))
+	gauge(c.waiters, float64(s.Waiters))
+	c.durations.Collect(ch)
+}
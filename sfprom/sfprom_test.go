@@ -0,0 +1,50 @@
+package sfprom
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+func TestCollector(t *testing.T) {
+	g := singleflight.NewGroup[string, int](singleflight.WithName("users"))
+	c := NewCollector("", g, WithNamespace("app"))
+	g.UpdateConfig(singleflight.WithExecutionObserver(c.ObserveExecution))
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+
+	g.Do("user:1", func() (int, error) { return 1, nil })
+	g.Do("user:2", func() (int, error) { return 0, errors.New("failed") })
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string]*dto.Metric, len(families))
+	for _, f := range families {
+		m := f.GetMetric()[0]
+		if l := m.GetLabel(); len(l) != 1 || l[0].GetName() != "group" || l[0].GetValue() != "users" {
+			t.Fatalf("%s labels=%v, want group=users", f.GetName(), l)
+		}
+		metrics[f.GetName()] = m
+	}
+
+	if v := metrics["app_calls_total"].GetCounter().GetValue(); v != 2 {
+		t.Fatalf("app_calls_total=%v, want 2", v)
+	}
+	if v := metrics["app_errors_total"].GetCounter().GetValue(); v != 1 {
+		t.Fatalf("app_errors_total=%v, want 1", v)
+	}
+	if v := metrics["app_in_flight"].GetGauge().GetValue(); v != 0 {
+		t.Fatalf("app_in_flight=%v, want 0", v)
+	}
+	if n := metrics["app_execution_duration_seconds"].GetHistogram().GetSampleCount(); n != 2 {
+		t.Fatalf("execution durations=%d, want 2", n)
+	}
+}
@@ -0,0 +1,196 @@
+// Package sfredis implements an sfdist.Backend on Redis, deduplicating
+// executions across the processes sharing a Redis server.
+//
+// A key is claimed via SET NX with the claim TTL, storing a token unique to
+// the claim. The leader stores the result under the key for the result TTL
+// and publishes it on a channel named like the key, where the waiting
+// processes receive it, unless the key no longer holds its token.
+package sfredis
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// DefaultPollInterval is the interval waiting processes check the key at,
+// in case the claim expired, unless configured otherwise via
+// WithPollInterval.
+const DefaultPollInterval = 100 * time.Millisecond
+
+// Markers prefixing the values and messages of a key.
+const (
+	pending  = "p"
+	result   = "r"
+	released = "x"
+)
+
+// settle replaces the claim ARGV[1] of the key KEYS[1] by the value
+// ARGV[2] for ARGV[3] milliseconds, or deletes the key if ARGV[3] is 0, and
+// publishes ARGV[2]. It returns 0, leaving the key alone, if the key does
+// not hold the claim.
+var settle = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+	redis.call("DEL", KEYS[1])
+end
+redis.call("PUBLISH", KEYS[1], ARGV[2])
+return 1
+`)
+
+// config configures a Backend.
+type config struct {
+	pollInterval time.Duration
+}
+
+// Option configures a Backend.
+type Option = func(*config)
+
+// WithPollInterval returns an Option that sets the interval waiting
+// processes check the key at, in case the claim expired without a result.
+// It defaults to DefaultPollInterval.
+func WithPollInterval(d time.Duration) Option {
+	return func(config *config) {
+		config.pollInterval = d
+	}
+}
+
+// Backend is an sfdist.Backend on Redis.
+type Backend struct {
+	client redis.UniversalClient
+	cfg    config
+
+	mu     sync.Mutex
+	claims map[string]string
+}
+
+var _ sfdist.Backend = (*Backend)(nil)
+
+// New returns a Backend on client, configured by opts.
+func New(client redis.UniversalClient, opts ...Option) *Backend {
+	cfg := config{pollInterval: DefaultPollInterval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Backend{client: client, cfg: cfg, claims: make(map[string]string)}
+}
+
+// Claim implements sfdist.Backend.
+func (b *Backend) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	claim := pending + rand.Text()
+
+	claimed, err := b.client.SetNX(ctx, key, claim, ttl).Result()
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	b.mu.Lock()
+	b.claims[key] = claim
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// Publish implements sfdist.Backend.
+func (b *Backend) Publish(ctx context.Context, key string, res []byte, ttl time.Duration) error {
+	var ms int64
+	if ttl > 0 {
+		ms = max(ttl.Milliseconds(), 1)
+	}
+
+	return b.settle(ctx, key, result+string(res), ms)
+}
+
+// Release implements sfdist.Backend.
+func (b *Backend) Release(ctx context.Context, key string) error {
+	b.mu.Lock()
+	_, ok := b.claims[key]
+	b.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return b.settle(ctx, key, released, 0)
+}
+
+// settle ends the claim of key, storing value for ms milliseconds unless ms
+// is 0, and publishes value.
+func (b *Backend) settle(ctx context.Context, key, value string, ms int64) error {
+	b.mu.Lock()
+	claim := b.claims[key]
+	delete(b.claims, key)
+	b.mu.Unlock()
+
+	settled, err := settle.Run(ctx, b.client, []string{key}, claim, value, ms).Int()
+	if err != nil {
+		return err
+	}
+	if settled == 0 {
+		return sfdist.ErrClaimLost
+	}
+
+	return nil
+}
+
+// Await implements sfdist.Backend.
+func (b *Backend) Await(ctx context.Context, key string) ([]byte, bool, error) {
+	sub := b.client.Subscribe(ctx, key)
+	defer sub.Close()
+
+	// Wait for the subscription, so no message published after the key was
+	// checked below is missed.
+	if _, err := sub.Receive(ctx); err != nil {
+		return nil, false, err
+	}
+	messages := sub.Channel()
+
+	ticker := time.NewTicker(b.cfg.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		value, err := b.client.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if res, ok := parse(value); ok {
+			return res, true, nil
+		}
+
+		select {
+		case msg := <-messages:
+			if res, ok := parse(msg.Payload); ok {
+				return res, true, nil
+			}
+			if msg.Payload == released {
+				return nil, false, nil
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// parse returns the result held by the value or message value, if any.
+func parse(value string) ([]byte, bool) {
+	if len(value) == 0 || value[:1] != result {
+		return nil, false
+	}
+
+	return []byte(value[1:]), true
+}
@@ -0,0 +1,126 @@
+package sfredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// newBackend returns a Backend on a Redis server for the test.
+func newBackend(t *testing.T, srv *miniredis.Miniredis) *Backend {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return New(client, WithPollInterval(10*time.Millisecond))
+}
+
+func TestBackend(t *testing.T) {
+	srv := miniredis.RunT(t)
+
+	var executions atomic.Int32
+	fn := func() (int, error) {
+		executions.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		g := sfdist.New[int](newBackend(t, srv))
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err, _ := g.Do("answer", fn); v != 42 || err != nil {
+					t.Errorf("got %d, %v, want 42", v, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions=%d, want 1 across processes", got)
+	}
+}
+
+func TestBackendAwait(t *testing.T) {
+	srv := miniredis.RunT(t)
+	b := newBackend(t, srv)
+	ctx := context.Background()
+
+	if _, ok, err := b.Await(ctx, "free"); ok || err != nil {
+		t.Fatalf("Await of free key=%t, %v, want not ok", ok, err)
+	}
+	if err := b.Release(ctx, "free"); err != nil {
+		t.Fatalf("Release of free key=%v, want nil", err)
+	}
+
+	if claimed, err := b.Claim(ctx, "answer", time.Minute); !claimed || err != nil {
+		t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+	}
+	if claimed, _ := b.Claim(ctx, "answer", time.Minute); claimed {
+		t.Fatal("claimed key claimed twice")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Release(ctx, "answer")
+	}()
+	if _, ok, err := b.Await(ctx, "answer"); ok || err != nil {
+		t.Fatalf("Await of released key=%t, %v, want not ok", ok, err)
+	}
+
+	b.Claim(ctx, "answer", time.Minute)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Publish(ctx, "answer", []byte("result"), time.Minute)
+	}()
+	if res, ok, err := b.Await(ctx, "answer"); string(res) != "result" || !ok || err != nil {
+		t.Fatalf("Await=%q, %t, %v, want result", res, ok, err)
+	}
+	if res, ok, _ := b.Await(ctx, "answer"); string(res) != "result" || !ok {
+		t.Fatalf("Await of published key=%q, %t, want result", res, ok)
+	}
+}
+
+func TestBackendClaimLost(t *testing.T) {
+	srv := miniredis.RunT(t)
+	stale, leader := newBackend(t, srv), newBackend(t, srv)
+	ctx := context.Background()
+
+	settle := map[string]func() error{
+		"Release": func() error { return stale.Release(ctx, "answer") },
+		"Publish": func() error { return stale.Publish(ctx, "answer", []byte("stale"), time.Minute) },
+	}
+	for name, settle := range settle {
+		srv.FlushAll()
+		if claimed, err := stale.Claim(ctx, "answer", time.Second); !claimed || err != nil {
+			t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+		}
+		srv.FastForward(2 * time.Second)
+		if claimed, err := leader.Claim(ctx, "answer", time.Minute); !claimed || err != nil {
+			t.Fatalf("Claim of expired key=%t, %v, want claimed", claimed, err)
+		}
+
+		if err := settle(); !errors.Is(err, sfdist.ErrClaimLost) {
+			t.Fatalf("%s of expired claim=%v, want ErrClaimLost", name, err)
+		}
+		if err := leader.Publish(ctx, "answer", []byte("result"), time.Minute); err != nil {
+			t.Fatalf("Publish after stale %s=%v, want nil", name, err)
+		}
+		if res, ok, _ := leader.Await(ctx, "answer"); string(res) != "result" || !ok {
+			t.Fatalf("Await after stale %s=%q, %t, want result", name, res, ok)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Package sfsql deduplicates database/sql reads with singleflight groups:
+// identical concurrent queries collapse into one execution, with typed
+// results.
+package sfsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	singleflight "github.com/iwpnd/singleflightx"
+)
+
+// QueryFunc executes query with args, e.g. via sql.DB.QueryContext, and
+// scans its result.
+type QueryFunc[V any] func(ctx context.Context, query string, args ...any) (V, error)
+
+// config configures a Reader.
+type config struct {
+	ttl       time.Duration
+	groupOpts []singleflight.GroupConfigOption
+}
+
+// Option configures a Reader.
+type Option = func(*config)
+
+// WithTTL returns an Option that keeps the result of a query for ttl, so
+// identical queries arriving shortly after it completed receive its result,
+// including its error, instead of executing again, see
+// singleflight.WithCoalesceWindow. By default, results are only shared
+// among concurrent queries.
+func WithTTL(ttl time.Duration) Option {
+	return func(config *config) {
+		config.ttl = ttl
+	}
+}
+
+// WithGroupOptions returns an Option that configures the group queries are
+// deduplicated through, e.g. with singleflight.WithName.
+func WithGroupOptions(opts ...singleflight.GroupConfigOption) Option {
+	return func(config *config) {
+		config.groupOpts = append(config.groupOpts, opts...)
+	}
+}
+
+// Reader collapses identical concurrent queries returning V into one
+// execution, sharing its result. Queries are identical if their key, see
+// Key, is. A query is canceled once the contexts of every caller are done.
+//
+// Callers share the value V; if it holds references, such as slices or
+// maps, callers must not modify it, unless the group copies values for
+// every caller (see singleflight.WithCloner and WithGroupOptions).
+type Reader[V any] struct {
+	group *singleflight.Group[string, V]
+}
+
+// NewReader returns a Reader configured by opts.
+func NewReader[V any](opts ...Option) *Reader[V] {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	groupOpts := append([]singleflight.GroupConfigOption{singleflight.WithCoalesceWindow(cfg.ttl)}, cfg.groupOpts...)
+
+	return &Reader[V]{group: singleflight.NewGroup[string, V](groupOpts...)}
+}
+
+// Query executes fn with query and args, unless an identical query is in
+// flight, in which case it waits for and returns its result. shared
+// reports whether the result was shared with other callers.
+func (r *Reader[V]) Query(
+	ctx context.Context, fn QueryFunc[V], query string, args ...any,
+) (v V, err error, shared bool) {
+	key, err := Key(query, args...)
+	if err != nil {
+		return v, err, false
+	}
+
+	return r.group.DoContextFunc(ctx, key, func(ctx context.Context) (V, error) {
+		return fn(ctx, query, args...)
+	})
+}
+
+// Wrap returns a function executing query via fn with the arguments it is
+// called with, deduplicated through r, so a read can be declared once and
+// called like a typed function:
+//
+//	getUser := sfsql.Wrap(users, "SELECT name FROM users WHERE id = $1",
+//		func(ctx context.Context, query string, args ...any) (User, error) {
+//			var u User
+//			err := db.QueryRowContext(ctx, query, args...).Scan(&u.Name)
+//			return u, err
+//		})
+//
+//	u, err := getUser(ctx, 42)
+func Wrap[V any](r *Reader[V], query string, fn QueryFunc[V]) func(ctx context.Context, args ...any) (V, error) {
+	return func(ctx context.Context, args ...any) (V, error) {
+		v, err, _ := r.Query(ctx, fn, query, args...)
+		return v, err
+	}
+}
+
+// Inflight returns the queries in flight, see singleflight.Group.Inflight.
+func (r *Reader[V]) Inflight() []singleflight.FlightInfo {
+	return r.group.Inflight()
+}
+
+// Stats returns the statistics of the deduplicated queries, see
+// singleflight.Group.Stats.
+func (r *Reader[V]) Stats() singleflight.GroupStats {
+	return r.group.Stats()
+}
+
+// Key derives the key of query with args: the query text followed by the
+// type and value of every argument. Arguments implementing driver.Valuer
+// are keyed by their value, named arguments (see sql.Named) by their name
+// as well. Other arguments are keyed by the value database/sql passes to
+// the driver (see driver.DefaultParameterConverter), so pointers are keyed
+// by the value they point to rather than by their address; Key fails for
+// pointers to values it cannot convert.
+func Key(query string, args ...any) (string, error) {
+	var b strings.Builder
+	b.WriteString(query)
+
+	for _, arg := range args {
+		b.WriteByte('\x00')
+		if named, ok := arg.(sql.NamedArg); ok {
+			b.WriteString(named.Name)
+			b.WriteByte('=')
+			arg = named.Value
+		}
+		if valuer, ok := arg.(driver.Valuer); ok {
+			v, err := valuer.Value()
+			if err != nil {
+				return "", err
+			}
+			arg = v
+		} else if v, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			arg = v
+		} else if strings.HasPrefix(fmt.Sprintf("%T", arg), "*") {
+			return "", fmt.Errorf("sfsql: key argument %T: %w", arg, err)
+		}
+		fmt.Fprintf(&b, "%T:%#v", arg, arg)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,105 @@
+package sfsql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const userQuery = "SELECT name FROM users WHERE id = $1"
+
+// countingQuery returns a QueryFunc counting its executions.
+func countingQuery(delay time.Duration) (QueryFunc[string], *atomic.Int32) {
+	var executions atomic.Int32
+
+	return func(ctx context.Context, query string, args ...any) (string, error) {
+		executions.Add(1)
+		time.Sleep(delay)
+		return "user " + args[0].(string), nil
+	}, &executions
+}
+
+func TestReader(t *testing.T) {
+	r := NewReader[string]()
+	fn, executions := countingQuery(30 * time.Millisecond)
+	getUser := Wrap(r, userQuery, fn)
+
+	var wg sync.WaitGroup
+	names := make([]string, 6)
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names[i], _ = getUser(context.Background(), []string{"alice", "bob"}[i%2])
+		}()
+	}
+	wg.Wait()
+
+	for i, name := range names {
+		if want := "user " + []string{"alice", "bob"}[i%2]; name != want {
+			t.Fatalf("name=%q, want %q", name, want)
+		}
+	}
+	if got := executions.Load(); got != 2 {
+		t.Fatalf("executions=%d, want 2", got)
+	}
+}
+
+func TestReaderTTL(t *testing.T) {
+	r := NewReader[string](WithTTL(time.Second))
+	fn, executions := countingQuery(0)
+
+	for range 3 {
+		if _, err, _ := r.Query(context.Background(), fn, userQuery, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions within TTL=%d, want 1", got)
+	}
+}
+
+func TestKey(t *testing.T) {
+	key := func(query string, args ...any) string {
+		t.Helper()
+		k, err := Key(query, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	for _, tc := range []struct {
+		name string
+		a, b string
+	}{
+		{"argument types", key(userQuery, 1), key(userQuery, "1")},
+		{"argument boundaries", key(userQuery, "a", "b"), key(userQuery, "a\x00string:b")},
+		{"named arguments", key(userQuery, sql.Named("id", 1)), key(userQuery, sql.Named("other", 1))},
+		{"query text", key(userQuery, 1), key("SELECT 1", 1)},
+	} {
+		if tc.a == tc.b {
+			t.Errorf("%s: keys equal: %q", tc.name, tc.a)
+		}
+	}
+
+	if key(userQuery, sql.NullString{String: "a", Valid: true}) != key(userQuery, "a") {
+		t.Error("driver.Valuer not keyed by its value")
+	}
+
+	id := 1
+	before := key(userQuery, &id)
+	id = 2
+	if after := key(userQuery, &id); after == before || after != key(userQuery, 2) {
+		t.Errorf("pointer not keyed by its value: %q, %q", before, after)
+	}
+	if key(userQuery, (*int)(nil)) != key(userQuery, nil) {
+		t.Error("nil pointer not keyed as nil")
+	}
+	if _, err := Key(userQuery, &struct{ ID int }{1}); err == nil {
+		t.Error("pointer to unconvertible value keyed")
+	}
+}
@@ -0,0 +1,142 @@
+package sfunix
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// Backend is an sfdist.Backend on the Server listening on a unix socket.
+type Backend struct {
+	path   string
+	dialer net.Dialer
+
+	mu     sync.Mutex
+	claims map[string]uint64
+}
+
+var _ sfdist.Backend = (*Backend)(nil)
+
+// New returns a Backend on the Server listening on the unix socket path.
+func New(path string) *Backend {
+	return &Backend{path: path, claims: make(map[string]uint64)}
+}
+
+// Claim implements sfdist.Backend.
+func (b *Backend) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	resp, err := b.do(ctx, request{Op: opClaim, Key: key, TTL: ttl})
+	if err != nil || !resp.OK {
+		return false, err
+	}
+
+	b.mu.Lock()
+	b.claims[key] = resp.Claim
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// Publish implements sfdist.Backend.
+func (b *Backend) Publish(ctx context.Context, key string, res []byte, ttl time.Duration) error {
+	return b.settle(ctx, request{Op: opPublish, Key: key, TTL: ttl, Claim: b.claim(key), Result: res})
+}
+
+// Release implements sfdist.Backend.
+func (b *Backend) Release(ctx context.Context, key string) error {
+	claim := b.claim(key)
+	if claim == 0 {
+		return nil
+	}
+
+	return b.settle(ctx, request{Op: opRelease, Key: key, Claim: claim})
+}
+
+// settle sends req, publishing or releasing the claim of its key, failing
+// with sfdist.ErrClaimLost if the key no longer holds the claim.
+func (b *Backend) settle(ctx context.Context, req request) error {
+	resp, err := b.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return sfdist.ErrClaimLost
+	}
+
+	return nil
+}
+
+// claim returns and forgets the claim of key, 0 if there is none.
+func (b *Backend) claim(key string) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	claim := b.claims[key]
+	delete(b.claims, key)
+
+	return claim
+}
+
+// Await implements sfdist.Backend.
+func (b *Backend) Await(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := b.do(ctx, request{Op: opAwait, Key: key})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return resp.Result, resp.OK, nil
+}
+
+// send writes msg as a frame to conn.
+func send(conn net.Conn, msg encoding.BinaryMarshaler) error {
+	body, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return writeFrame(conn, body)
+}
+
+// receive reads a frame from conn into msg.
+func receive(conn net.Conn, msg encoding.BinaryUnmarshaler) error {
+	body, err := readFrame(conn)
+	if err != nil {
+		return err
+	}
+
+	return msg.UnmarshalBinary(body)
+}
+
+// do sends req to the Server on a connection of its own and returns the
+// response. The connection is closed once ctx is done.
+func (b *Backend) do(ctx context.Context, req request) (response, error) {
+	conn, err := b.dialer.DialContext(ctx, "unix", b.path)
+	if err != nil {
+		return response{}, err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var resp response
+	err = send(conn, req)
+	if err == nil {
+		err = receive(conn, &resp)
+	}
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return response{}, ctx.Err()
+	case err != nil:
+		return response{}, err
+	case resp.Err != "":
+		return response{}, errors.New(resp.Err)
+	default:
+		return resp, nil
+	}
+}
@@ -0,0 +1,215 @@
+package sfunix
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Server coordinates the flights of the processes connected to its
+// listeners. The zero value is ready to use.
+type Server struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	claims  uint64 // the last claim issued
+}
+
+// entry is the state of a claimed key, or of a key holding a result.
+type entry struct {
+	claim     uint64
+	result    []byte
+	published bool
+	done      chan struct{} // closed once the claim ends
+	timer     *time.Timer   // ends the claim or expires the result
+}
+
+// ListenAndServe listens on the unix socket path and serves on it, see
+// Serve.
+func (s *Server) ListenAndServe(path string) error {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve serves the connections accepted on l until l is closed, which
+// returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers the request on conn.
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	var req request
+	if err := receive(conn, &req); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if req.Op == opAwait {
+		// The client sends nothing else, so the read returns once it hangs
+		// up, e.g. because its context is done.
+		go func() {
+			conn.Read(make([]byte, 1)) //nolint:errcheck
+			cancel()
+		}()
+	}
+
+	resp := s.handle(ctx, req)
+	send(conn, resp) //nolint:errcheck
+}
+
+// handle executes req.
+func (s *Server) handle(ctx context.Context, req request) response {
+	switch req.Op {
+	case opClaim:
+		claim, ok := s.claim(req.Key, req.TTL)
+		return response{OK: ok, Claim: claim}
+	case opPublish:
+		return response{OK: s.publish(req.Key, req.Claim, req.Result, req.TTL)}
+	case opRelease:
+		return response{OK: s.release(req.Key, req.Claim)}
+	case opAwait:
+		res, ok := s.await(ctx, req.Key)
+		return response{OK: ok, Result: res}
+	default:
+		return response{Err: "sfunix: unknown operation"}
+	}
+}
+
+// claim claims the free key for ttl and returns the claim, reporting
+// whether it did.
+func (s *Server) claim(key string, ttl time.Duration) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; ok {
+		return 0, false
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]*entry)
+	}
+
+	s.claims++
+	e := &entry{claim: s.claims, done: make(chan struct{})}
+	s.entries[key] = e
+	e.timer = time.AfterFunc(ttl, func() { s.expire(key, e) })
+
+	return e.claim, true
+}
+
+// held returns the entry of key if it holds claim. The caller must hold
+// s.mu.
+func (s *Server) held(key string, claim uint64) (*entry, bool) {
+	e, ok := s.entries[key]
+	if !ok || e.published || e.claim != claim {
+		return nil, false
+	}
+
+	return e, true
+}
+
+// publish stores the result of key for ttl, ending its claim, and reports
+// whether key still held the claim.
+func (s *Server) publish(key string, claim uint64, res []byte, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.held(key, claim)
+	if !ok {
+		return false
+	}
+
+	e.timer.Stop()
+	e.result = res
+	e.published = true
+	close(e.done)
+
+	if ttl <= 0 {
+		delete(s.entries, key)
+		return true
+	}
+	e.timer = time.AfterFunc(ttl, func() { s.expire(key, e) })
+
+	return true
+}
+
+// release frees the claimed key without a result and reports whether key
+// still held the claim.
+func (s *Server) release(key string, claim uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.held(key, claim)
+	if !ok {
+		return false
+	}
+
+	e.timer.Stop()
+	s.remove(key, e)
+
+	return true
+}
+
+// await waits for the result of key. ok is false if the key is or becomes
+// free without a result, or ctx is done.
+func (s *Server) await(ctx context.Context, key string) (res []byte, ok bool) {
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return e.result, e.published
+}
+
+// expire removes e, the entry of key unless replaced since, once its claim
+// or result expired.
+func (s *Server) expire(key string, e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key, e)
+}
+
+// remove removes e, the entry of key unless replaced since, ending its
+// claim. The caller must hold s.mu.
+func (s *Server) remove(key string, e *entry) {
+	if s.entries[key] != e {
+		return
+	}
+
+	delete(s.entries, key)
+	if !e.published {
+		close(e.done)
+	}
+}
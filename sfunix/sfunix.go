@@ -0,0 +1,48 @@
+// Package sfunix implements an sfdist.Backend over a unix domain socket,
+// deduplicating executions across the processes of one host, e.g. the
+// workers of a prefork server, without external infrastructure.
+//
+// One process, typically the parent of the workers, runs a Server on the
+// socket, which keeps the claims and results of the keys in memory. The
+// processes reach it via a Backend on the socket path:
+//
+//	l, err := net.Listen("unix", "/run/app/singleflight.sock")
+//	go new(sfunix.Server).Serve(l)
+//
+//	users := sfdist.New[User](sfunix.New("/run/app/singleflight.sock"))
+package sfunix
+
+import "time"
+
+// op is the operation of a request.
+type op uint8
+
+// Operations of requests, one per method of sfdist.Backend.
+const (
+	opClaim op = iota + 1
+	opPublish
+	opRelease
+	opAwait
+)
+
+// request is a request of a Backend to a Server, sent as a frame on a
+// connection of its own, see writeFrame.
+type request struct {
+	Op  op
+	Key string
+	TTL time.Duration
+	// Claim is the claim published or released.
+	Claim  uint64
+	Result []byte
+}
+
+// response is the response of a Server to a request.
+type response struct {
+	// OK reports whether the key was claimed, holds Result, or still held
+	// the claim published or released.
+	OK bool
+	// Claim is the claim of a claimed key.
+	Claim  uint64
+	Result []byte
+	Err    string
+}
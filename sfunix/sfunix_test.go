@@ -0,0 +1,203 @@
+package sfunix
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/singleflightx/sfdist"
+)
+
+// serve runs a Server for the test and returns the path of its socket.
+func serve(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sf.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- new(Server).Serve(l) }()
+	t.Cleanup(func() {
+		l.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve=%v, want nil once closed", err)
+		}
+	})
+
+	return path
+}
+
+func TestBackend(t *testing.T) {
+	path := serve(t)
+
+	var executions atomic.Int32
+	fn := func() (int, error) {
+		executions.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		g := sfdist.New[int](New(path))
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err, _ := g.Do("answer", fn); v != 42 || err != nil {
+					t.Errorf("got %d, %v, want 42", v, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if got := executions.Load(); got != 1 {
+		t.Fatalf("executions=%d, want 1 across processes", got)
+	}
+}
+
+func TestBackendAwait(t *testing.T) {
+	b := New(serve(t))
+	ctx := context.Background()
+
+	if _, ok, err := b.Await(ctx, "free"); ok || err != nil {
+		t.Fatalf("Await of free key=%t, %v, want not ok", ok, err)
+	}
+
+	if claimed, err := b.Claim(ctx, "answer", time.Minute); !claimed || err != nil {
+		t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+	}
+	if claimed, _ := b.Claim(ctx, "answer", time.Minute); claimed {
+		t.Fatal("claimed key claimed twice")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Release(ctx, "answer")
+	}()
+	if _, ok, err := b.Await(ctx, "answer"); ok || err != nil {
+		t.Fatalf("Await of released key=%t, %v, want not ok", ok, err)
+	}
+
+	b.Claim(ctx, "answer", time.Minute)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Publish(ctx, "answer", []byte("result"), time.Minute)
+	}()
+	if res, ok, err := b.Await(ctx, "answer"); string(res) != "result" || !ok || err != nil {
+		t.Fatalf("Await=%q, %t, %v, want result", res, ok, err)
+	}
+	if res, ok, _ := b.Await(ctx, "answer"); string(res) != "result" || !ok {
+		t.Fatalf("Await of published key=%q, %t, want result", res, ok)
+	}
+}
+
+func TestBackendExpiry(t *testing.T) {
+	b := New(serve(t))
+	ctx := context.Background()
+
+	b.Claim(ctx, "answer", 20*time.Millisecond)
+	if _, ok, err := b.Await(ctx, "answer"); ok || err != nil {
+		t.Fatalf("Await of expired claim=%t, %v, want not ok", ok, err)
+	}
+	if claimed, _ := b.Claim(ctx, "answer", time.Minute); !claimed {
+		t.Fatal("expired claim not claimable")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := b.Await(ctx, "answer"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Await=%v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBackendClaimLost(t *testing.T) {
+	path := serve(t)
+	stale, leader := New(path), New(path)
+	ctx := context.Background()
+
+	settle := map[string]func() error{
+		"Release": func() error { return stale.Release(ctx, "answer") },
+		"Publish": func() error { return stale.Publish(ctx, "answer", []byte("stale"), time.Minute) },
+	}
+	for name, settle := range settle {
+		if claimed, err := stale.Claim(ctx, "answer", 20*time.Millisecond); !claimed || err != nil {
+			t.Fatalf("Claim=%t, %v, want claimed", claimed, err)
+		}
+		time.Sleep(40 * time.Millisecond)
+		if claimed, err := leader.Claim(ctx, "answer", time.Minute); !claimed || err != nil {
+			t.Fatalf("Claim of expired key=%t, %v, want claimed", claimed, err)
+		}
+
+		if err := settle(); !errors.Is(err, sfdist.ErrClaimLost) {
+			t.Fatalf("%s of expired claim=%v, want ErrClaimLost", name, err)
+		}
+		if err := leader.Publish(ctx, "answer", []byte("result"), 0); err != nil {
+			t.Fatalf("Publish after stale %s=%v, want nil", name, err)
+		}
+	}
+}
+
+func TestBackendUnavailable(t *testing.T) {
+	b := New(filepath.Join(t.TempDir(), "missing.sock"))
+
+	if _, err := b.Claim(context.Background(), "answer", time.Minute); err == nil {
+		t.Fatal("Claim without server succeeded")
+	}
+
+	g := sfdist.New[int](b)
+	if v, err, _ := g.Do("answer", func() (int, error) { return 42, nil }); v != 42 || err != nil {
+		t.Fatalf("got %d, %v, want 42 executed locally", v, err)
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+
+	req := request{Op: opPublish, Key: "answer", TTL: time.Minute, Claim: 7, Result: []byte("42")}
+	resp := response{OK: true, Claim: 7, Result: []byte("42"), Err: "failed"}
+	for _, msg := range []encoding.BinaryMarshaler{req, resp} {
+		body, _ := msg.MarshalBinary()
+		if err := writeFrame(&buf, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var gotReq request
+	var gotResp response
+	for _, msg := range []encoding.BinaryUnmarshaler{&gotReq, &gotResp} {
+		body, err := readFrame(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := msg.UnmarshalBinary(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if gotReq.Op != req.Op || gotReq.Key != req.Key || gotReq.TTL != req.TTL || gotReq.Claim != req.Claim ||
+		string(gotReq.Result) != "42" {
+		t.Fatalf("request=%+v, want %+v", gotReq, req)
+	}
+	if !gotResp.OK || gotResp.Claim != resp.Claim || string(gotResp.Result) != "42" || gotResp.Err != resp.Err {
+		t.Fatalf("response=%+v, want %+v", gotResp, resp)
+	}
+
+	body, _ := req.MarshalBinary()
+	for _, malformed := range [][]byte{nil, body[:len(body)-1], append(body, 0)} {
+		if err := new(request).UnmarshalBinary(malformed); !errors.Is(err, errMalformed) {
+			t.Errorf("UnmarshalBinary(%q)=%v, want %v", malformed, err, errMalformed)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package sfunix
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// maxFrame is the size of the largest frame read from a connection.
+const maxFrame = 1 << 28
+
+// errMalformed is returned for frames that do not decode.
+var errMalformed = errors.New("sfunix: malformed frame")
+
+// Requests and responses are sent as frames: the big-endian uint32 length
+// of the body followed by the body. Bodies are sequences of fields, where
+// byte strings are prefixed by their big-endian uint32 length:
+//
+//	request:  op (1 byte), TTL in nanoseconds (8 bytes), claim (8 bytes),
+//	          key, result
+//	response: OK (1 byte), claim (8 bytes), result, error
+
+// writeFrame writes body as a frame to w.
+func writeFrame(w io.Writer, body []byte) error {
+	frame := make([]byte, 0, 4+len(body))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(body))) //nolint:gosec
+	_, err := w.Write(append(frame, body...))
+
+	return err
+}
+
+// readFrame reads the body of a frame from r.
+func readFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrame {
+		return nil, errMalformed
+	}
+
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (r request) MarshalBinary() ([]byte, error) {
+	body := make([]byte, 0, 25+len(r.Key)+len(r.Result))
+	body = append(body, byte(r.Op))
+	body = binary.BigEndian.AppendUint64(body, uint64(r.TTL)) //nolint:gosec
+	body = binary.BigEndian.AppendUint64(body, r.Claim)
+	body = appendField(body, []byte(r.Key))
+
+	return appendField(body, r.Result), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (r *request) UnmarshalBinary(data []byte) error {
+	d := fields{data: data}
+	r.Op = op(d.uint8())
+	r.TTL = time.Duration(d.uint64()) //nolint:gosec
+	r.Claim = d.uint64()
+	r.Key = string(d.bytes())
+	r.Result = d.bytes()
+
+	return d.end()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (r response) MarshalBinary() ([]byte, error) {
+	body := make([]byte, 0, 17+len(r.Result)+len(r.Err))
+	if r.OK {
+		body = append(body, 1)
+	} else {
+		body = append(body, 0)
+	}
+	body = binary.BigEndian.AppendUint64(body, r.Claim)
+	body = appendField(body, r.Result)
+
+	return appendField(body, []byte(r.Err)), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (r *response) UnmarshalBinary(data []byte) error {
+	d := fields{data: data}
+	r.OK = d.uint8() == 1
+	r.Claim = d.uint64()
+	r.Result = d.bytes()
+	r.Err = string(d.bytes())
+
+	return d.end()
+}
+
+// appendField appends the byte string b as a field to body.
+func appendField(body, b []byte) []byte {
+	body = binary.BigEndian.AppendUint32(body, uint32(len(b))) //nolint:gosec
+
+	return append(body, b...)
+}
+
+// fields reads the fields of a frame body. Reading past the end of the body
+// yields zero values and fails end.
+type fields struct {
+	data []byte
+	bad  bool
+}
+
+// next returns the next n bytes of the body.
+func (f *fields) next(n uint64) []byte {
+	if f.bad || uint64(len(f.data)) < n {
+		f.bad = true
+		return nil
+	}
+
+	b := f.data[:n]
+	f.data = f.data[n:]
+
+	return b
+}
+
+// uint8 reads a single byte.
+func (f *fields) uint8() uint8 {
+	if b := f.next(1); b != nil {
+		return b[0]
+	}
+
+	return 0
+}
+
+// uint64 reads a big-endian uint64.
+func (f *fields) uint64() uint64 {
+	if b := f.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+
+	return 0
+}
+
+// bytes reads a length-prefixed byte string, nil if it is empty.
+func (f *fields) bytes() []byte {
+	size := f.next(4)
+	if size == nil {
+		return nil
+	}
+
+	b := f.next(uint64(binary.BigEndian.Uint32(size)))
+	if len(b) == 0 {
+		return nil
+	}
+
+	return b
+}
+
+// end returns errMalformed unless the body was read exactly.
+func (f *fields) end() error {
+	if f.bad || len(f.data) > 0 {
+		return errMalformed
+	}
+
+	return nil
+}
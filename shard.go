@@ -0,0 +1,95 @@
+package singleflight
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+)
+
+// shardRouter maps keys to shards. It is the sharding infrastructure shared
+// by ShardedGroup, KeyedMutex and friends.
+type shardRouter struct {
+	hashFn     NewHash
+	seed       maphash.Seed
+	shardCount uint64
+}
+
+// newShardConfig returns the ShardConfig described by opts, applying the
+// package defaults and a minimum of two shards.
+func newShardConfig(opts ...ShardConfigOption) *ShardConfig {
+	config := &ShardConfig{
+		hashFn:     newHash,
+		shardCount: DefaultShardCount,
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.shardCount < 2 {
+		config.shardCount = 2
+	}
+
+	return config
+}
+
+// newShardRouter returns a shardRouter for config.
+func newShardRouter(config *ShardConfig) shardRouter {
+	return shardRouter{
+		hashFn:     config.hashFn,
+		seed:       maphash.MakeSeed(),
+		shardCount: config.shardCount,
+	}
+}
+
+// index returns the shard index for key.
+//
+// The hash is computed over the UTF-8 bytes of the key string, and the
+// result is reduced modulo shardCount.
+func (r shardRouter) index(key string) uint64 {
+	return r.indexBytes([]byte(key))
+}
+
+// indexUint64 returns the shard index for an integer key, hashed by its
+// 8-byte little-endian form.
+func (r shardRouter) indexUint64(key uint64) uint64 {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], key)
+
+	return r.indexBytes(b[:])
+}
+
+// indexBytes returns the shard index for the raw bytes of a key.
+func (r shardRouter) indexBytes(key []byte) uint64 {
+	hasher := r.hashFn()
+	hasher.Write(key)
+
+	return hasher.Sum64() % r.shardCount
+}
+
+// indexKey returns the shard index for a key of any comparable type.
+// Strings and byte arrays are hashed as they are, integers by their
+// fixed-size binary form, both via the hash function of the router. Other
+// keys, including defined string and integer types, are hashed via
+// maphash.Comparable, which neither formats nor allocates.
+func indexKey[K comparable](r shardRouter, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return r.index(k)
+	case [16]byte:
+		return r.indexBytes(k[:])
+	case int:
+		return r.indexUint64(uint64(k))
+	case int64:
+		return r.indexUint64(uint64(k))
+	case int32:
+		return r.indexUint64(uint64(k))
+	case uint:
+		return r.indexUint64(uint64(k))
+	case uint64:
+		return r.indexUint64(k)
+	case uint32:
+		return r.indexUint64(uint64(k))
+	default:
+		return maphash.Comparable(r.seed, key) % r.shardCount
+	}
+}
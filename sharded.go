@@ -5,41 +5,43 @@
 // Portions adapted from github.com/tarndt/shardedsingleflight (MPL-2.0).
 package singleflight
 
+import (
+	"context"
+	"fmt"
+)
+
 // ShardedGroup distributes singleflight coordination across multiple shards
 // to reduce lock contention for workloads with many distinct keys.
 //
-// The shard index is derived by hashing the key via newHash() and taking
-// modulo shardCount. By default, NewShardedGroup constructs shardCount
-// groups using DefaultShardCount and the package's newHash implementation.
-type ShardedGroup[T ~string, V any] struct {
-	hashFn NewHash
-	shards []Group[T, V]
-
-	shardCount uint64
+// K may be any comparable type, like the key of Group. The shard index is
+// derived by hashing the key via newHash() and taking modulo shardCount:
+// strings and byte arrays are hashed as they are, integers by their
+// fixed-size binary form, and other keys via maphash.Comparable, unless a
+// hash over K is configured via WithKeyHash, or shards are picked via
+// WithShardPicker. By default, NewShardedGroup constructs shardCount groups
+// using DefaultShardCount and the package's newHash implementation.
+type ShardedGroup[K comparable, V any] struct {
+	router   shardRouter
+	shards   []Group[K, V]
+	keyIndex func(K) uint64
 }
 
 // NewShardedGroup constructs a ShardedGroup that uses DefaultShardCount
 // shards and the package's newHash function to map keys to shards.
-func NewShardedGroup[T ~string, V any](opts ...ShardConfigOption) *ShardedGroup[T, V] {
-	config := &ShardConfig{
-		hashFn:     newHash,
-		shardCount: DefaultShardCount,
-	}
+func NewShardedGroup[K comparable, V any](opts ...ShardConfigOption) *ShardedGroup[K, V] {
+	config := newShardConfig(opts...)
 
-	for _, opt := range opts {
-		opt(config)
+	s := &ShardedGroup[K, V]{
+		router: newShardRouter(config),
 	}
 
-	if config.shardCount < 2 {
-		config.shardCount = 2
+	s.shards = make([]Group[K, V], config.shardCount)
+	for i := range s.shards {
+		s.shards[i].shard = i + 1
+		s.shards[i].configure(config.groupOpts...)
 	}
 
-	s := &ShardedGroup[T, V]{
-		hashFn:     config.hashFn,
-		shardCount: config.shardCount,
-	}
-
-	s.shards = make([]Group[T, V], s.shardCount)
+	s.keyIndex = configuredKeyIndex[K](config)
 
 	return s
 }
@@ -48,36 +50,177 @@ func NewShardedGroup[T ~string, V any](opts ...ShardConfigOption) *ShardedGroup[
 //
 // Behavior matches Group.Do, but sharding reduces contention between
 // unrelated keys under high concurrency.
-func (sg *ShardedGroup[T, V]) Do(
-	key T, fn func() (V, error),
+func (sg *ShardedGroup[K, V]) Do(
+	key K, fn func() (V, error),
 ) (v V, err error, shared bool) {
 	return sg.shards[sg.shardIndex(key)].Do(key, fn)
 }
 
+// DoWithCost is the sharded variant of Group.DoWithCost.
+func (sg *ShardedGroup[K, V]) DoWithCost(
+	key K, cost int64, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return sg.shards[sg.shardIndex(key)].DoWithCost(key, cost, fn)
+}
+
+// DoResult is like Do, but returns the outcome as a Result carrying the
+// metadata of the execution, see Group.DoResult.
+func (sg *ShardedGroup[K, V]) DoResult(key K, fn func() (V, error)) Result[V] {
+	return sg.shards[sg.shardIndex(key)].DoResult(key, fn)
+}
+
 // DoChan is the channel-based variant of Do for the sharded group.
 //
 // Behavior matches Group.DoChan, scoped to the shard determined by key.
-func (sg *ShardedGroup[T, V]) DoChan(
-	key T, fn func() (V, error),
+func (sg *ShardedGroup[K, V]) DoChan(
+	key K, fn func() (V, error),
 ) <-chan Result[V] {
 	return sg.shards[sg.shardIndex(key)].DoChan(key, fn)
 }
 
+// DoContext is the context-aware variant of Do for the sharded group.
+//
+// Behavior matches Group.DoContext, scoped to the shard determined by key.
+func (sg *ShardedGroup[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return sg.shards[sg.shardIndex(key)].DoContext(ctx, key, fn)
+}
+
 // Forget clears any in-flight or recently completed state for key on its shard.
 //
 // After Forget, a subsequent call with the same key will not join an
 // in-flight execution started before Forget; it will start a new one.
-func (sg *ShardedGroup[T, V]) Forget(key T) {
-	sg.shards[sg.shardIndex(key)].Forget(key)
+// Forget reports whether there was any state for key to clear.
+func (sg *ShardedGroup[K, V]) Forget(key K) bool {
+	return sg.shards[sg.shardIndex(key)].Forget(key)
 }
 
-// shardIndex returns the shard index for key using the configured hash function.
+// Start starts a flight for key on its shard that is completed later via
+// the returned Completer, see Group.Start.
+func (sg *ShardedGroup[K, V]) Start(key K) (completer *Completer[V], joined bool) {
+	return sg.shards[sg.shardIndex(key)].Start(key)
+}
+
+// Subscribe observes every future flight of key on its shard, see
+// Group.Subscribe.
+func (sg *ShardedGroup[K, V]) Subscribe(key K) (ch <-chan Result[V], cancel func()) {
+	return sg.shards[sg.shardIndex(key)].Subscribe(key)
+}
+
+// UpdateConfig atomically applies opts on top of the current configuration
+// of every shard, see Group.UpdateConfig. Shards are updated one after
+// another.
+func (sg *ShardedGroup[K, V]) UpdateConfig(opts ...GroupConfigOption) {
+	for i := range sg.shards {
+		sg.shards[i].UpdateConfig(opts...)
+	}
+}
+
+// shardIndex returns the shard index for key using the configured hash
+// function. String keys are normalized first if the shards normalize keys,
+// so keys sharing a flight map to the same shard.
+func (sg *ShardedGroup[K, V]) shardIndex(key K) uint64 {
+	if sg.keyIndex != nil {
+		return sg.keyIndex(key)
+	}
+
+	if config := sg.shards[0].settings(); config.keyNormalizer != nil {
+		if s, ok := stringKey(config, key); ok {
+			return sg.router.index(config.keyNormalizer(s))
+		}
+	}
+
+	return indexKey(sg.router, key)
+}
+
+// Sharded routes calls to shards of any Singleflighter implementation, such
+// as instrumented or cached groups, mapping keys to shards like
+// ShardedGroup. It implements Singleflighter.
+type Sharded[K comparable, V any] struct {
+	router   shardRouter
+	shards   []Singleflighter[K, V]
+	keyIndex func(K) uint64
+}
+
+// NewSharded constructs a Sharded of the shards returned by newShard for
+// every shard index, configured by opts. WithGroupOptions does not apply;
+// newShard configures its shards itself.
+func NewSharded[K comparable, V any](
+	newShard func(shard int) Singleflighter[K, V], opts ...ShardConfigOption,
+) *Sharded[K, V] {
+	config := newShardConfig(opts...)
+
+	s := &Sharded[K, V]{
+		router:   newShardRouter(config),
+		shards:   make([]Singleflighter[K, V], config.shardCount),
+		keyIndex: configuredKeyIndex[K](config),
+	}
+	for i := range s.shards {
+		s.shards[i] = newShard(i)
+	}
+
+	return s
+}
+
+// Do executes and deduplicates fn on the shard determined by key.
 //
-// The hash is computed over the UTF-8 bytes of the key string, and the
-// result is reduced modulo shardCount.
-func (sg *ShardedGroup[T, V]) shardIndex(key T) uint64 {
-	hasher := sg.hashFn()
-	hasher.Write([]byte(key))
+// Behavior matches the Do method of the shard.
+func (s *Sharded[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	return s.Shard(key).Do(key, fn)
+}
+
+// DoChan is the channel-based variant of Do.
+func (s *Sharded[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	return s.Shard(key).DoChan(key, fn)
+}
+
+// DoContext is like Do, but stops waiting when ctx is done, returning
+// ctx.Err() while the execution continues for the other callers.
+func (s *Sharded[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return waitContext(ctx, s.DoChan(key, fn))
+}
 
-	return hasher.Sum64() % sg.shardCount
+// Forget forgets the flight of key on its shard and reports whether there
+// was one.
+func (s *Sharded[K, V]) Forget(key K) bool {
+	return s.Shard(key).Forget(key)
+}
+
+// Shard returns the shard key maps to, e.g. to call methods of the shard
+// implementation beyond Singleflighter.
+func (s *Sharded[K, V]) Shard(key K) Singleflighter[K, V] {
+	if s.keyIndex != nil {
+		return s.shards[s.keyIndex(key)]
+	}
+
+	return s.shards[indexKey(s.router, key)]
+}
+
+// configuredKeyIndex returns the shard index function configured for keys
+// of type K via WithShardPicker or WithKeyHash, or nil if there is none. It
+// panics if either is configured for another key type.
+func configuredKeyIndex[K comparable](config *ShardConfig) func(K) uint64 {
+	pick, ok := config.shardPicker.(func(K) uint64)
+	if config.shardPicker != nil && !ok {
+		panic(fmt.Sprintf("singleflight: shard picker %T does not match key type %s", config.shardPicker, typeName(typeOf[K]())))
+	}
+	hash, ok := config.keyHash.(func(K) uint64)
+	if config.keyHash != nil && !ok {
+		panic(fmt.Sprintf("singleflight: key hash %T does not match key type %s", config.keyHash, typeName(typeOf[K]())))
+	}
+
+	index := pick
+	if index == nil {
+		index = hash
+	}
+	if index == nil {
+		return nil
+	}
+
+	return func(key K) uint64 {
+		return index(key) % config.shardCount
+	}
 }
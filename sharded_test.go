@@ -1,12 +1,33 @@
 package singleflight
 
-import "testing"
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestShardedGroupDo(t *testing.T) {
 	sg := NewShardedGroup[string, int](WithShardCount(2))
 	doDedupe(t, sg, keyA)
 }
 
+func TestShardedGroupDoResult(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	doResultReportsMetadata(t, sg, keyB)
+}
+
+func TestShardedGroupSharedErrors(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithSharedErrors()))
+	sharedErrorsWrapJoined(t, sg, keyB)
+}
+
+func TestShardedGroupCloner(t *testing.T) {
+	sg := NewShardedGroup[string, map[string]int](WithGroupOptions(WithCloner(maps.Clone[map[string]int])))
+	clonerCopiesValues(t, sg, keyB)
+}
+
 func TestShardedGroupDoChan(t *testing.T) {
 	sg := NewShardedGroup[string, string]()
 	doChanDedupe(t, sg, keyB)
@@ -21,3 +42,176 @@ func TestShardedGroupError(t *testing.T) {
 	sg := NewShardedGroup[string, int]()
 	doErrorPropagates(t, sg, keyB, 0)
 }
+
+func TestShardedGroupMaxWaiters(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithMaxWaiters(2)))
+	maxWaitersRejects(t, sg, keyB, 2)
+}
+
+func TestShardedGroupUpdateConfig(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithRateLimit("key", 0.001, 1)))
+	updateConfigApplies(t, sg, keyA)
+}
+
+func TestShardedGroupDoContext(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	doContextStopsWaiting(t, sg, keyB)
+}
+
+func TestShardedGroupRecoverPanics(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithRecoverPanics()))
+	recoverPanicsDelivers(t, sg, keyB)
+}
+
+func TestShardedGroupMaxConcurrent(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithMaxConcurrent(2)))
+	concurrencyCapped(t, sg)
+}
+
+func TestShardedGroupComparableKeys(t *testing.T) {
+	type userKey struct {
+		tenant string
+		id     int64
+	}
+
+	t.Run("int", func(t *testing.T) {
+		sg := NewShardedGroup[int64, int](WithShardCount(4))
+		comparableKeyDedupe(t, sg, 42, 43)
+	})
+	t.Run("struct", func(t *testing.T) {
+		sg := NewShardedGroup[userKey, int](WithShardCount(4))
+		comparableKeyDedupe(t, sg, userKey{"a", 1}, userKey{"b", 1})
+	})
+	t.Run("key hash", func(t *testing.T) {
+		sg := NewShardedGroup[userKey, int](
+			WithShardCount(4),
+			WithKeyHash(func(key userKey) uint64 { return uint64(key.id) }),
+		)
+		comparableKeyDedupe(t, sg, userKey{"a", 1}, userKey{"b", 1})
+
+		if got := sg.shardIndex(userKey{"c", 6}); got != 2 {
+			t.Fatalf("shardIndex=%d, want 2", got)
+		}
+	})
+	t.Run("shard picker", func(t *testing.T) {
+		sg := NewShardedGroup[userKey, int](
+			WithShardCount(4),
+			WithKeyHash(func(userKey) uint64 { return 0 }),
+			WithShardPicker(func(key userKey) uint64 { return uint64(key.id) }),
+		)
+		comparableKeyDedupe(t, sg, userKey{"a", 1}, userKey{"b", 1})
+
+		// the picker takes precedence over the key hash
+		if got := sg.shardIndex(userKey{"c", 6}); got != 2 {
+			t.Fatalf("shardIndex=%d, want 2", got)
+		}
+	})
+}
+
+func TestShardedGroupKeyIndexOfAnotherType(t *testing.T) {
+	for name, opt := range map[string]ShardConfigOption{
+		"key hash":     WithKeyHash(func(key int) uint64 { return uint64(key) }),
+		"shard picker": WithShardPicker(func(key int) uint64 { return uint64(key) }),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "func(int) uint64") {
+					t.Fatalf("recovered %v, want panic naming the %s", r, name)
+				}
+			}()
+			NewShardedGroup[string, int](opt)
+		})
+	}
+}
+
+// newGroupShard returns a new Group as a shard of a Sharded.
+func newGroupShard[K comparable, V any](int) Singleflighter[K, V] {
+	return &Group[K, V]{}
+}
+
+func TestShardedDo(t *testing.T) {
+	s := NewSharded(newGroupShard[string, int], WithShardCount(4))
+	doDedupe(t, s, keyA)
+}
+
+func TestShardedDoChan(t *testing.T) {
+	s := NewSharded(newGroupShard[string, string])
+	doChanDedupe(t, s, keyB)
+}
+
+func TestShardedForget(t *testing.T) {
+	s := NewSharded(newGroupShard[string, int])
+	forgetCreatesNewExecution(t, s, keyA)
+}
+
+func TestShardedDoContext(t *testing.T) {
+	s := NewSharded(newGroupShard[string, int])
+	doContextStopsWaiting(t, s, keyB)
+}
+
+func TestShardedCustomShards(t *testing.T) {
+	// shards of any implementation, here caching groups
+	s := NewSharded(func(int) Singleflighter[string, int] {
+		return NewCachedGroup[string, int](nil, time.Minute)
+	}, WithShardCount(4))
+
+	var calls int
+	fn := func() (int, error) { calls++; return calls, nil }
+	for range 3 {
+		if v, _, _ := s.Do(keyA, fn); v != 1 {
+			t.Fatalf("Do=%d, want cached 1", v)
+		}
+	}
+
+	if _, ok := s.Shard(keyA).(*CachedGroup[string, int]); !ok {
+		t.Fatalf("Shard=%T, want *CachedGroup", s.Shard(keyA))
+	}
+}
+
+func TestShardedKeyHash(t *testing.T) {
+	shards := make([]*Group[int64, int], 4)
+	s := NewSharded(func(i int) Singleflighter[int64, int] {
+		shards[i] = &Group[int64, int]{}
+		return shards[i]
+	}, WithShardCount(4), WithKeyHash(func(key int64) uint64 { return uint64(key) }))
+
+	comparableKeyDedupe(t, s, 42, 43)
+
+	if got := s.Shard(6); got != shards[2] {
+		t.Fatal("Shard(6) is not the shard of index 2")
+	}
+}
+
+func TestShardedShardPicker(t *testing.T) {
+	shards := make([]*Group[string, int], 4)
+	s := NewSharded(func(i int) Singleflighter[string, int] {
+		shards[i] = &Group[string, int]{}
+		return shards[i]
+	}, WithShardCount(4), WithShardPicker(func(key string) uint64 { return uint64(len(key)) }))
+
+	if got := s.Shard("tenant-3"); got != shards[0] {
+		t.Fatal(`Shard("tenant-3") is not the shard of index 0`)
+	}
+	if got := s.Shard("abc"); got != shards[3] {
+		t.Fatal(`Shard("abc") is not the shard of index 3`)
+	}
+}
+
+func TestShardedGroupDoAllocsDefinedKeys(t *testing.T) {
+	type id int64
+	type name string
+
+	fn := func() (int, error) { return wantValueInt, nil }
+	base := NewShardedGroup[int64, int]()
+	want := testing.AllocsPerRun(100, func() { base.Do(1, fn) })
+
+	// defined key types are routed without formatting the key
+	ids := NewShardedGroup[id, int]()
+	if allocs := testing.AllocsPerRun(100, func() { ids.Do(1, fn) }); allocs > want {
+		t.Fatalf("allocs per Do with defined integer keys = %v, want %v", allocs, want)
+	}
+	names := NewShardedGroup[name, int]()
+	if allocs := testing.AllocsPerRun(100, func() { names.Do("user:42", fn) }); allocs > want {
+		t.Fatalf("allocs per Do with defined string keys = %v, want %v", allocs, want)
+	}
+}
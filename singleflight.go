@@ -1,37 +1,280 @@
-// Package singleflight provides generic helpers around golang.org/x/sync/singleflight
+// Portions adapted from golang.org/x/sync/singleflight (BSD-3-Clause).
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of that source code is governed by a BSD-style license that can be
+// found at https://cs.opensource.google/go/x/sync/+/master:LICENSE
+
+// Package singleflight provides a generic duplicate function call
+// suppression mechanism in the spirit of golang.org/x/sync/singleflight.
 package singleflight
 
 import (
-	"golang.org/x/sync/singleflight"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Singleflighter is anything that implements singleflight.Group.
-type Singleflighter[T ~string, V any] interface {
-	Do(key T, fn func() (V, error)) (V, error, bool)
-	DoChan(key T, fn func() (V, error)) <-chan Result[V]
-	Forget(key T)
+// errGoexit indicates the runtime.Goexit was called in the user given
+// function.
+var errGoexit = errors.New("runtime.Goexit was called")
+
+// Singleflighter is anything that deduplicates calls like Group.
+type Singleflighter[K comparable, V any] interface {
+	Do(key K, fn func() (V, error)) (V, error, bool)
+	DoChan(key K, fn func() (V, error)) <-chan Result[V]
+	Forget(key K) bool
 }
 
-// Group wraps singleflight.Group with generics.
+// Group represents a class of work and forms a namespace in which units of
+// work can be executed with duplicate suppression.
+//
+// K is the key type and may be any comparable type, e.g. strings, integer
+// IDs, small structs or arrays. V is the result type returned by the work
+// function; results stay typed end-to-end.
 //
-// T must be a string-like type (constraint ~string) to ensure keys can be
-// passed through to the underlying singleflight. V is the result type
-// returned by the work function.
-type Group[T ~string, V any] struct {
-	group singleflight.Group
+// The zero value is ready to use with default behavior; use NewGroup to
+// configure it.
+type Group[K comparable, V any] struct {
+	mu      sync.Mutex
+	m       map[flightKey[K]]*call[V]
+	waiters int
+
+	calls      uint64
+	executions uint64
+	shared     uint64
+	errors     uint64
+	panics     uint64
+	forgets    uint64
+
+	config   atomic.Pointer[GroupConfig]
+	shard    int // index within a ShardedGroup plus one, zero otherwise
+	averages latencyAverages
+	subs     map[flightKey[K]][]*subscription[V]
+	recent   map[flightKey[K]]*call[V]
+	streams  []*eventStream[K]
 }
 
+// defaultGroupConfig is the configuration of a Group that has not been
+// configured.
+var defaultGroupConfig GroupConfig
+
 // Result is the typed output sent on channels returned by Group.DoChan and
 // ShardedGroup.DoChan.
 //
 // Val is the value produced by the underlying function. Err is any error
 // returned by that function. Shared reports whether this caller received a
 // duplicate-suppressed (shared) result, as opposed to being the caller that
-// actually executed the function.
+// actually executed the function. Stale reports whether Val is a previous
+// result served in place of a failed execution, see WithServeStale.
 type Result[V any] struct {
 	Val    V
 	Err    error
 	Shared bool
+	Stale  bool
+
+	// Start, Duration and Waiters describe the execution that produced
+	// Val: when it started, how long it took and how many callers joined
+	// it in addition to the caller that started it. They are zero for
+	// results that were not produced by an execution, e.g. rejected calls.
+	Start    time.Time
+	Duration time.Duration
+	Waiters  int
+}
+
+// lane separates independent flights of the same key, e.g. the primary and
+// fallback flights of DoWithFallback.
+type lane uint8
+
+const (
+	laneNormal lane = iota
+	laneFallback
+	lanePriority
+)
+
+// lanes are all lanes a key may have flights in.
+var lanes = [...]lane{laneNormal, laneFallback, lanePriority}
+
+// flightKey identifies a flight within a Group. Keys normalized due to
+// WithKeyNormalizer are identified by their normalized text, long keys
+// hashed due to WithLongKeyHashing by their digest instead of the key.
+type flightKey[K comparable] struct {
+	key    K
+	text   string
+	digest keyDigest
+	lane   lane
+}
+
+// call is an in-flight or completed execution of a work function.
+type call[V any] struct {
+	wg sync.WaitGroup
+
+	// These fields are written once before the WaitGroup is done and are
+	// only read after the WaitGroup is done.
+	val V
+	err error
+
+	// These fields are read and written with the group's mutex held before
+	// the WaitGroup is done, and are read but not written after the
+	// WaitGroup is done.
+	dups  int
+	chans []chan<- Result[V]
+
+	// interest is the number of callers still waiting for the result, and
+	// abandon cancels the execution once it drops to zero, if set.
+	interest int
+	abandon  context.CancelFunc
+
+	start     time.Time
+	duration  time.Duration
+	published chan V
+
+	// key is the key of a call tracked by its normalized form, or by its
+	// digest if configured via WithLongKeyRetention, instead of the key
+	// itself, see keyOf.
+	key any
+
+	// task traces the flight, see WithRuntimeTrace.
+	task *flightTask
+
+	// chanLeader reports whether the first channel of chans belongs to the
+	// caller that started the flight.
+	chanLeader bool
+
+	// wrapShared reports whether errors are wrapped in a SharedError for
+	// callers that joined the flight, see WithSharedErrors.
+	wrapShared bool
+
+	// leaderErrors is the policy applied to callers that joined the flight
+	// if it fails, see WithLeaderErrors.
+	leaderErrors LeaderErrorPolicy
+
+	// clone copies the value for every caller, see WithCloner.
+	clone func(V) V
+
+	// leader is the context of the caller that started the flight via
+	// DoContext, if any, and reelect reports whether callers that joined
+	// the flight elect a new leader once it is done, see
+	// WithLeaderReelection.
+	leader  context.Context //nolint:containedctx
+	reelect bool
+
+	// recovered reports whether a panic of the execution is delivered as
+	// a PanicError instead of being re-raised, see WithRecoverPanics.
+	recovered bool
+
+	// progress is the progress reported by the execution, see
+	// ReportProgress.
+	progress *flightProgress
+}
+
+// PanicError is an arbitrary value recovered from a panic with the stack
+// trace during the execution of given function. Groups recovering panics
+// (see WithRecoverPanics) deliver it to every caller as the error of the
+// flight.
+type PanicError struct {
+	// Value is the value the function panicked with.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error implements error interface.
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap returns the recovered value if it is an error.
+func (p *PanicError) Unwrap() error {
+	err, ok := p.Value.(error)
+	if !ok {
+		return nil
+	}
+
+	return err
+}
+
+// newPanicError captures the stack of the panicking goroutine along with v.
+func newPanicError(v any) error {
+	stack := debug.Stack()
+
+	// The first line of the stack trace is of the form "goroutine N [status]:"
+	// but by the time the panic reaches Do the goroutine may no longer exist
+	// and its status will have changed. Trim out the misleading line.
+	if line := bytes.IndexByte(stack, '\n'); line >= 0 {
+		stack = stack[line+1:]
+	}
+
+	return &PanicError{Value: v, Stack: stack}
+}
+
+// NewGroup constructs a Group configured by opts.
+func NewGroup[K comparable, V any](opts ...GroupConfigOption) *Group[K, V] {
+	g := &Group[K, V]{}
+	g.configure(opts...)
+
+	return g
+}
+
+// configure applies opts to the configuration of g.
+func (g *Group[K, V]) configure(opts ...GroupConfigOption) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	next := *g.settings()
+	for _, opt := range opts {
+		opt(&next)
+	}
+	checkConfig[K, V](&next)
+
+	g.config.Store(&next)
+}
+
+// checkConfig panics if an option of config applies to another key or value
+// type than the group's.
+func checkConfig[K comparable, V any](config *GroupConfig) {
+	if _, ok := config.hooks.(Hooks[K]); config.hooks != nil && !ok {
+		panic(fmt.Sprintf("singleflight: hooks %T do not match key type %s", config.hooks, typeName(typeOf[K]())))
+	}
+	if _, ok := config.cloner.(func(V) V); config.cloner != nil && !ok {
+		panic(fmt.Sprintf("singleflight: cloner %T does not match value type %s", config.cloner, typeName(typeOf[V]())))
+	}
+	if _, ok := config.stringKeys.(func(K) string); config.stringKeys != nil && !ok {
+		panic(fmt.Sprintf("singleflight: string keys %T do not match key type %s", config.stringKeys, typeName(typeOf[K]())))
+	}
+	if _, ok := config.validator.(func(V, error) error); config.validator != nil && !ok {
+		panic(fmt.Sprintf("singleflight: validator %T does not match value type %s", config.validator, typeName(typeOf[V]())))
+	}
+	if limit := config.resultLimit; limit != nil {
+		if _, ok := limit.sizer.(func(V) int64); !ok {
+			panic(fmt.Sprintf("singleflight: result sizer %T does not match value type %s", limit.sizer, typeName(typeOf[V]())))
+		}
+	}
+}
+
+// UpdateConfig atomically applies opts on top of the current configuration
+// of g, e.g. to tune limits of a live group from a config service. Calls
+// started afterwards observe the new configuration as a whole, while calls
+// already in flight may finish under the previous one.
+//
+// Options replace the setting they configure; WithRateLimit replaces the
+// limit of an already limited prefix. Limits that keep state, such as rate
+// limits and cost budgets, start afresh when replaced.
+func (g *Group[K, V]) UpdateConfig(opts ...GroupConfigOption) {
+	g.configure(opts...)
+}
+
+// settings returns the current configuration of g.
+func (g *Group[K, V]) settings() *GroupConfig {
+	if config := g.config.Load(); config != nil {
+		return config
+	}
+
+	return &defaultGroupConfig
 }
 
 // Do executes and deduplicates the provided function for the given key.
@@ -42,16 +285,105 @@ type Result[V any] struct {
 //
 // It returns the function's value V, its error (if any), and a boolean
 // shared indicating whether this caller received a shared result.
-func (g *Group[T, V]) Do(key T, fn func() (V, error)) (v V, err error, shared bool) {
-	result, err, shared := g.group.Do(string(key), func() (any, error) {
-		return fn()
-	})
+//
+// If the group limits waiters via WithMaxWaiters and the limit is reached
+// for key, Do returns ErrTooManyWaiters without joining the call. If the
+// group sheds load via WithLoadShedding and is overloaded, callers that
+// would join an in-flight call fail with ErrLoadShed. If the execution
+// budget of key is exhausted (see WithRateLimit), every caller of the
+// flight receives ErrRateLimited. If the group enforces a cost budget (see
+// WithCostBudget), the execution waits for or fails with ErrCostBudget
+// according to the configured CostPolicy. Keys exceeding the maximum length
+// set via WithMaxKeyLen are rejected with a *KeyTooLongError.
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	return g.do(key, laneNormal, costDerived, fn)
+}
 
-	if result != nil {
-		v, _ = result.(V) //nolint:errcheck
+// DoWithCost is like Do, but declares the cost of executing fn explicitly
+// instead of deriving it from the cost function of the group. The cost only
+// applies if the call starts a new execution; see WithCostBudget. Costs
+// below 1 count as 1.
+func (g *Group[K, V]) DoWithCost(
+	key K, cost int64, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return g.do(key, laneNormal, max(cost, 1), fn)
+}
+
+// DoResult is like Do, but returns the outcome as a Result carrying the
+// metadata of the execution as well: when it started, how long it took and
+// how many callers joined it, e.g. to decide whether the value is fresh
+// enough or to record metrics of the caller.
+func (g *Group[K, V]) DoResult(key K, fn func() (V, error)) Result[V] {
+	return g.flight(key, laneNormal, costDerived, fn, false)
+}
+
+// do implements Do and its variants for the flight of key in lane l.
+func (g *Group[K, V]) do(
+	key K, l lane, cost int64, fn func() (V, error),
+) (v V, err error, shared bool) {
+	res := g.flight(key, l, cost, fn, false)
+	return res.Val, res.Err, res.Shared
+}
+
+// flight implements DoResult and do for the flight of key in lane l.
+// retried reports whether the caller already re-executed after a failed
+// flight it joined, see LeaderErrorRetry.
+func (g *Group[K, V]) flight(
+	key K, l lane, cost int64, fn func() (V, error), retried bool,
+) Result[V] {
+	if err := g.checkKey(key); err != nil {
+		return Result[V]{Err: err}
+	}
+
+	fk := g.flightKey(key, l)
+
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[flightKey[K]]*call[V])
+	}
+
+	if c, ok := g.coalesced(fk); ok {
+		g.mu.Unlock()
+		g.joined(key)
+		return c.joinedResult()
+	}
+
+	if c, ok := g.inflight(fk); ok {
+		if err := g.admit(c); err != nil {
+			g.mu.Unlock()
+			return Result[V]{Err: err}
+		}
+		g.join(key, c)
+		g.mu.Unlock()
+		g.joined(key)
+
+		end := c.task.region("waiting")
+		c.wg.Wait()
+		end()
+
+		if e, ok := c.err.(*PanicError); ok && !c.recovered { //nolint:errorlint
+			panic(e)
+		} else if c.err == errGoexit { //nolint:errorlint
+			runtime.Goexit()
+		}
+
+		if c.abdicated() {
+			return g.flight(key, l, cost, fn, retried)
+		}
+		if c.rerun(retried) {
+			return g.flight(key, l, cost, fn, true)
+		}
+
+		return c.joinedResult()
 	}
 
-	return v, err, shared
+	c := g.newCall(key, fk)
+	g.mu.Unlock()
+	g.started(key)
+
+	g.doCall(c, key, fk, cost, task[V]{fn: fn})
+
+	return c.result(c.dups > 0)
 }
 
 // DoChan is the channel-based variant of Do.
@@ -63,44 +395,419 @@ func (g *Group[T, V]) Do(key T, fn func() (V, error)) (v V, err error, shared bo
 //
 // As with Do, callers that join an in-flight execution receive the same
 // result and Err, and the Shared field indicates whether this caller
-// received a shared result.
-func (g *Group[T, V]) DoChan(key T, fn func() (V, error)) <-chan Result[V] {
+// received a shared result. If the caller is rejected by the waiter limit
+// or load shedding, the channel receives a Result carrying the error.
+func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
 	ch := make(chan Result[V], 1)
+	if err := g.checkKey(key); err != nil {
+		ch <- Result[V]{Err: err}
+		return ch
+	}
 
-	upstreamCh := g.group.DoChan(string(key), func() (any, error) {
-		return fn()
-	})
+	fk := g.flightKey(key, laneNormal)
 
-	go g.toResult(upstreamCh, ch)
+	c, leader, err := g.enlist(key, fk, ch)
+	if err != nil {
+		ch <- Result[V]{Err: err}
+		return ch
+	}
+	if leader {
+		go g.doCall(c, key, fk, costDerived, task[V]{fn: fn})
+	}
 
 	return ch
 }
 
+// enlist registers a caller awaiting the result of the flight fk of key on
+// ch. It joins the call in flight, if any, or registers a new call the
+// caller leads and has to execute.
+func (g *Group[K, V]) enlist(
+	key K, fk flightKey[K], ch chan<- Result[V],
+) (c *call[V], leader bool, err error) {
+	defer func() {
+		switch {
+		case leader:
+			g.started(key)
+		case c != nil:
+			g.joined(key)
+		}
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.m == nil {
+		g.m = make(map[flightKey[K]]*call[V])
+	}
+
+	if c, ok := g.coalesced(fk); ok {
+		ch <- c.joinedResult()
+		return c, false, nil
+	}
+
+	if c, ok := g.inflight(fk); ok {
+		if err := g.admit(c); err != nil {
+			return nil, false, err
+		}
+		g.join(key, c)
+		c.chans = append(c.chans, ch)
+
+		return c, false, nil
+	}
+
+	c = g.newCall(key, fk)
+	c.chans = append(c.chans, ch)
+	c.chanLeader = true
+
+	return c, true, nil
+}
+
+// DoContext is like Do, but stops waiting when ctx is done.
+//
+// If ctx is canceled or its deadline passes before the result is available,
+// DoContext returns ctx.Err() while the execution continues for the other
+// callers. Once no caller is left, the flight is forgotten. If the group
+// rejects joins that cannot complete in time (see WithDeadlineAwareJoins),
+// DoContext fails fast with ErrInsufficientDeadline instead of joining a
+// call that is expected to outlast the deadline of ctx.
+func (g *Group[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return g.doContext(ctx, key, func(context.Context) (V, error) {
+		return fn()
+	}, false)
+}
+
+// waitContext waits for the result on ch until ctx is done.
+func waitContext[V any](ctx context.Context, ch <-chan Result[V]) (v V, err error, shared bool) {
+	select {
+	case res := <-ch:
+		return res.Val, res.Err, res.Shared
+	case <-ctx.Done():
+		return v, ctx.Err(), false
+	}
+}
+
 // Forget tells the group to forget about an in-flight or completed entry for key.
 //
 // If there is a call in flight for key, subsequent Do/DoChan calls with the
 // same key will not join that call after Forget has been invoked; instead,
-// they will start a new, independent execution. If there is a cached
-// result (from a recently completed call), it is also cleared.
-func (g *Group[T, V]) Forget(key T) {
-	g.group.Forget(string(key))
+// they will start a new, independent execution. This applies to all flights
+// of key, including fallback and high-priority flights. Forget reports
+// whether there was an entry for key to forget.
+func (g *Group[K, V]) Forget(key K) bool {
+	var keys []K
+	defer func() { g.forgot(keys) }()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	forgotten := false
+	for _, l := range lanes {
+		fk := g.flightKey(key, l)
+		for _, m := range []map[flightKey[K]]*call[V]{g.m, g.recent} {
+			if c, ok := m[fk]; ok {
+				delete(m, fk)
+				g.forget(fk, c, &keys)
+				forgotten = true
+			}
+		}
+	}
+
+	return forgotten
+}
+
+// newCall registers a new call of key for fk. The caller must hold g.mu.
+func (g *Group[K, V]) newCall(key K, fk flightKey[K]) *call[V] {
+	g.calls++
+	g.executions++
+
+	c := &call[V]{start: time.Now(), interest: 1}
+	if g.settings().priorityLanes && fk.lane == laneNormal {
+		c.published = make(chan V, 1)
+	}
+	if fk.text != "" || fk.digest != (keyDigest{}) && g.settings().longKeyRetention {
+		c.key = key
+	}
+	if g.settings().runtimeTrace {
+		c.task = g.newFlightTask(fk)
+	}
+	c.wrapShared = g.settings().sharedErrors
+	c.leaderErrors = g.settings().leaderErrors
+	c.reelect = g.settings().reelection
+	c.clone, _ = g.settings().cloner.(func(V) V)
+	c.wg.Add(1)
+	g.m[fk] = c
+	g.emit(EventCallStarted, key, c)
+
+	return c
+}
+
+// task is the work function of a flight: either fn, or work executed with
+// ctx.
+type task[V any] struct {
+	fn   func() (V, error)
+	work func(context.Context) (V, error)
+	ctx  context.Context //nolint:containedctx
+}
+
+// plain returns the task as a function without context.
+func (t task[V]) plain() func() (V, error) {
+	if t.work == nil {
+		return t.fn
+	}
+
+	return func() (V, error) {
+		return t.work(t.ctx)
+	}
 }
 
-// toResult adapts singleflight.Result into a typed Result[V].
-func (g *Group[T, V]) toResult(
-	sourceCh <-chan singleflight.Result,
-	destCh chan<- Result[V],
+// contextual returns the task as a function receiving a context, and the
+// context to execute it with.
+func (t task[V]) contextual() (func(context.Context) (V, error), context.Context) {
+	if t.work != nil {
+		return t.work, t.ctx
+	}
+
+	return func(context.Context) (V, error) {
+		return t.fn()
+	}, context.Background()
+}
+
+// doCall handles the single call for key, registered under fk.
+func (g *Group[K, V]) doCall(
+	c *call[V], key K, fk flightKey[K], cost int64, t task[V],
 ) {
-	sourceResult := <-sourceCh
+	normalReturn := false
+	recovered := false
+	c.recovered = g.settings().recoverPanics
+	if t.work != nil {
+		t.ctx = g.progressContext(t.ctx, c)
+	}
+
+	// use double-defer to distinguish panic from runtime.Goexit,
+	// more details see https://golang.org/cl/134395
+	defer func() {
+		// the given function invoked runtime.Goexit
+		if !normalReturn && !recovered {
+			c.err = errGoexit
+		}
+
+		g.observe(key, c)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.finish(c, key, fk)
+
+		if e, ok := c.err.(*PanicError); ok && !c.recovered { //nolint:errorlint
+			// In order to prevent the waiting channels from being blocked forever,
+			// needs to ensure that this panic cannot be recovered.
+			if len(c.chans) > 0 {
+				go panic(e)
+				select {} // Keep this goroutine around so that it will appear in the crash dump.
+			} else {
+				panic(e)
+			}
+		} else if c.err == errGoexit { //nolint:errorlint
+			// Already in the process of goexit, no need to call again
+			return
+		}
+
+		g.deliver(c, fk)
+	}()
 
-	result := Result[V]{
-		Err:    sourceResult.Err,
-		Shared: sourceResult.Shared,
+	func() {
+		defer func() {
+			if !normalReturn {
+				// Ideally, we would wait to take a stack trace until we've determined
+				// whether this is a panic or a runtime.Goexit.
+				//
+				// Unfortunately, the only way we can distinguish the two is to see
+				// whether the recover stopped the goroutine from terminating, and by
+				// the time we know that, the part of the stack trace relevant to the
+				// panic has been discarded.
+				if r := recover(); r != nil {
+					c.err = newPanicError(r)
+				}
+			}
+		}()
+
+		if c.task != nil || g.settings().pprofLabels {
+			g.instrumented(c, key, fk, cost, t)
+		} else {
+			c.val, c.err = g.execute(c, key, cost, t)
+		}
+		if c.err == errGoexit { //nolint:errorlint
+			// fn invoked runtime.Goexit on a goroutine of an execution
+			// policy, so exit the goroutine of the flight in its place
+			runtime.Goexit()
+		}
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
+	}
+}
+
+// result returns the result of the completed call c for a caller, shared
+// reporting whether the result is shared with the caller. The value is a
+// copy of its own if the group clones values, see WithCloner.
+func (c *call[V]) result(shared bool) Result[V] {
+	v := c.val
+	if c.clone != nil {
+		v = c.clone(v)
+	}
+
+	return Result[V]{
+		Val:      v,
+		Err:      c.err,
+		Shared:   shared,
+		Start:    c.start,
+		Duration: c.duration,
+		Waiters:  c.dups,
+	}
+}
+
+// joinedResult returns the result of the completed call c for a caller
+// that joined it, replacing its error by a LeaderFailedError and wrapping
+// it in a SharedError as configured.
+func (c *call[V]) joinedResult() Result[V] {
+	res := c.result(true)
+	if c.leaderFailed() {
+		res.Err = &LeaderFailedError{Err: res.Err}
+	}
+	if c.wrapShared && res.Err != nil {
+		res.Err = &SharedError{Err: res.Err}
 	}
 
-	if sourceResult.Val != nil {
-		result.Val, _ = sourceResult.Val.(V) //nolint:errcheck
+	return res
+}
+
+// join registers a caller joining the in-flight call c for key. The caller
+// must hold g.mu.
+func (g *Group[K, V]) join(key K, c *call[V]) {
+	c.dups++
+	c.interest++
+	g.waiters++
+	g.calls++
+	g.shared++
+	g.emit(EventWaiterJoined, key, c)
+}
+
+// finish marks the call c for key, registered under fk, as completed and
+// releases its waiters. The caller must hold g.mu.
+func (g *Group[K, V]) finish(c *call[V], key K, fk flightKey[K]) {
+	c.duration = time.Since(c.start)
+	c.wg.Done()
+	if g.m[fk] == c {
+		delete(g.m, fk)
+		g.retain(c, fk)
+	}
+	g.waiters -= c.dups
+	g.count(c.err)
+	g.recordCall(fk, c)
+	c.task.end()
+	c.progress.end()
+	g.emit(EventCallFinished, key, c)
+	if policy := g.settings().deadlineAware; policy != nil {
+		g.averages.record(policy, keyString(key), time.Since(c.start))
+	}
+}
+
+// deliver sends the result of the completed call c, registered under fk,
+// to the channels of its callers and to the subscribers of its key. The
+// caller must hold g.mu.
+func (g *Group[K, V]) deliver(c *call[V], fk flightKey[K]) {
+	for i, ch := range c.chans {
+		if i == 0 && c.chanLeader {
+			ch <- c.result(c.dups > 0)
+		} else {
+			ch <- c.joinedResult()
+		}
+	}
+	g.notify(fk, c)
+}
+
+// execute runs the task t on behalf of every caller of the flight c for
+// key, applying the execution policies configured for the group.
+func (g *Group[K, V]) execute(c *call[V], key K, cost int64, t task[V]) (V, error) {
+	var zero V
+	config := g.settings()
+
+	fn := t.plain()
+	if config.hedgeAfter > 0 {
+		work, ctx := t.contextual()
+		fn = hedged(ctx, config.hedgeAfter, work)
+	}
+
+	if len(config.rateLimits) > 0 {
+		if err := config.rateLimits.allow(keyString(key)); err != nil {
+			return zero, err
+		}
+	}
+
+	// Slots are released once fn returns, which may be after execute gave up
+	// on it, see timed.
+	var release []func()
+	if budget := config.costBudget; budget != nil {
+		if cost == costDerived {
+			cost = g.costOf(key)
+		}
+		_, ctx := t.contextual()
+		if err := budget.acquire(ctx, cost); err != nil {
+			return zero, err
+		}
+		release = append(release, func() { budget.release(cost) })
+	}
+
+	if validate := g.validator(); validate != nil {
+		fn = validating(validate, fn)
+	}
+
+	if policy := config.retry; policy != nil {
+		fn = retrying(policy, fn)
+	}
+
+	if limit := config.maxConcurrent; limit != nil {
+		limit.acquire(poolLane, 1)
+		release = append(release, limit.release)
+	}
+
+	if len(release) > 0 {
+		fn = releasing(release, fn)
+	}
+
+	if pool := config.workerPool; pool != nil {
+		fn = pooled(pool, fn)
+	}
+
+	if config.executionTimeout > 0 {
+		fn = timed(config.executionTimeout, fn)
+	}
+
+	if c.published != nil {
+		return g.checkResult(race(c.published, fn))
+	}
+
+	return g.checkResult(fn())
+}
+
+// keyString returns the textual form of key seen by options that operate on
+// strings, such as the prefixes of WithRateLimit or the cost function of
+// WithCostFn. Keys implementing Keyer are serialized via SingleflightKey,
+// [16]byte keys are formatted as UUIDs, other keys that are not strings,
+// including defined string types, are formatted with fmt.Sprint.
+func keyString[K comparable](key K) string {
+	switch k := any(key).(type) {
+	case string:
+		return k
+	case [16]byte:
+		return uuidString(k)
+	case Keyer:
+		return k.SingleflightKey()
 	}
 
-	destCh <- result
+	return fmt.Sprint(key)
 }
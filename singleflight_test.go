@@ -1,7 +1,12 @@
 package singleflight
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -46,7 +51,7 @@ func TestGroupError(t *testing.T) {
 type doer[T ~string, V any] interface {
 	Do(T, func() (V, error)) (V, error, bool)
 	DoChan(T, func() (V, error)) <-chan Result[V]
-	Forget(T)
+	Forget(T) bool
 }
 
 func forgetCreatesNewExecution[T ~string](t *testing.T, d doer[T, int], key T) {
@@ -79,7 +84,9 @@ func forgetCreatesNewExecution[T ~string](t *testing.T, d doer[T, int], key T) {
 	time.Sleep(sleepJoin)
 
 	// forget and start a fresh, independent call
-	d.Forget(key)
+	if !d.Forget(key) {
+		t.Fatal("Forget reported no flight to forget")
+	}
 	fn2 := func() (int, error) {
 		atomic.AddInt32(&total, 1)
 		return 2, nil
@@ -103,6 +110,9 @@ func forgetCreatesNewExecution[T ~string](t *testing.T, d doer[T, int], key T) {
 	if s1 || s2 {
 		t.Fatalf("shared flags = (%v,%v), want both false", s1, s2)
 	}
+	if d.Forget(key) {
+		t.Fatal("Forget reported a completed flight")
+	}
 }
 
 func doDedupe[T ~string](t *testing.T, d doer[T, int], key T) {
@@ -227,3 +237,405 @@ func doErrorPropagates[T ~string, V any](t *testing.T, d doer[T, V], key T, zero
 		t.Fatalf("shared=%v, want false", shared)
 	}
 }
+
+func TestGroupComparableKeys(t *testing.T) {
+	type userKey struct {
+		tenant string
+		id     int64
+	}
+
+	t.Run("int", func(t *testing.T) {
+		var g Group[int64, int]
+		comparableKeyDedupe(t, &g, 42, 43)
+	})
+	t.Run("struct", func(t *testing.T) {
+		var g Group[userKey, int]
+		comparableKeyDedupe(t, &g, userKey{"a", 1}, userKey{"b", 1})
+	})
+	t.Run("array", func(t *testing.T) {
+		var g Group[[2]uint64, int]
+		comparableKeyDedupe(t, &g, [2]uint64{1, 2}, [2]uint64{2, 1})
+	})
+}
+
+func comparableKeyDedupe[K comparable](t *testing.T, g Singleflighter[K, int], key, other K) {
+	t.Helper()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(numCallers + 1)
+	for i := range numCallers + 1 {
+		k := key
+		if i == numCallers {
+			k = other
+		}
+		go func() {
+			defer wg.Done()
+			g.Do(k, fn)
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	// one execution for key, one for other
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+}
+
+func TestGroupDoPanics(t *testing.T) {
+	var g Group[string, int]
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected Do to re-panic")
+		}
+		if err, ok := r.(error); !ok || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("recovered %v, want error mentioning boom", r)
+		}
+		if _, _, shared := g.Do(keyA, func() (int, error) { return 0, nil }); shared {
+			t.Fatal("expected key to be released after panic")
+		}
+	}()
+
+	g.Do(keyA, func() (int, error) { panic("boom") })
+}
+
+func TestGroupRecoverPanics(t *testing.T) {
+	g := NewGroup[string, int](WithRecoverPanics())
+	recoverPanicsDelivers(t, g, keyA)
+}
+
+func recoverPanicsDelivers[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	wantErr := errors.New("boom")
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		panic(wantErr)
+	}
+
+	ch := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err, _ := d.Do(key, fn)
+		done <- err
+	}()
+	time.Sleep(sleepJoin)
+	close(release)
+
+	for _, err := range []error{(<-ch).Err, <-done} {
+		var pe *PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("err=%v, want *PanicError", err)
+		}
+		if pe.Value != wantErr || len(pe.Stack) == 0 || !errors.Is(err, wantErr) {
+			t.Fatalf("panic value=%v stack=%d bytes, want %v with stack", pe.Value, len(pe.Stack), wantErr)
+		}
+	}
+}
+
+func TestGroupMaxConcurrent(t *testing.T) {
+	g := NewGroup[string, int](WithMaxConcurrent(2))
+	concurrencyCapped(t, g)
+}
+
+func TestGroupMaxConcurrentUncapped(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		g := NewGroup[string, int](WithMaxConcurrent(n))
+		if v, err, _ := g.Do(keyA, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || err != nil {
+			t.Fatalf("WithMaxConcurrent(%d): v=%d err=%v, want %d nil", n, v, err, wantValueInt)
+		}
+	}
+}
+
+type configUpdater[T ~string, V any] interface {
+	doer[T, V]
+	UpdateConfig(...GroupConfigOption)
+}
+
+func TestGroupUpdateConfig(t *testing.T) {
+	g := NewGroup[string, int](WithRateLimit("key", 0.001, 1))
+	updateConfigApplies(t, g, keyA)
+}
+
+func updateConfigApplies[T ~string](t *testing.T, d configUpdater[T, int], key T) {
+	t.Helper()
+
+	fn := func() (int, error) { return wantValueInt, nil }
+
+	if _, err, _ := d.Do(key, fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if _, err, _ := d.Do(key, fn); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err=%v, want %v", err, ErrRateLimited)
+	}
+
+	// replacing the limit of the prefix starts a fresh budget
+	d.UpdateConfig(WithRateLimit("key", 0.001, 2))
+	for range 2 {
+		if _, err, _ := d.Do(key, fn); err != nil {
+			t.Fatalf("err after update=%v, want nil", err)
+		}
+	}
+	if _, err, _ := d.Do(key, fn); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err after update=%v, want %v", err, ErrRateLimited)
+	}
+
+	// other settings are kept and updates race freely with calls
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			d.UpdateConfig(WithMaxWaiters(i + 1))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			d.Do(key+"-other", fn)
+		}
+	}()
+	wg.Wait()
+
+	if _, err, _ := d.Do(key, fn); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err after updates=%v, want %v", err, ErrRateLimited)
+	}
+}
+
+func TestGroupDoContext(t *testing.T) {
+	var g Group[string, int]
+	doContextStopsWaiting(t, &g, keyA)
+}
+
+func doContextStopsWaiting[T ~string](t *testing.T, d contextDoer[T, int], key T) {
+	t.Helper()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	leader := make(chan Result[int], 1)
+	go func() {
+		v, err, shared := d.DoContext(t.Context(), key, fn)
+		leader <- Result[int]{Val: v, Err: err, Shared: shared}
+	}()
+	time.Sleep(sleepJoin)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(sleepJoin, cancel)
+
+	if _, err, shared := d.DoContext(ctx, key, fn); !errors.Is(err, context.Canceled) || shared {
+		t.Fatalf("err=%v shared=%v, want %v false", err, shared, context.Canceled)
+	}
+
+	// the execution continues for the remaining callers
+	close(release)
+	if res := <-leader; res.Val != wantValueInt || res.Err != nil {
+		t.Fatalf("leader res=%+v, want %d nil", res, wantValueInt)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+}
+
+func TestGroupDoResult(t *testing.T) {
+	var g Group[string, int]
+	doResultReportsMetadata(t, &g, keyA)
+}
+
+type resultDoer[T ~string, V any] interface {
+	doer[T, V]
+	DoResult(T, func() (V, error)) Result[V]
+}
+
+func doResultReportsMetadata[T ~string](t *testing.T, d resultDoer[T, int], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	before := time.Now()
+	waiter := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+	leader := make(chan Result[int], 1)
+	go func() { leader <- d.DoResult(key, fn) }()
+	time.Sleep(sleepJoin)
+	close(release)
+
+	for _, res := range []Result[int]{<-waiter, <-leader} {
+		if res.Val != wantValueInt || !res.Shared || res.Waiters != 1 {
+			t.Fatalf("res=%+v, want shared %d with 1 waiter", res, wantValueInt)
+		}
+		if res.Start.Before(before) || res.Duration < 2*sleepJoin {
+			t.Fatalf("res=%+v, want started after %v and taking at least %v", res, before, 2*sleepJoin)
+		}
+	}
+
+	res := d.DoResult(key, func() (int, error) { return wantValueInt, nil })
+	if res.Shared || res.Waiters != 0 || res.Start.IsZero() {
+		t.Fatalf("res=%+v, want unshared execution", res)
+	}
+}
+
+func TestGroupSharedErrors(t *testing.T) {
+	g := NewGroup[string, int](WithSharedErrors())
+	sharedErrorsWrapJoined(t, g, keyA)
+}
+
+func sharedErrorsWrapJoined[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	errFn := errors.New("failed")
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return 0, errFn
+	}
+
+	leader := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+	joined := make(chan error, 1)
+	go func() {
+		_, err, _ := d.Do(key, fn)
+		joined <- err
+	}()
+	waiter := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+	close(release)
+
+	if res := <-leader; !errors.Is(res.Err, errFn) || errors.Is(res.Err, ErrShared) {
+		t.Fatalf("leader err=%v, want %v not shared", res.Err, errFn)
+	}
+	for _, err := range []error{<-joined, (<-waiter).Err} {
+		var se *SharedError
+		if !errors.Is(err, ErrShared) || !errors.Is(err, errFn) || !errors.As(err, &se) || err.Error() != "failed" {
+			t.Fatalf("waiter err=%v, want shared %v", err, errFn)
+		}
+	}
+}
+
+func TestGroupSharedErrorsDisabled(t *testing.T) {
+	var g Group[string, int]
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return 0, errors.New("failed")
+	}
+
+	g.DoChan(keyA, fn)
+	waiter := g.DoChan(keyA, fn)
+	time.Sleep(sleepJoin)
+	close(release)
+
+	if res := <-waiter; errors.Is(res.Err, ErrShared) {
+		t.Fatalf("waiter err=%v, want unwrapped by default", res.Err)
+	}
+}
+
+func TestGroupCloner(t *testing.T) {
+	g := NewGroup[string, map[string]int](WithCloner(maps.Clone[map[string]int]))
+	clonerCopiesValues(t, g, keyA)
+}
+
+func clonerCopiesValues[T ~string](t *testing.T, d doer[T, map[string]int], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (map[string]int, error) {
+		<-release
+		return map[string]int{"v": wantValueInt}, nil
+	}
+
+	chans := []<-chan Result[map[string]int]{d.DoChan(key, fn), d.DoChan(key, fn)}
+	go func() {
+		time.Sleep(sleepJoin)
+		close(release)
+	}()
+	v, _, _ := d.Do(key, fn)
+
+	// every caller modifies its value without affecting the others
+	values := []map[string]int{v}
+	for _, ch := range chans {
+		values = append(values, (<-ch).Val)
+	}
+	for i, v := range values {
+		if v["v"] != wantValueInt {
+			t.Fatalf("value %d=%v, want unmodified", i, v)
+		}
+		v["v"] = i
+	}
+}
+
+func TestGroupClonerOfAnotherType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "func(string) string") {
+			t.Fatalf("recovered %v, want panic naming the cloner", r)
+		}
+	}()
+	NewGroup[string, int](WithCloner(func(s string) string { return s + "!" }))
+}
+
+func TestGroupDoAllocs(t *testing.T) {
+	var g Group[int64, int]
+	fn := func() (int, error) { return wantValueInt, nil }
+
+	// the call record is the only allocation, results are never boxed
+	if allocs := testing.AllocsPerRun(100, func() { g.Do(1, fn) }); allocs > 1 {
+		t.Fatalf("allocs per Do = %v, want at most 1", allocs)
+	}
+}
+
+// doGoexits checks that a runtime.Goexit of fn exits the goroutine of the
+// caller like it does with a plain Group, and that the flight is forgotten.
+func doGoexits[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	returned := make(chan bool)
+	go func() {
+		ok := false
+		defer func() { returned <- ok }()
+
+		d.Do(key, func() (int, error) {
+			runtime.Goexit()
+			return wantValueInt, nil
+		})
+		ok = true
+	}()
+
+	if <-returned {
+		t.Fatal("Do returned after fn called runtime.Goexit")
+	}
+
+	v, err, _ := d.Do(key, func() (int, error) { return wantValueInt, nil })
+	if v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+	}
+}
+
+func TestGroupGoexit(t *testing.T) {
+	var g Group[string, int]
+	doGoexits(t, &g, keyA)
+}
@@ -0,0 +1,129 @@
+package singleflight
+
+import "time"
+
+// GroupStats is a point-in-time snapshot of the activity of a Group.
+type GroupStats struct {
+	// Name is the name of the group, see WithName.
+	Name string `json:"name,omitempty"`
+	// Calls is the number of calls that started or joined a flight.
+	Calls uint64 `json:"calls"`
+	// Executions is the number of flights started.
+	Executions uint64 `json:"executions"`
+	// InFlight is the number of flights in progress.
+	InFlight int `json:"inFlight"`
+	// Waiters is the number of callers currently waiting on a flight they
+	// joined.
+	Waiters int `json:"waiters"`
+	// Shared is the number of calls served by a flight started by another
+	// caller.
+	Shared uint64 `json:"shared"`
+	// Errors is the number of flights that completed with an error other
+	// than a panic.
+	Errors uint64 `json:"errors"`
+	// Panics is the number of flights whose work function panicked.
+	Panics uint64 `json:"panics"`
+	// Forgets is the number of flights and completed results forgotten via
+	// Forget and its variants or a Watchdog.
+	Forgets uint64 `json:"forgets"`
+}
+
+// DedupeRatio returns the fraction of calls that were served by a flight
+// started by another caller.
+func (s GroupStats) DedupeRatio() float64 {
+	if s.Calls == 0 || s.Executions >= s.Calls {
+		return 0
+	}
+
+	return float64(s.Calls-s.Executions) / float64(s.Calls)
+}
+
+// AverageWaiters returns the average number of callers that shared the
+// result of a flight.
+func (s GroupStats) AverageWaiters() float64 {
+	if s.Executions == 0 {
+		return 0
+	}
+
+	return float64(s.Shared) / float64(s.Executions)
+}
+
+// Name returns the name of g, see WithName.
+func (g *Group[K, V]) Name() string {
+	return g.settings().name
+}
+
+// Name returns the name of sg, see WithName.
+func (sg *ShardedGroup[K, V]) Name() string {
+	return sg.shards[0].Name()
+}
+
+// Stats returns a snapshot of the statistics of g.
+func (g *Group[K, V]) Stats() GroupStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return GroupStats{
+		Name:       g.settings().name,
+		Calls:      g.calls,
+		Executions: g.executions,
+		InFlight:   len(g.m),
+		Waiters:    g.waiters,
+		Shared:     g.shared,
+		Errors:     g.errors,
+		Panics:     g.panics,
+		Forgets:    g.forgets,
+	}
+}
+
+// Stats returns the statistics of all shards of sg combined.
+func (sg *ShardedGroup[K, V]) Stats() GroupStats {
+	stats := GroupStats{Name: sg.Name()}
+	for i := range sg.shards {
+		s := sg.shards[i].Stats()
+
+		stats.Calls += s.Calls
+		stats.Executions += s.Executions
+		stats.InFlight += s.InFlight
+		stats.Waiters += s.Waiters
+		stats.Shared += s.Shared
+		stats.Errors += s.Errors
+		stats.Panics += s.Panics
+		stats.Forgets += s.Forgets
+	}
+
+	return stats
+}
+
+// count records the outcome err of a completed flight. The caller must hold
+// g.mu.
+func (g *Group[K, V]) count(err error) {
+	switch err.(type) { //nolint:errorlint
+	case nil:
+	case *PanicError:
+		g.panics++
+	default:
+		if err != errGoexit { //nolint:errorlint
+			g.errors++
+		}
+	}
+}
+
+// observe reports the completed flight c of key to the hooks, the execution
+// observer and the logger of the group, if any. The caller must not hold g.mu.
+func (g *Group[K, V]) observe(key K, c *call[V]) {
+	if c.err == errGoexit { //nolint:errorlint
+		return
+	}
+
+	config, d := g.settings(), time.Since(c.start)
+	if h := g.hooks(); h != nil {
+		h.OnCallEnd(key, d, c.err)
+	}
+	if config.observer != nil {
+		config.observer(keyString(key), d, c.err)
+	}
+	if config.logger != nil {
+		g.log(config.logger, keyString(key), d, c)
+	}
+}
@@ -0,0 +1,113 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type statser[T ~string, V any] interface {
+	doer[T, V]
+	Stats() GroupStats
+}
+
+func TestGroupStats(t *testing.T) {
+	var g Group[string, int]
+	statsCountCalls(t, &g)
+}
+
+func TestShardedGroupStats(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithShardCount(4))
+	statsCountCalls(t, sg)
+}
+
+func statsCountCalls[T ~string](t *testing.T, d statser[T, int]) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	for _, key := range []T{keyA, keyA, keyA, keyA, keyB} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Do(key, fn)
+		}()
+	}
+	time.Sleep(sleepJoin)
+
+	if s := d.Stats(); s.InFlight != 2 || s.Waiters != 3 {
+		t.Fatalf("stats=%+v, want 2 in flight with 3 waiters", s)
+	}
+
+	close(release)
+	wg.Wait()
+
+	s := d.Stats()
+	if s.Calls != 5 || s.Executions != 2 || s.InFlight != 0 || s.Waiters != 0 {
+		t.Fatalf("stats=%+v, want 5 calls, 2 executions", s)
+	}
+	if got := s.DedupeRatio(); got != 0.6 {
+		t.Fatalf("DedupeRatio=%v, want 0.6", got)
+	}
+	if got := s.AverageWaiters(); s.Shared != 3 || got != 1.5 {
+		t.Fatalf("Shared=%d AverageWaiters=%v, want 3 shared, 1.5 waiters", s.Shared, got)
+	}
+}
+
+func TestGroupStatsName(t *testing.T) {
+	g := NewGroup[string, int](WithName("users"))
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithName("search")))
+
+	if g.Stats().Name != "users" || sg.Stats().Name != "search" || sg.Name() != "search" {
+		t.Fatalf("names=(%q, %q), want (users, search)", g.Stats().Name, sg.Stats().Name)
+	}
+}
+
+func TestGroupStatsOutcomes(t *testing.T) {
+	g := NewGroup[string, int](WithRecoverPanics())
+
+	g.Do(keyA, func() (int, error) { return 0, errors.New("failed") })
+	g.Do(keyA, func() (int, error) { panic("boom") })
+	g.Do(keyA, func() (int, error) { return wantValueInt, nil })
+
+	release := make(chan struct{})
+	defer close(release)
+	g.DoChan(keyB, func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	})
+	g.Forget(keyB)
+
+	s := g.Stats()
+	if s.Errors != 1 || s.Panics != 1 || s.Forgets != 1 || s.Executions != 4 {
+		t.Fatalf("stats=%+v, want 1 error, 1 panic, 1 forget in 4 executions", s)
+	}
+}
+
+func TestGroupExecutionObserver(t *testing.T) {
+	type observation struct {
+		key string
+		d   time.Duration
+		err error
+	}
+	observed := make(chan observation, 1)
+	g := NewGroup[string, int](WithExecutionObserver(func(key string, d time.Duration, err error) {
+		observed <- observation{key, d, err}
+	}))
+
+	errFn := errors.New("failed")
+	g.Do(keyA, func() (int, error) {
+		time.Sleep(sleepJoin)
+		return 0, errFn
+	})
+
+	if o := <-observed; o.key != keyA || o.d < sleepJoin || !errors.Is(o.err, errFn) {
+		t.Fatalf("observed %+v, want %s after %v with %v", o, keyA, sleepJoin, errFn)
+	}
+}
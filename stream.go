@@ -0,0 +1,235 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+)
+
+// StreamItem is an item of a stream, see StreamGroup.DoStream.
+type StreamItem[T any] struct {
+	// Val is the value of the item, unless Err is set.
+	Val T
+	// Err is the error the stream failed with. It is only set on the last
+	// item of a stream.
+	Err error
+	// Shared reports whether the caller joined a stream started by another
+	// caller.
+	Shared bool
+}
+
+// StreamGroup deduplicates streams: concurrent callers of DoStream with the
+// same key share one execution of a function yielding values, e.g. the
+// pages of a paginated load, and every caller receives all of its values
+// as they are yielded, without waiting for the stream to complete.
+//
+// The zero value is ready to use. A StreamGroup must not be copied after
+// first use.
+type StreamGroup[K comparable, T any] struct {
+	mu sync.Mutex
+	m  map[K]*streamCall[T]
+}
+
+// streamCall is a stream in progress or completed.
+type streamCall[T any] struct {
+	mu sync.Mutex
+	// items holds the values yielded so far, so callers joining late
+	// receive the stream from its start.
+	items []T
+	err   error
+	done  bool
+	// canceled is set once no caller is interested in the stream anymore,
+	// making yield report false.
+	canceled bool
+	// callers is the number of callers still receiving the stream.
+	callers int
+	// changed is closed and replaced whenever items or done change.
+	changed chan struct{}
+}
+
+// DoStream executes and returns the stream of values of fn, making sure
+// that only one execution is in-flight for a given key at a time. fn
+// passes every value to yield; if a duplicate comes in, the duplicate
+// caller joins the stream and receives the values yielded so far followed
+// by those still to come.
+//
+// The returned channel receives the values of the stream in order. If fn
+// returns an error, it is delivered as a last item with Err set. The
+// channel is closed once the stream is complete or ctx is done, whichever
+// happens first. Callers must receive until the channel is closed or
+// cancel ctx.
+//
+// Slow callers do not hold up the stream or the other callers: every value
+// is buffered until the stream is complete, so that callers joining late
+// receive the stream from its start. A stream is therefore held in memory
+// whole while it is in flight. yield reports false once ctx of every caller
+// is done, in which case fn should stop and return; a later call with the
+// same key starts a new stream. A panic in fn is delivered as a *PanicError.
+func (g *StreamGroup[K, T]) DoStream(
+	ctx context.Context, key K, fn func(yield func(T) bool) error,
+) <-chan StreamItem[T] {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*streamCall[T])
+	}
+
+	s, shared := g.m[key]
+	if !shared {
+		s = &streamCall[T]{changed: make(chan struct{})}
+		g.m[key] = s
+	}
+
+	s.mu.Lock()
+	s.callers++
+	s.mu.Unlock()
+	g.mu.Unlock()
+
+	if !shared {
+		go g.run(s, key, fn)
+	}
+
+	ch := make(chan StreamItem[T])
+	go g.deliver(ctx, s, key, ch, shared)
+
+	return ch
+}
+
+// Forget tells the group to forget about the stream of key. Future calls
+// to DoStream for this key start a new stream rather than joining the
+// current one. It reports whether there was a stream to forget.
+func (g *StreamGroup[K, T]) Forget(key K) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, ok := g.m[key]
+	delete(g.m, key)
+
+	return ok
+}
+
+// run executes fn for the stream s of key.
+func (g *StreamGroup[K, T]) run(s *streamCall[T], key K, fn func(yield func(T) bool) error) {
+	normalReturn := false
+	var err error
+
+	defer func() {
+		if !normalReturn {
+			if r := recover(); r != nil {
+				err = newPanicError(r)
+			} else {
+				err = errGoexit
+			}
+		}
+
+		g.remove(s, key)
+		s.finish(err)
+	}()
+
+	err = fn(s.yield)
+	normalReturn = true
+}
+
+// remove forgets s unless a new stream was registered for key in the
+// meantime.
+func (g *StreamGroup[K, T]) remove(s *streamCall[T], key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.m[key] == s {
+		delete(g.m, key)
+	}
+}
+
+// deliver sends the stream s to ch until it is complete or ctx is done.
+func (g *StreamGroup[K, T]) deliver(
+	ctx context.Context, s *streamCall[T], key K, ch chan<- StreamItem[T], shared bool,
+) {
+	defer close(ch)
+
+	for next := 0; ; {
+		s.mu.Lock()
+		items, err, done, changed := s.items[next:], s.err, s.done, s.changed
+		s.mu.Unlock()
+
+		for _, v := range items {
+			select {
+			case ch <- StreamItem[T]{Val: v, Shared: shared}:
+				next++
+			case <-ctx.Done():
+				g.leave(s, key)
+				return
+			}
+		}
+		if len(items) > 0 {
+			continue
+		}
+
+		if done {
+			if err != nil {
+				select {
+				case ch <- StreamItem[T]{Err: err, Shared: shared}:
+				case <-ctx.Done():
+				}
+			}
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			g.leave(s, key)
+			return
+		}
+	}
+}
+
+// leave unregisters a caller of the stream s of key that stopped
+// receiving, canceling and forgetting the stream if it was the last one.
+func (g *StreamGroup[K, T]) leave(s *streamCall[T], key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.callers--
+	if s.callers > 0 || s.done {
+		return
+	}
+
+	s.canceled = true
+	if g.m[key] == s {
+		delete(g.m, key)
+	}
+}
+
+// yield appends v to the stream and reports whether anybody is still
+// interested in it.
+func (s *streamCall[T]) yield(v T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.canceled {
+		return false
+	}
+
+	s.items = append(s.items, v)
+	s.notify()
+
+	return true
+}
+
+// finish completes the stream with err.
+func (s *streamCall[T]) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err, s.done = err, true
+	s.notify()
+}
+
+// notify wakes the callers waiting for the stream to change. The caller
+// must hold s.mu.
+func (s *streamCall[T]) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
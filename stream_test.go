@@ -0,0 +1,122 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collectStream receives the stream ch until it is closed.
+func collectStream[T any](ch <-chan StreamItem[T]) (vals []T, err error, shared bool) {
+	for item := range ch {
+		if item.Err != nil {
+			err = item.Err
+			continue
+		}
+		vals = append(vals, item.Val)
+		shared = item.Shared
+	}
+
+	return vals, err, shared
+}
+
+func TestDoStream(t *testing.T) {
+	var g StreamGroup[string, int]
+
+	var calls int32
+	first := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(yield func(int) bool) error {
+		atomic.AddInt32(&calls, 1)
+		yield(1)
+		close(first)
+		<-release
+		for _, v := range []int{2, 3} {
+			if !yield(v) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	leader := g.DoStream(context.Background(), keyA, fn)
+	if item := <-leader; item.Val != 1 || item.Shared {
+		t.Fatalf("first item=%+v, want 1, not shared", item)
+	}
+
+	<-first
+	joined := g.DoStream(context.Background(), keyA, fn)
+	close(release)
+
+	vals, err, _ := collectStream(leader)
+	if err != nil || !slices.Equal(vals, []int{2, 3}) {
+		t.Fatalf("leader got %v, %v, want [2 3]", vals, err)
+	}
+	vals, err, shared := collectStream(joined)
+	if err != nil || !slices.Equal(vals, []int{1, 2, 3}) || !shared {
+		t.Fatalf("joined got %v, %v, shared=%t, want [1 2 3], shared", vals, err, shared)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+}
+
+func TestDoStreamErrors(t *testing.T) {
+	var g StreamGroup[string, int]
+
+	errFn := errors.New("failed")
+	vals, err, _ := collectStream(g.DoStream(context.Background(), keyA, func(yield func(int) bool) error {
+		yield(wantValueInt)
+		return errFn
+	}))
+	if !errors.Is(err, errFn) || !slices.Equal(vals, []int{wantValueInt}) {
+		t.Fatalf("got %v, %v, want [%d], %v", vals, err, wantValueInt, errFn)
+	}
+
+	_, err, _ = collectStream(g.DoStream(context.Background(), keyB, func(yield func(int) bool) error {
+		panic("boom")
+	}))
+	var perr *PanicError
+	if !errors.As(err, &perr) || perr.Value != "boom" {
+		t.Fatalf("err=%v, want panic error", err)
+	}
+}
+
+func TestDoStreamCancel(t *testing.T) {
+	var g StreamGroup[string, int]
+
+	stopped := make(chan struct{})
+	fn := func(yield func(int) bool) error {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return nil
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := g.DoStream(ctx, keyA, fn)
+	<-ch
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stream not stopped after every caller canceled")
+	}
+	for range ch {
+	}
+
+	vals, _, shared := collectStream(g.DoStream(context.Background(), keyA, func(yield func(int) bool) error {
+		yield(wantValueInt)
+		return nil
+	}))
+	if !slices.Equal(vals, []int{wantValueInt}) || shared {
+		t.Fatalf("got %v, shared=%t, want new stream", vals, shared)
+	}
+}
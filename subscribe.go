@@ -0,0 +1,93 @@
+package singleflight
+
+import (
+	"slices"
+	"sync"
+)
+
+// subscription is a long-lived observer of the flights of a key, see
+// Group.Subscribe.
+type subscription[V any] struct {
+	mu     sync.Mutex
+	ch     chan Result[V]
+	closed bool
+}
+
+// deliver hands res to the subscriber, replacing a result it has not
+// received yet.
+func (s *subscription[V]) deliver(res Result[V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case <-s.ch:
+	default:
+	}
+	s.ch <- res
+}
+
+// close closes the channel of the subscriber.
+func (s *subscription[V]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	close(s.ch)
+}
+
+// Subscribe observes every future flight of key without initiating one,
+// e.g. for cache writers or push notifications that follow refreshes.
+//
+// The returned channel receives the result of every flight of key that
+// completes until cancel is called, after which it is closed. Results carry
+// Shared set to true. A result the subscriber has not received by the time
+// the next flight completes is replaced by the newer one, so slow
+// subscribers never hold up flights. Flights ending in a panic or
+// runtime.Goexit are not delivered.
+func (g *Group[K, V]) Subscribe(key K) (ch <-chan Result[V], cancel func()) {
+	sk := g.flightKey(key, laneNormal)
+	s := &subscription[V]{ch: make(chan Result[V], 1)}
+
+	g.mu.Lock()
+	if g.subs == nil {
+		g.subs = make(map[flightKey[K]][]*subscription[V])
+	}
+	g.subs[sk] = append(g.subs[sk], s)
+	g.mu.Unlock()
+
+	var once sync.Once
+
+	return s.ch, func() {
+		once.Do(func() {
+			g.mu.Lock()
+			subs := slices.DeleteFunc(g.subs[sk], func(other *subscription[V]) bool {
+				return other == s
+			})
+			if len(subs) == 0 {
+				delete(g.subs, sk)
+			} else {
+				g.subs[sk] = subs
+			}
+			g.mu.Unlock()
+
+			s.close()
+		})
+	}
+}
+
+// notify delivers the result of the completed call c, registered under fk,
+// to the subscribers of its key. The caller must hold g.mu.
+func (g *Group[K, V]) notify(fk flightKey[K], c *call[V]) {
+	if len(g.subs) == 0 {
+		return
+	}
+
+	fk.lane = laneNormal
+	for _, s := range g.subs[fk] {
+		s.deliver(c.joinedResult())
+	}
+}
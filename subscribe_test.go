@@ -0,0 +1,64 @@
+package singleflight
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type subscriber[T ~string, V any] interface {
+	doer[T, V]
+	Subscribe(T) (<-chan Result[V], func())
+}
+
+func TestGroupSubscribe(t *testing.T) {
+	var g Group[string, int]
+	subscribeReceivesFlights(t, &g, keyA)
+}
+
+func TestShardedGroupSubscribe(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+	subscribeReceivesFlights(t, sg, keyB)
+}
+
+func subscribeReceivesFlights[T ~string](t *testing.T, d subscriber[T, int], key T) {
+	t.Helper()
+
+	ch, cancel := d.Subscribe(key)
+
+	// subscribing does not initiate a flight
+	select {
+	case res := <-ch:
+		t.Fatalf("unexpected result %+v", res)
+	case <-time.After(sleepJoin):
+	}
+
+	errFn := errors.New("failed")
+	d.Do(key, func() (int, error) { return 1, nil })
+	if res := <-ch; res.Val != 1 || res.Err != nil || !res.Shared {
+		t.Fatalf("res=%+v, want 1 nil shared", res)
+	}
+
+	d.Do(key, func() (int, error) { return 0, errFn })
+	if res := <-ch; !errors.Is(res.Err, errFn) {
+		t.Fatalf("err=%v, want %v", res.Err, errFn)
+	}
+
+	// flights of other keys are not delivered
+	d.Do(key+"-other", func() (int, error) { return 2, nil })
+
+	// an unreceived result is replaced by the newer one
+	d.Do(key, func() (int, error) { return 3, nil })
+	d.Do(key, func() (int, error) { return 4, nil })
+	if res := <-ch; res.Val != 4 {
+		t.Fatalf("val=%d, want 4", res.Val)
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel open after cancel")
+	}
+
+	d.Do(key, func() (int, error) { return 5, nil })
+}
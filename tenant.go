@@ -0,0 +1,261 @@
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// TenantGroup scopes singleflight coordination by tenant ID.
+//
+// Every tenant owns an independent Group, so identical keys issued by
+// different tenants never share a flight, and Forget on one tenant never
+// touches another tenant's flights. The zero value is ready to use and
+// applies no quota; use NewTenantGroup to configure one.
+type TenantGroup[K comparable, V any] struct {
+	mu      sync.Mutex
+	tenants map[string]*Tenant[K, V]
+
+	config TenantConfig
+	pool   *fairPool
+}
+
+// Tenant is the view of a TenantGroup scoped to a single tenant ID.
+//
+// It implements Singleflighter and behaves like a Group whose flights are
+// isolated from those of every other tenant.
+type Tenant[K comparable, V any] struct {
+	id    string
+	group atomic.Pointer[Group[K, V]]
+	pool  *fairPool
+
+	quota     int
+	weight    int
+	executing atomic.Int64
+
+	calls      atomic.Uint64
+	shared     atomic.Uint64
+	executions atomic.Uint64
+	waiters    atomic.Int64
+}
+
+// TenantStats is a point-in-time snapshot of the activity of a Tenant.
+type TenantStats struct {
+	// Calls is the number of completed calls.
+	Calls uint64
+	// Shared is the number of completed calls that received a shared result.
+	Shared uint64
+	// Executions is the number of times a work function was executed.
+	Executions uint64
+	// Waiters is the number of callers currently waiting for a result.
+	Waiters int64
+}
+
+// DedupeRatio returns the fraction of completed calls that were served
+// without an execution of their own.
+func (s TenantStats) DedupeRatio() float64 {
+	if s.Calls == 0 || s.Executions >= s.Calls {
+		return 0
+	}
+
+	return float64(s.Calls-s.Executions) / float64(s.Calls)
+}
+
+// NewTenantGroup constructs a TenantGroup configured by opts.
+func NewTenantGroup[K comparable, V any](opts ...TenantConfigOption) *TenantGroup[K, V] {
+	tg := &TenantGroup[K, V]{}
+
+	for _, opt := range opts {
+		opt(&tg.config)
+	}
+
+	if tg.config.poolWorkers > 0 {
+		tg.pool = newFairPool(tg.config.poolWorkers)
+	}
+
+	return tg
+}
+
+// ForTenant returns the view of tg scoped to the tenant id, creating it on
+// first use. Repeated calls with the same id return the same Tenant.
+func (tg *TenantGroup[K, V]) ForTenant(id string) *Tenant[K, V] {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	if tg.tenants == nil {
+		tg.tenants = make(map[string]*Tenant[K, V])
+	}
+
+	t, ok := tg.tenants[id]
+	if !ok {
+		t = &Tenant[K, V]{
+			id:     id,
+			pool:   tg.pool,
+			quota:  tg.config.quota,
+			weight: 1,
+		}
+		if tg.config.weightFn != nil {
+			t.weight = tg.config.weightFn(id)
+		}
+		t.group.Store(&Group[K, V]{})
+		tg.tenants[id] = t
+	}
+
+	return t
+}
+
+// ForgetTenant clears all in-flight and recently completed state of the
+// tenant id, as if Forget had been called for every key of the tenant.
+//
+// Calls already waiting on a forgotten flight still receive its result;
+// subsequent calls start new executions. Statistics of the tenant are kept.
+func (tg *TenantGroup[K, V]) ForgetTenant(id string) {
+	tg.mu.Lock()
+	t, ok := tg.tenants[id]
+	tg.mu.Unlock()
+
+	if ok {
+		t.group.Store(&Group[K, V]{})
+	}
+}
+
+// Stats returns a snapshot of the statistics of every tenant of tg, keyed
+// by tenant ID.
+func (tg *TenantGroup[K, V]) Stats() map[string]TenantStats {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	stats := make(map[string]TenantStats, len(tg.tenants))
+	for id, t := range tg.tenants {
+		stats[id] = t.Stats()
+	}
+
+	return stats
+}
+
+// ID returns the tenant ID t is scoped to.
+func (t *Tenant[K, V]) ID() string {
+	return t.id
+}
+
+// Do executes and deduplicates fn for key within the tenant.
+//
+// Behavior matches Group.Do; callers of other tenants never join the flight.
+// If starting the flight would exceed the tenant quota, every caller of the
+// flight receives ErrTenantQuota.
+func (t *Tenant[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	t.waiters.Add(1)
+	defer t.waiters.Add(-1)
+
+	v, err, shared = t.group.Load().Do(key, t.guard(fn))
+	t.record(shared)
+
+	return v, err, shared
+}
+
+// DoChan is the channel-based variant of Do scoped to the tenant.
+//
+// Behavior matches Group.DoChan.
+func (t *Tenant[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	t.waiters.Add(1)
+
+	ch := make(chan Result[V], 1)
+	upstreamCh := t.group.Load().DoChan(key, t.guard(fn))
+
+	go func() {
+		defer t.waiters.Add(-1)
+
+		res := <-upstreamCh
+		t.record(res.Shared)
+		ch <- res
+	}()
+
+	return ch
+}
+
+// DoContext is the context-aware variant of Do scoped to the tenant.
+//
+// Behavior matches Group.DoContext.
+func (t *Tenant[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	t.waiters.Add(1)
+	defer t.waiters.Add(-1)
+
+	v, err, shared = t.group.Load().DoContext(ctx, key, t.guard(fn))
+	t.record(shared)
+
+	return v, err, shared
+}
+
+// DoWithFallback is the tenant-scoped variant of Group.DoWithFallback.
+func (t *Tenant[K, V]) DoWithFallback(
+	key K, primary, fallback func() (V, error),
+) (v V, err error, shared bool) {
+	t.waiters.Add(1)
+	defer t.waiters.Add(-1)
+
+	v, err, shared = t.group.Load().DoWithFallback(key, t.guard(primary), t.guard(fallback))
+	t.record(shared)
+
+	return v, err, shared
+}
+
+// Forget clears any in-flight or recently completed state for key within
+// the tenant and reports whether there was any. Flights of other tenants
+// with the same key are unaffected.
+func (t *Tenant[K, V]) Forget(key K) bool {
+	return t.group.Load().Forget(key)
+}
+
+// Stats returns a snapshot of the statistics of the tenant.
+func (t *Tenant[K, V]) Stats() TenantStats {
+	return TenantStats{
+		Calls:      t.calls.Load(),
+		Shared:     t.shared.Load(),
+		Executions: t.executions.Load(),
+		Waiters:    t.waiters.Load(),
+	}
+}
+
+// record accounts a completed call in the statistics of the tenant.
+func (t *Tenant[K, V]) record(shared bool) {
+	t.calls.Add(1)
+	if shared {
+		t.shared.Add(1)
+	}
+}
+
+// guard wraps fn so that it only executes while the tenant is within its
+// quota of concurrently executing flights, and on the shared pool if one
+// is configured.
+//
+// The check runs inside the flight, so callers joining an in-flight call
+// are never rejected; only the start of a new execution counts. A flight
+// queued for a pool slot counts against the quota.
+func (t *Tenant[K, V]) guard(fn func() (V, error)) func() (V, error) {
+	return func() (V, error) {
+		if n := t.executing.Add(1); t.quota > 0 && n > int64(t.quota) {
+			t.executing.Add(-1)
+
+			var zero V
+			return zero, ErrTenantQuota
+		}
+		defer t.executing.Add(-1)
+
+		t.executions.Add(1)
+		if t.pool == nil {
+			return fn()
+		}
+
+		var (
+			v   V
+			err error
+		)
+		t.pool.run(t.id, t.weight, func() {
+			v, err = fn()
+		})
+
+		return v, err
+	}
+}
@@ -0,0 +1,228 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTenantDo(t *testing.T) {
+	var tg TenantGroup[string, int]
+	doDedupe(t, tg.ForTenant("a"), keyA)
+}
+
+func TestTenantDoChan(t *testing.T) {
+	var tg TenantGroup[string, string]
+	doChanDedupe(t, tg.ForTenant("a"), keyB)
+}
+
+func TestTenantForget(t *testing.T) {
+	var tg TenantGroup[string, int]
+	forgetCreatesNewExecution(t, tg.ForTenant("a"), keyA)
+}
+
+func TestTenantError(t *testing.T) {
+	var tg TenantGroup[string, int]
+	doErrorPropagates(t, tg.ForTenant("a"), keyB, 0)
+}
+
+func TestTenantDoContext(t *testing.T) {
+	var tg TenantGroup[string, int]
+	doContextStopsWaiting(t, tg.ForTenant("a"), keyA)
+}
+
+func TestTenantForTenantReturnsSameView(t *testing.T) {
+	var tg TenantGroup[string, int]
+	if tg.ForTenant("a") != tg.ForTenant("a") {
+		t.Fatal("expected ForTenant to return the same view for the same id")
+	}
+	if got := tg.ForTenant("b").ID(); got != "b" {
+		t.Fatalf("ID()=%q, want %q", got, "b")
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	var tg TenantGroup[string, int]
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	shared := make([]bool, 2)
+	for i, id := range []string{"a", "b"} {
+		go func() {
+			defer wg.Done()
+			_, _, shared[i] = tg.ForTenant(id).Do(keyA, fn)
+		}()
+	}
+
+	// let both tenants register their flights
+	time.Sleep(sleepJoin)
+
+	// forgetting on one tenant must not affect the other
+	tg.ForTenant("a").Forget(keyA)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("underlying calls = %d, want 2", got)
+	}
+	if shared[0] || shared[1] {
+		t.Fatalf("shared flags = (%v,%v), want both false", shared[0], shared[1])
+	}
+}
+
+func TestTenantQuota(t *testing.T) {
+	tg := NewTenantGroup[string, int](WithTenantQuota(1))
+
+	release := make(chan struct{})
+	slow := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	errs := make([]error, 2)
+	for i := range 2 {
+		go func() {
+			defer wg.Done()
+			_, errs[i], _ = tg.ForTenant("a").Do(keyA, slow)
+		}()
+	}
+
+	// let the flight start and a second caller join it
+	time.Sleep(sleepJoin)
+
+	fast := func() (int, error) { return wantValueInt, nil }
+	if _, err, _ := tg.ForTenant("a").Do(keyB, fast); !errors.Is(err, ErrTenantQuota) {
+		t.Fatalf("err=%v, want %v", err, ErrTenantQuota)
+	}
+	if _, err, _ := tg.ForTenant("b").Do(keyB, fast); err != nil {
+		t.Fatalf("other tenant err=%v, want nil", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("errs[%d]=%v, want nil", i, err)
+		}
+	}
+
+	if _, err, _ := tg.ForTenant("a").Do(keyB, fast); err != nil {
+		t.Fatalf("err after release=%v, want nil", err)
+	}
+}
+
+func TestTenantPool(t *testing.T) {
+	tg := NewTenantGroup[string, int](
+		WithTenantPool(1),
+		WithTenantWeight(func(string) int { return 2 }),
+	)
+
+	if got := tg.ForTenant("a").weight; got != 2 {
+		t.Fatalf("weight=%d, want 2", got)
+	}
+
+	release := make(chan struct{})
+	slow := func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tg.ForTenant("a").Do(keyA, slow)
+	}()
+
+	// let the first flight occupy the only slot
+	time.Sleep(sleepJoin)
+
+	ch := tg.ForTenant("b").DoChan(keyB, func() (int, error) { return wantValueInt, nil })
+	select {
+	case <-ch:
+		t.Fatal("expected flight to wait for a pool slot")
+	case <-time.After(sleepJoin):
+	}
+
+	close(release)
+	wg.Wait()
+
+	if res := <-ch; res.Err != nil || res.Val != wantValueInt {
+		t.Fatalf("res=%+v, want Val=%d Err=nil", res, wantValueInt)
+	}
+}
+
+func TestTenantGroupForgetTenant(t *testing.T) {
+	var tg TenantGroup[string, int]
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for _, id := range []string{"a", "a", "b"} {
+		go func() {
+			defer wg.Done()
+			tg.ForTenant(id).Do(keyA, fn)
+		}()
+	}
+
+	// let the flights register
+	time.Sleep(sleepJoin)
+
+	tg.ForgetTenant("a")
+
+	wg.Add(2)
+	for _, id := range []string{"a", "b"} {
+		go func() {
+			defer wg.Done()
+			tg.ForTenant(id).Do(keyA, fn)
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	// tenant "a" runs a fresh flight after ForgetTenant, "b" keeps joining.
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("underlying calls = %d, want 3", got)
+	}
+}
+
+func TestTenantGroupStats(t *testing.T) {
+	var tg TenantGroup[string, int]
+	doDedupe(t, tg.ForTenant("a"), keyA)
+
+	stats := tg.Stats()
+	if _, ok := stats["b"]; ok {
+		t.Fatal("expected no stats for unknown tenant")
+	}
+
+	// one single-caller and one multi-caller flight
+	a := stats["a"]
+	if a.Calls != 1+numCallers || a.Executions != 2 || a.Waiters != 0 {
+		t.Fatalf("stats=%+v, want Calls=%d Executions=2 Waiters=0", a, 1+numCallers)
+	}
+	if want := float64(numCallers-1) / float64(numCallers+1); a.DedupeRatio() != want {
+		t.Fatalf("DedupeRatio()=%v, want %v", a.DedupeRatio(), want)
+	}
+}
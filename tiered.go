@@ -0,0 +1,156 @@
+package singleflight
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Tiered chains Singleflighters into tiers of deduplication, e.g. a
+// process-local Group in front of a ShardedGroup in front of a distributed
+// sfdist.Group. A call is deduplicated by the first tier, and only the
+// leader of a flight escalates to the next tier, so the costlier tiers
+// coordinate once per flight of the tier in front of them. The last tier
+// executes fn.
+//
+// Tiered implements Singleflighter.
+type Tiered[K comparable, V any] struct {
+	tiers []Singleflighter[K, V]
+	stats []tierCounters
+}
+
+// TierStats is a snapshot of the calls seen by one tier of a Tiered.
+type TierStats struct {
+	// Calls is the number of calls that reached the tier.
+	Calls uint64 `json:"calls"`
+	// Executions is the number of flights of the tier, i.e. the calls it
+	// escalated to the next tier or, on the last tier, executions of fn.
+	Executions uint64 `json:"executions"`
+	// Shared is the number of calls the tier served by a flight started by
+	// another caller, without escalating them.
+	Shared uint64 `json:"shared"`
+}
+
+// tierCounters counts the calls seen by one tier of a Tiered.
+type tierCounters struct {
+	calls      atomic.Uint64
+	executions atomic.Uint64
+	shared     atomic.Uint64
+}
+
+// tierCall is a call to one tier of a Tiered.
+type tierCall struct {
+	led    atomic.Bool // the caller led the flight of the tier
+	shared atomic.Bool // a tier behind served the caller a shared result
+}
+
+// NewTiered returns a Tiered chaining tiers, the first of which sees every
+// call and the last of which executes fn.
+//
+// NewTiered panics if no tier is given.
+func NewTiered[K comparable, V any](tiers ...Singleflighter[K, V]) *Tiered[K, V] {
+	if len(tiers) == 0 {
+		panic("singleflight: NewTiered requires at least one tier")
+	}
+
+	return &Tiered[K, V]{
+		tiers: tiers,
+		stats: make([]tierCounters, len(tiers)),
+	}
+}
+
+// Do executes and deduplicates fn for key on the tiers.
+//
+// shared reports whether any tier reported the result of the caller as
+// shared.
+func (t *Tiered[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bool) {
+	return t.do(0, key, fn)
+}
+
+// DoChan is the channel-based variant of Do.
+func (t *Tiered[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	call := new(tierCall)
+	t.stats[0].calls.Add(1)
+	base := t.tiers[0].DoChan(key, t.escalate(0, key, fn, call))
+
+	ch := make(chan Result[V], 1)
+	go func() {
+		res := <-base
+		res.Shared = t.settle(0, call, res.Shared)
+		ch <- res
+	}()
+
+	return ch
+}
+
+// DoContext is like Do, but stops waiting when ctx is done, returning
+// ctx.Err() while the execution continues for the other callers.
+func (t *Tiered[K, V]) DoContext(
+	ctx context.Context, key K, fn func() (V, error),
+) (v V, err error, shared bool) {
+	return waitContext(ctx, t.DoChan(key, fn))
+}
+
+// Forget forgets the flight of key on every tier and reports whether any
+// tier had one.
+func (t *Tiered[K, V]) Forget(key K) bool {
+	forgot := false
+	for _, tier := range t.tiers {
+		forgot = tier.Forget(key) || forgot
+	}
+
+	return forgot
+}
+
+// Stats returns a snapshot of the calls seen by each tier, in the order of
+// the tiers.
+func (t *Tiered[K, V]) Stats() []TierStats {
+	stats := make([]TierStats, len(t.stats))
+	for i := range t.stats {
+		stats[i] = TierStats{
+			Calls:      t.stats[i].calls.Load(),
+			Executions: t.stats[i].executions.Load(),
+			Shared:     t.stats[i].shared.Load(),
+		}
+	}
+
+	return stats
+}
+
+// do executes and deduplicates fn for key on tier i and the tiers behind
+// it.
+func (t *Tiered[K, V]) do(i int, key K, fn func() (V, error)) (v V, err error, shared bool) {
+	call := new(tierCall)
+	t.stats[i].calls.Add(1)
+
+	v, err, shared = t.tiers[i].Do(key, t.escalate(i, key, fn, call))
+
+	return v, err, t.settle(i, call, shared)
+}
+
+// settle records the completed call to tier i and returns whether its
+// result is shared, given that tier i reported it as shared.
+func (t *Tiered[K, V]) settle(i int, call *tierCall, shared bool) bool {
+	if !call.led.Load() {
+		t.stats[i].shared.Add(1)
+	}
+
+	return shared || call.shared.Load()
+}
+
+// escalate returns the function executed by the leader of a flight of
+// tier i on behalf of call: fn on the last tier, and the call to the next
+// tier otherwise.
+func (t *Tiered[K, V]) escalate(i int, key K, fn func() (V, error), call *tierCall) func() (V, error) {
+	return func() (V, error) {
+		call.led.Store(true)
+		t.stats[i].executions.Add(1)
+		if i == len(t.tiers)-1 {
+			return fn()
+		}
+
+		v, err, shared := t.do(i+1, key, fn)
+		call.shared.Store(shared)
+
+		return v, err
+	}
+}
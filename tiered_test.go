@@ -0,0 +1,124 @@
+package singleflight
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTiered(t *testing.T) {
+	// two processes with local groups in front of a group they share
+	shared := NewShardedGroup[string, int]()
+	a := NewTiered[string, int](&Group[string, int]{}, shared)
+	b := NewTiered[string, int](&Group[string, int]{}, shared)
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	var sharedCalls atomic.Int32
+	for _, tiered := range []*Tiered[string, int]{a, a, a, b, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := tiered.Do(keyA, fn)
+			if v != wantValueInt || err != nil {
+				t.Errorf("Do=%d, %v, want %d", v, err, wantValueInt)
+			}
+			if shared {
+				sharedCalls.Add(1)
+			}
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("underlying calls = %d, want 1", got)
+	}
+	if got := sharedCalls.Load(); got != 5 {
+		t.Fatalf("shared calls = %d, want 5", got)
+	}
+
+	want := []TierStats{
+		{Calls: 3, Executions: 1, Shared: 2},
+		{Calls: 1, Executions: 1, Shared: 0},
+	}
+	if got := a.Stats(); got[0] != want[0] {
+		t.Fatalf("local tier stats=%+v, want %+v", got[0], want[0])
+	}
+	// exactly one process escalated as leader of the shared tier
+	sa, sb := a.Stats()[1], b.Stats()[1]
+	if sa.Calls != 1 || sb.Calls != 1 || sa.Executions+sb.Executions != 1 || sa.Shared+sb.Shared != 1 {
+		t.Fatalf("shared tier stats=%+v and %+v, want one leader and one shared call", sa, sb)
+	}
+}
+
+func TestTieredDoChan(t *testing.T) {
+	inner := &Group[string, int]{}
+	tiered := NewTiered[string, int](&Group[string, int]{}, inner)
+
+	release := make(chan struct{})
+	ch := inner.DoChan(keyA, func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	})
+	time.Sleep(sleepJoin)
+
+	// the local leader joins the flight already in progress on the inner
+	// tier, so its result is shared
+	res := tiered.DoChan(keyA, func() (int, error) { return 0, nil })
+	time.Sleep(sleepJoin)
+	close(release)
+
+	if got := <-res; got.Val != wantValueInt || !got.Shared {
+		t.Fatalf("DoChan=%+v, want shared %d", got, wantValueInt)
+	}
+	<-ch
+
+	want := []TierStats{{Calls: 1, Executions: 1}, {Calls: 1, Shared: 1}}
+	if got := tiered.Stats(); got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Stats=%+v, want %+v", got, want)
+	}
+}
+
+func TestTieredDoContext(t *testing.T) {
+	doContextStopsWaiting(t, NewTiered[string, int](&Group[string, int]{}, &Group[string, int]{}), keyA)
+}
+
+func TestTieredForget(t *testing.T) {
+	inner := &Group[string, int]{}
+	tiered := NewTiered[string, int](&Group[string, int]{}, inner)
+
+	release := make(chan struct{})
+	ch := inner.DoChan(keyA, func() (int, error) {
+		<-release
+		return wantValueInt, nil
+	})
+	defer func() { close(release); <-ch }()
+	time.Sleep(sleepJoin)
+
+	if !tiered.Forget(keyA) {
+		t.Fatal("Forget=false, want true for the flight of the inner tier")
+	}
+	if tiered.Forget(keyA) {
+		t.Fatal("Forget=true, want false once forgotten")
+	}
+}
+
+func TestTieredNoTiers(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewTiered without tiers did not panic")
+		}
+	}()
+	NewTiered[string, int]()
+}
@@ -0,0 +1,40 @@
+package singleflight
+
+import "time"
+
+// timed returns fn wrapped to give up on fn once it has run for timeout,
+// failing with an *ExecutionTimeoutError while fn keeps running on a
+// goroutine of its own, see WithExecutionTimeout. A panic of fn is
+// returned as a PanicError, and a runtime.Goexit of fn as errGoexit.
+func timed[V any](timeout time.Duration, fn func() (V, error)) func() (V, error) {
+	return func() (V, error) {
+		done := make(chan Result[V], 1)
+		go capture(done, fn)
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case res := <-done:
+			return res.Val, res.Err
+		case <-timer.C:
+			var zero V
+			return zero, &ExecutionTimeoutError{Timeout: timeout}
+		}
+	}
+}
+
+// releasing returns fn wrapped to call every function of release once fn
+// returns, panics or exits, so the slots they free stay held for as long as
+// fn runs, even if timed gave up on it.
+func releasing[V any](release []func(), fn func() (V, error)) func() (V, error) {
+	return func() (V, error) {
+		defer func() {
+			for _, r := range release {
+				r()
+			}
+		}()
+
+		return fn()
+	}
+}
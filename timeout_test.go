@@ -0,0 +1,87 @@
+package singleflight
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupExecutionTimeout(t *testing.T) {
+	g := NewGroup[string, int](WithExecutionTimeout(sleepJoin))
+	executionTimeoutForgets(t, g, keyA)
+}
+
+func TestShardedGroupExecutionTimeout(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithExecutionTimeout(sleepJoin)))
+	executionTimeoutForgets(t, sg, keyB)
+}
+
+func executionTimeoutForgets[T ~string](t *testing.T, d doer[T, int], key T) {
+	t.Helper()
+
+	var calls int32
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	ch := d.DoChan(key, func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-stuck
+		return 1, nil
+	})
+	time.Sleep(sleepJoin / 3)
+	joined := d.DoChan(key, func() (int, error) { return 0, nil })
+
+	for _, c := range []<-chan Result[int]{ch, joined} {
+		res := <-c
+		var te *ExecutionTimeoutError
+		if !errors.As(res.Err, &te) || te.Timeout != sleepJoin || !errors.Is(res.Err, ErrExecutionTimeout) {
+			t.Fatalf("err=%v, want *ExecutionTimeoutError of %v", res.Err, sleepJoin)
+		}
+	}
+
+	// new callers start a fresh execution instead of joining the stuck one
+	if v, err, shared := d.Do(key, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || err != nil || shared {
+		t.Fatalf("v=%d err=%v shared=%v, want fresh %d", v, err, shared, wantValueInt)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("stuck calls=%d, want 1", got)
+	}
+}
+
+func TestGroupExecutionTimeoutGoexit(t *testing.T) {
+	g := NewGroup[string, int](WithExecutionTimeout(sleepHold))
+	doGoexits(t, g, keyA)
+}
+
+func TestGroupExecutionTimeoutHoldsSlots(t *testing.T) {
+	for name, opt := range map[string]GroupConfigOption{
+		"WithMaxConcurrent": WithMaxConcurrent(1),
+		"WithCostBudget":    WithCostBudget(1, CostQueue),
+	} {
+		g := NewGroup[string, int](WithExecutionTimeout(sleepJoin/3), opt)
+
+		var running, peak int32
+		stuck := make(chan struct{})
+		fn := func() (int, error) {
+			if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&peak) {
+				atomic.StoreInt32(&peak, n)
+			}
+			defer atomic.AddInt32(&running, -1)
+			<-stuck
+			return wantValueInt, nil
+		}
+
+		if _, err, _ := g.Do(keyA, fn); !errors.Is(err, ErrExecutionTimeout) {
+			t.Fatalf("%s: err=%v, want ErrExecutionTimeout", name, err)
+		}
+		ch := g.DoChan(keyB, fn)
+		time.Sleep(sleepJoin)
+		close(stuck)
+		<-ch
+
+		if got := atomic.LoadInt32(&peak); got != 1 {
+			t.Fatalf("%s: peak running executions=%d, want 1", name, got)
+		}
+	}
+}
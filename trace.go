@@ -0,0 +1,64 @@
+package singleflight
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// flightTask traces a flight as a runtime/trace task, see WithRuntimeTrace.
+type flightTask struct {
+	ctx  context.Context //nolint:containedctx
+	task *trace.Task
+}
+
+// newFlightTask starts a task tracing the flight fk of g, or returns nil if
+// runtime tracing is not enabled.
+func (g *Group[K, V]) newFlightTask(fk flightKey[K]) *flightTask {
+	if !trace.IsEnabled() {
+		return nil
+	}
+
+	ctx, task := trace.NewTask(context.Background(), "singleflight")
+	if name := g.settings().name; name != "" {
+		trace.Log(ctx, "group", name)
+	}
+	trace.Log(ctx, "key", fk.String())
+
+	return &flightTask{ctx: ctx, task: task}
+}
+
+// nopRegion ends a region that was never started.
+func nopRegion() {}
+
+// region starts a region of the task named name on the calling goroutine
+// and returns a function ending it. It is a no-op on a nil task.
+func (t *flightTask) region(name string) (end func()) {
+	if t == nil {
+		return nopRegion
+	}
+
+	return trace.StartRegion(t.ctx, name).End
+}
+
+// end ends the task. It is a no-op on a nil task.
+func (t *flightTask) end() {
+	if t != nil {
+		t.task.End()
+	}
+}
+
+// instrumented executes t for the flight c registered under fk like
+// execute, in a region "executing" of the task of the flight and labeled
+// for profiles, as configured.
+func (g *Group[K, V]) instrumented(c *call[V], key K, fk flightKey[K], cost int64, t task[V]) {
+	exec := func(t task[V]) {
+		defer c.task.region("executing")()
+		c.val, c.err = g.execute(c, key, cost, t)
+	}
+
+	if g.settings().pprofLabels {
+		g.profiled(fk, t, exec)
+	} else {
+		exec(t)
+	}
+}
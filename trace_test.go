@@ -0,0 +1,59 @@
+package singleflight
+
+import (
+	"bytes"
+	"context"
+	"runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupRuntimeTrace(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("runtime tracing unavailable: %v", err)
+	}
+
+	g := NewGroup[string, int](WithRuntimeTrace(), WithName("users"))
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Do(keyA, func() (int, error) {
+				time.Sleep(sleepJoin)
+				return wantValueInt, nil
+			})
+		}()
+	}
+	v, err, _ := g.DoContext(context.Background(), keyB, func() (int, error) {
+		return wantValueInt, nil
+	})
+	wg.Wait()
+	trace.Stop()
+
+	if v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want %d", v, err, wantValueInt)
+	}
+	for _, s := range []string{"singleflight", "executing", "waiting", "users", keyA, keyB} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Fatalf("trace does not contain %q", s)
+		}
+	}
+}
+
+func TestGroupRuntimeTraceDisabled(t *testing.T) {
+	g := NewGroup[string, int](WithRuntimeTrace())
+
+	g.Do(keyA, func() (int, error) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if c := g.m[g.flightKey(keyA, laneNormal)]; c.task != nil {
+			t.Error("flight traced while runtime tracing is disabled")
+		}
+		return wantValueInt, nil
+	})
+}
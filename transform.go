@@ -0,0 +1,23 @@
+package singleflight
+
+// DoTransform is like Do on g, but applies transform to the shared value
+// for this caller only, e.g. to filter the fields a specific caller is
+// allowed to see, without breaking the deduplication of the expensive
+// load. transform runs on the goroutine of the caller once fn succeeded;
+// if fn fails, its error is returned without calling transform.
+//
+// transform receives the value shared with the other callers and must not
+// modify it, unless the group copies values for every caller (see
+// WithCloner).
+func DoTransform[K comparable, V, R any](
+	g Singleflighter[K, V], key K, fn func() (V, error), transform func(V) (R, error),
+) (r R, err error, shared bool) {
+	v, err, shared := g.Do(key, fn)
+	if err != nil {
+		return r, err, shared
+	}
+
+	r, err = transform(v)
+
+	return r, err, shared
+}
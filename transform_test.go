@@ -0,0 +1,69 @@
+package singleflight
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoTransform(t *testing.T) {
+	var g Group[string, []string]
+
+	var calls int32
+	fn := func() ([]string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(sleepJoin)
+		return []string{"public:a", "secret:b", "public:c"}, nil
+	}
+	visible := func(prefix string) func([]string) (int, error) {
+		return func(fields []string) (int, error) {
+			n := 0
+			for _, f := range fields {
+				if strings.HasPrefix(f, prefix) {
+					n++
+				}
+			}
+			return n, nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int, 2)
+	for i, prefix := range []string{"public:", "secret:"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counts[i], _, _ = DoTransform(&g, keyA, fn, visible(prefix))
+		}()
+	}
+	wg.Wait()
+
+	if counts[0] != 2 || counts[1] != 1 {
+		t.Fatalf("counts=%v, want [2 1]", counts)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+}
+
+func TestDoTransformErrors(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+
+	errFn := errors.New("failed")
+	transform := func(v int) (string, error) {
+		t.Fatal("transform called for failed flight")
+		return "", nil
+	}
+	if _, err, _ := DoTransform(sg, keyA, func() (int, error) { return 0, errFn }, transform); !errors.Is(err, errFn) {
+		t.Fatalf("err=%v, want %v", err, errFn)
+	}
+
+	errDenied := errors.New("denied")
+	deny := func(int) (string, error) { return "", errDenied }
+	if _, err, _ := DoTransform(sg, keyB, func() (int, error) { return wantValueInt, nil }, deny); !errors.Is(err, errDenied) {
+		t.Fatalf("err=%v, want %v", err, errDenied)
+	}
+}
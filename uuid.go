@@ -0,0 +1,41 @@
+package singleflight
+
+import "encoding/hex"
+
+// UUIDShardedGroup is the variant of ShardedGroup for 16-byte keys such as
+// UUIDs, including defined types like uuid.UUID.
+//
+// Keys are used as map keys as they are, and shards are selected by hashing
+// the 16 key bytes directly, so hot paths never encode keys to strings.
+type UUIDShardedGroup[K ~[16]byte, V any] = ShardedGroup[K, V]
+
+// NewUUIDShardedGroup constructs a UUIDShardedGroup configured by opts.
+func NewUUIDShardedGroup[K ~[16]byte, V any](opts ...ShardConfigOption) *UUIDShardedGroup[K, V] {
+	s := NewShardedGroup[K, V](opts...)
+	if s.keyIndex == nil {
+		s.keyIndex = func(key K) uint64 {
+			b := [16]byte(key)
+
+			return s.router.indexBytes(b[:])
+		}
+	}
+
+	return s
+}
+
+// uuidString formats b in the canonical 8-4-4-4-12 hexadecimal UUID form.
+func uuidString(b [16]byte) string {
+	var buf [36]byte
+
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+
+	return string(buf[:])
+}
@@ -0,0 +1,67 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testUUID mimics defined UUID types such as uuid.UUID.
+type testUUID [16]byte
+
+func TestUUIDShardedGroup(t *testing.T) {
+	ug := NewUUIDShardedGroup[testUUID, int](WithShardCount(16))
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return wantValueInt, nil
+	}
+
+	keys := make([]testUUID, 8)
+	for i := range keys {
+		keys[i][0] = byte(i / 2) // pairs of equal keys
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(keys))
+	for _, key := range keys {
+		go func() {
+			defer wg.Done()
+			ug.Do(key, fn)
+		}()
+	}
+
+	time.Sleep(sleepJoin)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != int32(len(keys)/2) {
+		t.Fatalf("underlying calls = %d, want %d", got, len(keys)/2)
+	}
+
+	if res := <-ug.DoChan(keys[0], func() (int, error) { return 1, nil }); res.Val != 1 {
+		t.Fatalf("DoChan val=%d, want 1", res.Val)
+	}
+}
+
+func TestGroupUUIDKeyString(t *testing.T) {
+	key := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	if got, want := keyString(key), "123e4567-e89b-12d3-a456-426614174000"; got != want {
+		t.Fatalf("keyString=%q, want %q", got, want)
+	}
+
+	g := NewGroup[[16]byte, int](WithRateLimit("123e4567-", 0.001, 1))
+	fn := func() (int, error) { return wantValueInt, nil }
+
+	if _, err, _ := g.Do(key, fn); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if _, err, _ := g.Do(key, fn); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err=%v, want %v", err, ErrRateLimited)
+	}
+}
@@ -0,0 +1,30 @@
+package singleflight
+
+// validator returns the result validator of g, if any, see WithValidator.
+func (g *Group[K, V]) validator() func(V, error) error {
+	validate, _ := g.settings().validator.(func(V, error) error)
+	return validate
+}
+
+// checkValid applies validate to the result v, err of an execution,
+// replacing a result failing validation by a ValidationError.
+func checkValid[V any](validate func(V, error) error, v V, err error) (V, error) {
+	if verr := validate(v, err); verr != nil {
+		var zero V
+		return zero, &ValidationError{Err: verr}
+	}
+
+	return v, err
+}
+
+// validating returns fn wrapped to validate its results with validate.
+func validating[V any](validate func(V, error) error, fn func() (V, error)) func() (V, error) {
+	return func() (V, error) {
+		v, err := fn()
+		if err == errGoexit { //nolint:errorlint
+			return v, err
+		}
+
+		return checkValid(validate, v, err)
+	}
+}
@@ -0,0 +1,91 @@
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errEmpty = errors.New("empty")
+
+func nonEmpty(v string, err error) error {
+	if err == nil && v == "" {
+		return errEmpty
+	}
+
+	return nil
+}
+
+func TestGroupValidator(t *testing.T) {
+	g := NewGroup[string, string](WithValidator(nonEmpty), WithCoalesceWindow(sleepHold))
+	validatorRejects(t, g, keyA)
+}
+
+func TestShardedGroupValidator(t *testing.T) {
+	sg := NewShardedGroup[string, string](WithGroupOptions(WithValidator(nonEmpty)))
+	validatorRejects(t, sg, keyB)
+}
+
+func validatorRejects[T ~string](t *testing.T, d doer[T, string], key T) {
+	t.Helper()
+
+	release := make(chan struct{})
+	fn := func() (string, error) {
+		<-release
+		return "", nil
+	}
+
+	ch := d.DoChan(key, fn)
+	time.Sleep(sleepJoin)
+	waiter := d.DoChan(key, fn)
+	close(release)
+
+	for _, res := range []Result[string]{<-ch, <-waiter} {
+		var ve *ValidationError
+		if !errors.Is(res.Err, ErrInvalidResult) || !errors.Is(res.Err, errEmpty) || !errors.As(res.Err, &ve) {
+			t.Fatalf("err=%v, want %v", res.Err, ErrInvalidResult)
+		}
+	}
+
+	// invalid results are not retained
+	if v, err, _ := d.Do(key, func() (string, error) { return "ok", nil }); v != "ok" || err != nil {
+		t.Fatalf("v=%q err=%v, want fresh ok", v, err)
+	}
+}
+
+func TestGroupValidatorRetries(t *testing.T) {
+	var calls int32
+	g := NewGroup[string, string](WithValidator(nonEmpty), WithRetry(RetryPolicy{MaxAttempts: 2}))
+
+	v, err, _ := g.Do(keyA, func() (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return "", nil
+		}
+		return "ok", nil
+	})
+	if v != "ok" || err != nil || calls != 2 {
+		t.Fatalf("v=%q err=%v calls=%d, want ok after a retry", v, err, calls)
+	}
+}
+
+func TestCompleterValidator(t *testing.T) {
+	g := NewGroup[string, string](WithValidator(nonEmpty))
+
+	completer, _ := g.Start(keyA)
+	completer.Complete("", nil)
+	if _, err := completer.Wait(); !errors.Is(err, errEmpty) {
+		t.Fatalf("err=%v, want %v", err, errEmpty)
+	}
+}
+
+func TestGroupValidatorOfAnotherType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "func(string, error) error") {
+			t.Fatalf("recovered %v, want panic naming the validator", r)
+		}
+	}()
+	NewGroup[string, int](WithValidator(nonEmpty))
+}
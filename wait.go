@@ -0,0 +1,63 @@
+package singleflight
+
+import "context"
+
+// WaitAny waits for the first result delivered on any of chans, such as the
+// channels returned by DoChan. It returns the index of the channel that
+// delivered the result and the result itself. Results on the other channels
+// are left untouched, except for results delivered on them at the moment
+// WaitAny returns, which are dropped.
+//
+// If ctx is done first, WaitAny returns -1 and ctx.Err(). Without chans,
+// WaitAny waits for ctx.
+func WaitAny[V any](ctx context.Context, chans ...<-chan Result[V]) (int, Result[V], error) {
+	type indexed struct {
+		i   int
+		res Result[V]
+	}
+
+	// every channel is received from on a goroutine of its own, which
+	// forwards its result or gives up once WaitAny returns
+	first := make(chan indexed, len(chans))
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i, ch := range chans {
+		go func() {
+			select {
+			case res := <-ch:
+				first <- indexed{i: i, res: res}
+			case <-stop:
+			}
+		}()
+	}
+
+	select {
+	case r := <-first:
+		return r.i, r.res, nil
+	case <-ctx.Done():
+		return -1, Result[V]{}, ctx.Err()
+	}
+}
+
+// Collect waits for the results delivered on chans, such as the channels
+// returned by DoChan for a set of keys, and returns them by key.
+//
+// If ctx is done first, Collect returns the results gathered so far and
+// ctx.Err().
+func Collect[T comparable, V any](
+	ctx context.Context, chans map[T]<-chan Result[V],
+) (map[T]Result[V], error) {
+	results := make(map[T]Result[V], len(chans))
+
+	for key, ch := range chans {
+		select {
+		case res := <-ch:
+			results[key] = res
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
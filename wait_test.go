@@ -0,0 +1,99 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitAny(t *testing.T) {
+	var g Group[string, string]
+
+	release := make(chan struct{})
+	defer close(release)
+
+	slow := g.DoChan(keyA, func() (string, error) {
+		<-release
+		return keyA, nil
+	})
+	fast := g.DoChan(keyB, func() (string, error) {
+		return keyB, nil
+	})
+
+	i, res, err := WaitAny(t.Context(), slow, fast)
+	if err != nil || i != 1 || res.Val != keyB {
+		t.Fatalf("i=%d val=%q err=%v, want 1 %q nil", i, res.Val, err, keyB)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+
+	if i, _, err := WaitAny(ctx, slow); !errors.Is(err, context.DeadlineExceeded) || i != -1 {
+		t.Fatalf("i=%d err=%v, want -1 %v", i, err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitAnyLeavesOtherResults(t *testing.T) {
+	slow := make(chan Result[int], 1)
+	fast := make(chan Result[int], 1)
+	fast <- Result[int]{Val: 1}
+
+	if i, _, err := WaitAny(t.Context(), slow, fast); i != 1 || err != nil {
+		t.Fatalf("i=%d err=%v, want 1 nil", i, err)
+	}
+	time.Sleep(sleepJoin)
+
+	// the result delivered after WaitAny returned is still there
+	slow <- Result[int]{Val: 2}
+	select {
+	case res := <-slow:
+		if res.Val != 2 {
+			t.Fatalf("val=%d, want 2", res.Val)
+		}
+	case <-time.After(sleepJoin):
+		t.Fatal("result of the other channel consumed")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var g Group[string, string]
+
+	chans := make(map[string]<-chan Result[string])
+	for _, key := range []string{keyA, keyB} {
+		chans[key] = g.DoChan(key, func() (string, error) {
+			time.Sleep(sleepJoin)
+			return key, nil
+		})
+	}
+
+	results, err := Collect(t.Context(), chans)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("results=%v err=%v, want 2 results nil", results, err)
+	}
+	for key, res := range results {
+		if res.Val != key {
+			t.Fatalf("results[%q]=%q, want %q", key, res.Val, key)
+		}
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	chans[keyA] = g.DoChan(keyA+"-slow", func() (string, error) {
+		<-release
+		return keyA, nil
+	})
+	chans[keyB] = g.DoChan(keyB+"-fast", func() (string, error) { return keyB, nil })
+
+	ctx, cancel := context.WithTimeout(t.Context(), sleepJoin)
+	defer cancel()
+
+	results, err = Collect(ctx, chans)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err=%v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, ok := results[keyA]; ok {
+		t.Fatalf("results=%v, want no result for %q", results, keyA)
+	}
+}
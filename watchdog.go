@@ -0,0 +1,193 @@
+package singleflight
+
+import (
+	"sync"
+	"time"
+)
+
+// Watchable is implemented by the groups a Watchdog can watch, such as
+// Group, ShardedGroup, Sharded, Tenant and TenantGroup. Implement it to watch
+// groups of your own.
+type Watchable interface {
+	// Stuck returns the flights in progress for at least threshold,
+	// forgetting them if forget is set.
+	Stuck(threshold time.Duration, forget bool) []FlightInfo
+}
+
+// Watchdog periodically scans the flights in progress on a group and
+// reports those running for longer than a threshold to a hook, optionally
+// forgetting them so new callers start a fresh execution, see
+// WithAutoForget.
+//
+// Flights are scanned while the watchdog is started; see Start and Stop.
+type Watchdog struct {
+	group     Watchable
+	threshold time.Duration
+	hook      func(FlightInfo)
+	config    WatchdogConfig
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatchdog constructs a Watchdog calling hook for every flight on group
+// in progress for at least threshold, configured by opts. A stuck flight
+// is reported on every scan until it completes or is forgotten.
+func NewWatchdog(
+	group Watchable, threshold time.Duration, hook func(FlightInfo), opts ...WatchdogConfigOption,
+) *Watchdog {
+	w := &Watchdog{
+		group:     group,
+		threshold: threshold,
+		hook:      hook,
+		config:    WatchdogConfig{interval: threshold / 2},
+	}
+
+	for _, opt := range opts {
+		opt(&w.config)
+	}
+
+	return w
+}
+
+// Start starts scanning the group. Starting a started watchdog is a no-op.
+func (w *Watchdog) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stop != nil {
+		return
+	}
+
+	w.stop, w.done = make(chan struct{}), make(chan struct{})
+	go w.run(w.stop, w.done)
+}
+
+// Stop stops scanning and waits for a running scan to complete. The
+// watchdog may be started again.
+func (w *Watchdog) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Scan scans the group once, reporting and optionally forgetting its stuck
+// flights, and returns them.
+func (w *Watchdog) Scan() []FlightInfo {
+	flights := w.group.Stuck(w.threshold, w.config.autoForget)
+	sortFlights(flights)
+
+	if w.hook != nil {
+		for _, f := range flights {
+			w.hook(f)
+		}
+	}
+
+	return flights
+}
+
+// run scans the group every interval until stop is closed.
+func (w *Watchdog) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(max(w.config.interval, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.Scan()
+		}
+	}
+}
+
+// Stuck implements Watchable.
+func (g *Group[K, V]) Stuck(threshold time.Duration, forget bool) []FlightInfo {
+	return g.stuck(threshold, time.Now(), forget)
+}
+
+// stuck returns the flights in progress for at least threshold at now,
+// forgetting them if forget is set.
+func (g *Group[K, V]) stuck(threshold time.Duration, now time.Time, forget bool) []FlightInfo {
+	var keys []K
+	defer func() { g.forgot(keys) }()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var flights []FlightInfo
+	for fk, c := range g.m {
+		if now.Sub(c.start) < threshold {
+			continue
+		}
+
+		flights = append(flights, g.flightInfo(fk, c, now))
+		if forget {
+			delete(g.m, fk)
+			g.forget(fk, c, &keys)
+		}
+	}
+
+	return flights
+}
+
+// Stuck implements Watchable.
+func (sg *ShardedGroup[K, V]) Stuck(threshold time.Duration, forget bool) []FlightInfo {
+	now := time.Now()
+
+	var flights []FlightInfo
+	for i := range sg.shards {
+		flights = append(flights, sg.shards[i].stuck(threshold, now, forget)...)
+	}
+
+	return flights
+}
+
+// Stuck implements Watchable for the shards implementing it, reporting the
+// index of the shard of every flight.
+func (s *Sharded[K, V]) Stuck(threshold time.Duration, forget bool) []FlightInfo {
+	var flights []FlightInfo
+	for i, shard := range s.shards {
+		w, ok := shard.(Watchable)
+		if !ok {
+			continue
+		}
+		for _, f := range w.Stuck(threshold, forget) {
+			f.Shard = i
+			flights = append(flights, f)
+		}
+	}
+
+	return flights
+}
+
+// Stuck implements Watchable for the flights of the tenant.
+func (t *Tenant[K, V]) Stuck(threshold time.Duration, forget bool) []FlightInfo {
+	return t.group.Load().Stuck(threshold, forget)
+}
+
+// Stuck implements Watchable for the flights of every tenant.
+func (tg *TenantGroup[K, V]) Stuck(threshold time.Duration, forget bool) []FlightInfo {
+	tg.mu.Lock()
+	tenants := make([]*Tenant[K, V], 0, len(tg.tenants))
+	for _, t := range tg.tenants {
+		tenants = append(tenants, t)
+	}
+	tg.mu.Unlock()
+
+	var flights []FlightInfo
+	for _, t := range tenants {
+		flights = append(flights, t.Stuck(threshold, forget)...)
+	}
+
+	return flights
+}
@@ -0,0 +1,110 @@
+package singleflight
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	var g Group[string, int]
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	g.DoChan(keyA, func() (int, error) {
+		<-stuck
+		return 0, nil
+	})
+	g.DoChan(keyA, func() (int, error) { return 0, nil })
+
+	reported := make(chan FlightInfo, 1)
+	w := NewWatchdog(&g, sleepJoin, func(f FlightInfo) {
+		select {
+		case reported <- f:
+		default:
+		}
+	}, WithScanInterval(sleepJoin/3), WithAutoForget())
+	w.Start()
+	w.Start()
+	defer w.Stop()
+
+	select {
+	case f := <-reported:
+		if f.Key != keyA || f.Waiters != 1 || f.Age < sleepJoin || f.Start.IsZero() {
+			t.Fatalf("reported %+v, want %s with 1 waiter older than %v", f, keyA, sleepJoin)
+		}
+	case <-time.After(sleepHold * 2):
+		t.Fatal("stuck flight not reported")
+	}
+
+	// the stuck flight was forgotten
+	if v, _, shared := g.Do(keyA, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || shared {
+		t.Fatalf("v=%d shared=%v, want fresh %d", v, shared, wantValueInt)
+	}
+}
+
+func TestWatchdogScan(t *testing.T) {
+	sg := NewShardedGroup[string, int]()
+
+	release := make(chan struct{})
+	sg.DoChan(keyA, func() (int, error) {
+		<-release
+		return 0, nil
+	})
+	sg.DoChan(keyB, func() (int, error) { return 0, nil })
+
+	w := NewWatchdog(sg, sleepJoin, nil)
+	if flights := w.Scan(); len(flights) != 0 {
+		t.Fatalf("flights=%v, want none before threshold", flights)
+	}
+
+	time.Sleep(sleepJoin)
+	if flights := w.Scan(); len(flights) != 1 || flights[0].Key != keyA {
+		t.Fatalf("flights=%v, want %s", flights, keyA)
+	}
+
+	// without auto-forget the flight is kept
+	if len(sg.Inflight()) != 1 {
+		t.Fatalf("inflight=%v, want stuck flight kept", sg.Inflight())
+	}
+	close(release)
+}
+
+func TestWatchdogSharded(t *testing.T) {
+	s := NewSharded(func(int) Singleflighter[string, int] {
+		return &Group[string, int]{}
+	}, WithShardCount(4), WithShardPicker(func(string) uint64 { return 2 }))
+
+	release := make(chan struct{})
+	defer close(release)
+	s.DoChan(keyA, func() (int, error) {
+		<-release
+		return 0, nil
+	})
+
+	time.Sleep(sleepJoin)
+	flights := NewWatchdog(s, sleepJoin, nil).Scan()
+	if len(flights) != 1 || flights[0].Key != keyA || flights[0].Shard != 2 {
+		t.Fatalf("flights=%v, want %s on shard 2", flights, keyA)
+	}
+}
+
+func TestWatchdogTenants(t *testing.T) {
+	var tg TenantGroup[string, int]
+
+	release := make(chan struct{})
+	defer close(release)
+	for _, id := range []string{"a", "b"} {
+		tg.ForTenant(id).DoChan(keyA, func() (int, error) {
+			<-release
+			return 0, nil
+		})
+	}
+
+	time.Sleep(sleepJoin)
+	if flights := NewWatchdog(tg.ForTenant("a"), sleepJoin, nil).Scan(); len(flights) != 1 {
+		t.Fatalf("flights=%v, want the flight of tenant a", flights)
+	}
+	if flights := NewWatchdog(&tg, sleepJoin, nil).Scan(); len(flights) != 2 {
+		t.Fatalf("flights=%v, want the flights of both tenants", flights)
+	}
+}
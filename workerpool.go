@@ -0,0 +1,47 @@
+package singleflight
+
+// poolLane is the lane the executions of a group queue on in its worker
+// pool and concurrency limit.
+const poolLane = ""
+
+// pooled returns fn wrapped to execute on a goroutine of its own once p
+// has a free slot, see WithWorkerPool. A panic of fn is
+// returned as a PanicError, which the flight raises again like a panic of
+// its own, and a runtime.Goexit of fn as errGoexit.
+func pooled[V any](p *fairPool, fn func() (V, error)) func() (V, error) {
+	return func() (V, error) {
+		p.acquire(poolLane, 1)
+
+		done := make(chan Result[V], 1)
+		go func() {
+			defer p.release()
+			capture(done, fn)
+		}()
+
+		res := <-done
+
+		return res.Val, res.Err
+	}
+}
+
+// capture runs fn and sends its result to done, so that fn can execute on a
+// goroutine other than the one of its flight. A panic of fn is sent as a
+// PanicError, and a runtime.Goexit of fn as errGoexit.
+func capture[V any](done chan<- Result[V], fn func() (V, error)) {
+	normalReturn := false
+	var res Result[V]
+
+	defer func() {
+		if !normalReturn {
+			if r := recover(); r != nil {
+				res.Err = newPanicError(r)
+			} else {
+				res.Err = errGoexit
+			}
+		}
+		done <- res
+	}()
+
+	res.Val, res.Err = fn()
+	normalReturn = true
+}
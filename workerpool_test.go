@@ -0,0 +1,85 @@
+package singleflight
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupWorkerPool(t *testing.T) {
+	g := NewGroup[string, int](WithWorkerPool(2))
+	concurrencyCapped(t, g)
+}
+
+func TestShardedGroupWorkerPool(t *testing.T) {
+	sg := NewShardedGroup[string, int](WithGroupOptions(WithWorkerPool(2)))
+	concurrencyCapped(t, sg)
+}
+
+func concurrencyCapped[T ~string](t *testing.T, d doer[T, int]) {
+	t.Helper()
+
+	var running, peak int32
+	fn := func() (int, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(sleepJoin / 3)
+		return wantValueInt, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(6)
+	for i := range 6 {
+		go func() {
+			defer wg.Done()
+			if v, err, _ := d.Do(T(fmt.Sprint("key-", i)), fn); v != wantValueInt || err != nil {
+				t.Errorf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&peak); got != 2 {
+		t.Fatalf("peak concurrent executions=%d, want 2", got)
+	}
+}
+
+func TestGroupWorkerPoolPanics(t *testing.T) {
+	g := NewGroup[string, int](WithWorkerPool(1), WithRecoverPanics())
+
+	wantErr := errors.New("boom")
+	_, err, _ := g.Do(keyA, func() (int, error) { panic(wantErr) })
+
+	var pe *PanicError
+	if !errors.As(err, &pe) || !errors.Is(err, wantErr) {
+		t.Fatalf("err=%v, want *PanicError of %v", err, wantErr)
+	}
+
+	// the slot of the panicking execution is released
+	if v, err, _ := g.Do(keyA, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || err != nil {
+		t.Fatalf("v=%d err=%v, want %d nil", v, err, wantValueInt)
+	}
+}
+
+func TestGroupWorkerPoolDisabled(t *testing.T) {
+	for _, workers := range []int{0, -1} {
+		g := NewGroup[string, int](WithWorkerPool(workers))
+		if v, err, _ := g.Do(keyA, func() (int, error) { return wantValueInt, nil }); v != wantValueInt || err != nil {
+			t.Fatalf("WithWorkerPool(%d): v=%d err=%v, want %d nil", workers, v, err, wantValueInt)
+		}
+	}
+}
+
+func TestGroupWorkerPoolGoexit(t *testing.T) {
+	g := NewGroup[string, int](WithWorkerPool(2))
+	doGoexits(t, g, keyA)
+}